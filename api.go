@@ -8,6 +8,7 @@ import (
 	"errors"
 	"fmt"
 	"net/http"
+	"net/url"
 	"strconv"
 	"strings"
 	"time"
@@ -66,6 +67,59 @@ func registerAPIMethods() {
 		}{lnurlEncoded})
 	})
 
+	router.Path("/createinvoice").HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ctx, user, permission, err := loadUserFromAPICall(r)
+		if err != nil {
+			errorBadAuth(w)
+			return
+		}
+		if permission < InvoicePermissions {
+			errorInsufficientPermissions(w)
+			return
+		}
+
+		var params struct {
+			Satoshis    int64  `json:"satoshis"`
+			Description string `json:"description"`
+			Webhook     string `json:"webhook"`
+		}
+		err = json.NewDecoder(r.Body).Decode(&params)
+		if err != nil || params.Satoshis <= 0 {
+			errorInvalidParams(w)
+			return
+		}
+		if params.Webhook != "" {
+			if u, err := url.Parse(params.Webhook); err != nil ||
+				(u.Scheme != "https" && u.Scheme != "http") || u.Host == "" {
+				errorInvalidParams(w)
+				return
+			}
+		}
+
+		log.Debug().Int64("satoshis", params.Satoshis).
+			Str("description", params.Description).Str("webhook", params.Webhook).
+			Stringer("user", &user).Msg("api createinvoice")
+
+		bolt11, hash, err := user.makeInvoice(ctx, &MakeInvoiceArgs{
+			IgnoreInvoiceSizeLimit: true,
+			Msatoshi:               1000 * params.Satoshis,
+			Description:            params.Description,
+			Extra:                  InvoiceExtra{Webhook: params.Webhook},
+		})
+		if err != nil {
+			log.Warn().Err(err).Stringer("user", &user).
+				Msg("failed to make invoice on api")
+			errorInternal(w)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(struct {
+			Bolt11 string `json:"bolt11"`
+			Hash   string `json:"payment_hash"`
+		}{bolt11, hash})
+	})
+
 	router.Path("/invoicestatus/{hash}").HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		_, user, permission, err := loadUserFromAPICall(r)
 		if err != nil {
@@ -322,7 +376,7 @@ func handleAPI(ctx context.Context, opts docopt.Opts) {
 	passwordReadOnly := hashString(passwordInvoice)
 
 	tokenFull := base64.StdEncoding.EncodeToString([]byte(fmt.Sprintf("%d:%s", u.Id, passwordFull)))
-	tokenInvoice := base64.StdEncoding.EncodeToString([]byte(fmt.Sprintf("%d:%s", u.Id, passwordInvoice)))
+	tokenInvoice := u.invoiceAPIToken()
 	tokenReadOnly := base64.StdEncoding.EncodeToString([]byte(fmt.Sprintf("%d:%s", u.Id, passwordReadOnly)))
 
 	switch {
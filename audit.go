@@ -0,0 +1,91 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"strings"
+
+	"github.com/tidwall/gjson"
+)
+
+// handleAudit checks the ledger invariants and reports the ones that fail.
+// It's only available to the admin account.
+func handleAudit(ctx context.Context) {
+	u := ctx.Value("initiator").(User)
+
+	txn, err := pg.BeginTxx(ctx, &sql.TxOptions{
+		Isolation: sql.LevelRepeatableRead,
+		ReadOnly:  true,
+	})
+	if err != nil {
+		send(ctx, u, "audit: failed to start transaction: "+err.Error())
+		return
+	}
+	defer txn.Rollback()
+
+	var failures []string
+
+	// the proxy account only passes money along
+	if err := checkProxyBalance(txn); err != nil {
+		failures = append(failures, "proxy: "+err.Error())
+	}
+
+	// nobody can have spent more than they had
+	var negative []struct {
+		AccountId int   `db:"account_id"`
+		Balance   int64 `db:"balance"`
+	}
+	err = txn.Select(&negative, `
+SELECT account_id, balance::numeric(13) AS balance
+FROM lightning.balance
+WHERE balance < 0 AND account_id != $1
+    `, s.ProxyAccount)
+	if err != nil && err != sql.ErrNoRows {
+		failures = append(failures, "negative balances: "+err.Error())
+	}
+	for _, n := range negative {
+		failures = append(failures, fmt.Sprintf(
+			"negative balance: account %d has %.3f sat", n.AccountId, float64(n.Balance)/1000))
+	}
+
+	// all user balances must be backed by funds on the node
+	var usersTotal int64
+	err = txn.Get(&usersTotal, `
+SELECT coalesce(sum(balance), 0)::numeric(13) FROM lightning.balance
+    `)
+	if err != nil {
+		failures = append(failures, "users total: "+err.Error())
+	}
+
+	var nodeTotal int64
+	resp, err := ln().Call("get-info", map[string]interface{}{})
+	if retryAfterReconnect(err) {
+		resp, err = ln().Call("get-info", map[string]interface{}{})
+	}
+	if err != nil {
+		failures = append(failures, "node total: "+err.Error())
+	} else {
+		for _, balance := range gjson.ParseBytes(resp).Get("channels.#.balance").Array() {
+			nodeTotal += balance.Int()
+		}
+		if usersTotal > nodeTotal {
+			failures = append(failures, fmt.Sprintf(
+				"custody: users have %.3f sat but the node has only %.3f sat",
+				float64(usersTotal)/1000, float64(nodeTotal)/1000))
+		}
+	}
+
+	log.Info().Stringer("user", &u).Int("failures", len(failures)).
+		Int64("users", usersTotal).Int64("node", nodeTotal).Msg("audit")
+
+	report := fmt.Sprintf("users total: %.3f sat\nnode total: %.3f sat\n\n",
+		float64(usersTotal)/1000, float64(nodeTotal)/1000)
+	if len(failures) == 0 {
+		report += "all invariants hold."
+	} else {
+		report += strings.Join(failures, "\n")
+	}
+
+	send(ctx, u, report)
+}
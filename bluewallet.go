@@ -11,7 +11,6 @@ import (
 	"time"
 
 	"github.com/docopt/docopt-go"
-	decodepay "github.com/fiatjaf/ln-decodepay"
 	"github.com/fiatjaf/lntxbot/t"
 )
 
@@ -139,26 +138,26 @@ func registerBluewalletMethods() {
 		log.Debug().Str("bolt11", params.Invoice).Stringer("user", &user).
 			Msg("bluewallet /payinvoice")
 
+		if err := user.checkPaymentRate(); err != nil {
+			errorPaymentFailed(w, err)
+			return
+		}
+
 		decoded, _ := decodeInvoiceAsLndHub(params.Invoice)
-		var preimage string
 
-		go func() {
-			select {
-			case preimage = <-waitPaymentSuccess(decoded.PaymentHash):
-			case <-time.After(150 * time.Second):
-			}
-		}()
+		// start waiting before paying as internal payments resolve immediately
+		wait := waitPaymentSuccess(decoded.PaymentHash)
 
-		_, err = user.payInvoice(ctx, params.Invoice, 1000*amount)
+		_, err = user.payInvoice(ctx, params.Invoice, 1000*amount, nil)
 		if err != nil {
+			removeWaitingPaymentSuccess(decoded.PaymentHash, wait)
 			errorPaymentFailed(w, err)
 			return
 		}
 
-		select {
-		case preimage = <-waitPaymentSuccess(decoded.PaymentHash):
-		case <-time.After(5 * time.Second):
-		}
+		wctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+		defer cancel()
+		preimage, _ := awaitPaymentResult(wctx, decoded.PaymentHash, wait)
 
 		tx, _ := user.getTransaction(decoded.PaymentHash)
 
@@ -383,7 +382,7 @@ type LndHubDecoded struct {
 }
 
 func decodeInvoiceAsLndHub(bolt11 string) (LndHubDecoded, error) {
-	inv, err := decodepay.Decodepay(bolt11)
+	inv, err := decodeInvoice(bolt11)
 	if err != nil {
 		return LndHubDecoded{}, err
 	}
@@ -0,0 +1,109 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"sort"
+	"sync"
+	"time"
+)
+
+var errCircuitOpen = errors.New("host is failing, not calling it for now")
+
+// circuitBreaker wraps a transport and stops calling a host for
+// s.CircuitBreakerCooldown after s.CircuitBreakerFailures consecutive errors, so
+// a dead lnurl server doesn't keep goroutines stuck on timeouts. after the
+// cooldown a single call is let through to probe the host while the others
+// keep failing, it closes the circuit if it works or opens it again if not.
+type circuitBreaker struct {
+	next http.RoundTripper
+
+	sync.Mutex
+	hosts map[string]*hostCircuit
+}
+
+type hostCircuit struct {
+	failures  int
+	openUntil time.Time
+	probing   bool
+}
+
+// all breakers are kept here so the metrics can list open circuits
+var breakers []*circuitBreaker
+
+func newCircuitBreaker(next http.RoundTripper) *circuitBreaker {
+	if next == nil {
+		next = http.DefaultTransport
+	}
+	cb := &circuitBreaker{next: next, hosts: make(map[string]*hostCircuit)}
+	breakers = append(breakers, cb)
+	return cb
+}
+
+func (cb *circuitBreaker) RoundTrip(r *http.Request) (*http.Response, error) {
+	host := r.URL.Host
+
+	cb.Lock()
+	hc, ok := cb.hosts[host]
+	if !ok {
+		hc = &hostCircuit{}
+		cb.hosts[host] = hc
+	}
+	tripped := hc.failures >= s.CircuitBreakerFailures
+	if tripped && (hc.probing || time.Now().Before(hc.openUntil)) {
+		cb.Unlock()
+		return nil, fmt.Errorf("%s: %w", host, errCircuitOpen)
+	}
+	probe := tripped
+	hc.probing = probe
+	cb.Unlock()
+
+	resp, err := cb.next.RoundTrip(r)
+
+	cb.Lock()
+	defer cb.Unlock()
+	if probe {
+		hc.probing = false
+	}
+
+	if errors.Is(err, context.Canceled) {
+		// we gave up on it ourselves, that says nothing about the host
+		return resp, err
+	}
+
+	if err != nil || resp.StatusCode >= 500 {
+		hc.failures++
+		if hc.failures >= s.CircuitBreakerFailures {
+			hc.openUntil = time.Now().Add(s.CircuitBreakerCooldown)
+			log.Warn().Str("host", host).Int("failures", hc.failures).
+				Dur("cooldown", s.CircuitBreakerCooldown).Msg("circuit open")
+		}
+	} else {
+		if hc.failures >= s.CircuitBreakerFailures {
+			log.Info().Str("host", host).Msg("circuit closed")
+		}
+		hc.failures = 0
+		hc.openUntil = time.Time{}
+	}
+
+	return resp, err
+}
+
+// openCircuits returns the hosts we're currently not calling, sorted.
+func openCircuits() []string {
+	now := time.Now()
+	var open []string
+	for _, cb := range breakers {
+		cb.Lock()
+		for host, hc := range cb.hosts {
+			if now.Before(hc.openUntil) || hc.probing {
+				open = append(open, host)
+			}
+		}
+		cb.Unlock()
+	}
+	sort.Strings(open)
+	return open
+}
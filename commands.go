@@ -3,10 +3,13 @@ package main
 import (
 	"context"
 	"strings"
+	"time"
 
 	"github.com/docopt/docopt-go"
 	"github.com/fiatjaf/lntxbot/t"
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api"
 	"github.com/kballard/go-shellquote"
+	"github.com/lucsky/cuid"
 )
 
 type def struct {
@@ -32,6 +35,10 @@ var methods = []def{
 		aliases: []string{"lnurl"},
 		argstr:  "[--anonymous] <lnurl>",
 	},
+	def{
+		aliases: []string{"lnurlauth"},
+		argstr:  "<domain>",
+	},
 	def{
 		aliases:        []string{"receive", "invoice", "fund"},
 		argstr:         "(lnurl | (any | <satoshis>) [<description>...])",
@@ -39,14 +46,46 @@ var methods = []def{
 		inline_example: "invoice <satoshis>",
 	},
 	def{
-		aliases: []string{"pay", "decode", "paynow", "withdraw"},
-		argstr:  "(lnurl <satoshis> | [now] [<invoice>] [<satoshis>])",
+		aliases: []string{"pay", "paynow", "withdraw"},
+		argstr:  "(lnurl <satoshis> | [now] [<invoice>] [<satoshis>] [--max-fee=<fee>])",
+	},
+	def{
+		aliases: []string{"address", "lightningaddress"},
+		argstr:  "[<name>]",
+	},
+	def{
+		aliases: []string{"tipjar"},
+		argstr:  "[(min | max) <satoshis> | description <description>... | image [<url>] | reset]",
+	},
+	def{
+		aliases: []string{"nodeinfo"},
+		argstr:  "<pubkey>",
+	},
+	def{
+		aliases: []string{"decode"},
+		argstr:  "[<text>...]",
+	},
+	def{
+		aliases: []string{"payquote"},
+		argstr:  "<invoice> [<satoshis>]",
+	},
+	def{
+		aliases: []string{"setmaxfee"},
+		argstr:  "[off | <fee>]",
+	},
+	def{
+		aliases: []string{"webhook"},
+		argstr:  "[off | <url>]",
 	},
 	def{
 		aliases:        []string{"send", "tip", "sendanonymously", "honk"},
 		argstr:         "[anonymously] <satoshis> [<receiver>] [<description>...] [--anonymous]",
 		inline_example: "give <satoshis> <username>",
 	},
+	def{
+		aliases: []string{"sendmany"},
+		argstr:  "<payments>...",
+	},
 	def{
 		aliases: []string{"balance"},
 		argstr:  "[apps]",
@@ -59,7 +98,7 @@ var methods = []def{
 		argstr:  "<hash>",
 	},
 	def{
-		aliases: []string{"transactions"},
+		aliases: []string{"transactions", "history"},
 		argstr:  "[<tag>] [--in] [--out]",
 	},
 	def{
@@ -80,9 +119,24 @@ var methods = []def{
 		inline:         true,
 		inline_example: "giveflip <satoshis> <num_participants>",
 	},
+	def{
+		aliases: []string{"freeze"},
+	},
+	def{
+		aliases: []string{"unfreeze"},
+		argstr:  "[<code>]",
+	},
+	def{
+		aliases: []string{"twofactor", "2fa"},
+		argstr:  "[on <satoshis> | verify <code> | off <code>]",
+	},
+	def{
+		aliases: []string{"drop", "tipball"},
+		argstr:  "<satoshis> <num_participants>",
+	},
 	def{
 		aliases: []string{"fundraise", "crowdfund"},
-		argstr:  "<satoshis> <num_participants> <receiver>",
+		argstr:  "<satoshis> <num_participants> <receiver> [<title>...]",
 	},
 	def{
 		aliases: []string{"hide"},
@@ -119,7 +173,38 @@ var methods = []def{
 	},
 	def{
 		aliases: []string{"toggle"},
-		argstr:  "(ticket [<satoshis>] | renamable [<satoshis>] | spammy | expensive [<satoshis> <pattern>] | language [<lang>] | coinflips)",
+		argstr:  "(ticket [<satoshis>] | renamable [<satoshis>] | spammy | tiplimit [<satoshis>] | expensive [<satoshis> <pattern>] | language [<lang>] | currency [<currency>] | confirm [<satoshis>] | alert [<satoshis>] | description [<description>...] | qr | roman | coinflips | treasury)",
+	},
+	def{
+		aliases: []string{"treasury"},
+		argstr:  "[<satoshis>]",
+	},
+	def{
+		aliases: []string{"schedule"},
+		argstr:  "[(daily | weekly | monthly) <satoshis> <receiver> | cancel <id>]",
+	},
+	def{
+		aliases: []string{"pricealert"},
+		argstr:  "[(above | below) <price> [<currency>] | remove <id>]",
+	},
+	def{
+		aliases: []string{"menu"},
+		argstr:  "[add <name> <satoshis> | remove <name>]",
+	},
+	def{
+		aliases: []string{"stats"},
+	},
+	def{
+		aliases: []string{"language"},
+		argstr:  "[<lang>]",
+	},
+	def{
+		aliases: []string{"qr"},
+		argstr:  "[<text>...]",
+	},
+	def{
+		aliases: []string{"convert"},
+		argstr:  "<amount>...",
 	},
 	def{
 		aliases: []string{"satoshis", "calc"},
@@ -240,7 +325,7 @@ func handleHelp(ctx context.Context, method string) (handled bool) {
 			}
 		}
 	} else {
-		similar := findSimilar(method, commandList)
+		similar := findSimilar(method, commandList, 5)
 		if len(similar) > 0 {
 			send(ctx, t.HELPSIMILAR, t.T{
 				"Method":  method,
@@ -270,3 +355,71 @@ func handleHelp(ctx context.Context, method string) (handled bool) {
 	send(ctx, t.HELPMETHOD, params)
 	return true
 }
+
+// handleDidYouMean is called when a command fails to parse on a private chat.
+// If the method itself is unknown we offer buttons with the most similar ones,
+// which when clicked will run the corrected command with the same arguments.
+func handleDidYouMean(ctx context.Context, messageText string) (handled bool) {
+	u := ctx.Value("initiator").(User)
+
+	first := strings.Split(messageText, " ")[0]
+	method := strings.Split(strings.Split(first[1:], "_")[0], "@")[0]
+	if _, ok := commandIndex[strings.ToLower(method)]; ok || method == "" {
+		// the method is right, the arguments are wrong
+		return handleHelp(ctx, method)
+	}
+
+	similar := findSimilar(strings.ToLower(method), commandList, 3)
+	if len(similar) == 0 {
+		return false
+	}
+
+	// keep the original arguments, but drop the bot username if any
+	rest := strings.TrimPrefix(messageText[1+len(method):], "@"+bot.Self.UserName)
+
+	row := make([]tgbotapi.InlineKeyboardButton, 0, len(similar))
+	for _, suggestion := range similar {
+		key := cuid.Slug()
+		rds.Set("didyoumean:"+key, "/"+suggestion+rest, time.Minute*10)
+		row = append(row, tgbotapi.NewInlineKeyboardButtonData(
+			"/"+suggestion, "didyoumean="+key))
+	}
+
+	go u.track("didyoumean", map[string]interface{}{"method": method})
+
+	send(ctx, t.HELPSIMILAR, t.T{
+		"Method":  method,
+		"Similar": similar,
+	}, &tgbotapi.InlineKeyboardMarkup{
+		InlineKeyboard: [][]tgbotapi.InlineKeyboardButton{row},
+	})
+	return true
+}
+
+func handleDidYouMeanCallback(ctx context.Context) {
+	u := ctx.Value("initiator").(User)
+	cb := ctx.Value("callbackQuery").(*tgbotapi.CallbackQuery)
+
+	removeKeyboardButtons(ctx)
+	text, err := rds.Get("didyoumean:" + cb.Data[11:]).Result()
+	if err != nil || cb.Message == nil {
+		send(ctx, t.CALLBACKEXPIRED)
+		return
+	}
+
+	go u.track("didyoumean accepted", map[string]interface{}{
+		"command": strings.Split(text, " ")[0],
+	})
+
+	// run the corrected command as if the user had typed it
+	handleTelegramMessage(
+		context.WithValue(context.Background(), "origin", "telegram"),
+		&tgbotapi.Message{
+			MessageID: cb.Message.MessageID,
+			From:      cb.From,
+			Chat:      cb.Message.Chat,
+			Date:      int(time.Now().Unix()),
+			Text:      text,
+		},
+	)
+}
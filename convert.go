@@ -0,0 +1,44 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/docopt/docopt-go"
+	"github.com/fiatjaf/lntxbot/t"
+)
+
+func handleConvert(ctx context.Context, opts docopt.Opts) {
+	u := ctx.Value("initiator").(User)
+	raw := strings.Join(opts["<amount>"].([]string), " ")
+
+	// fiat to satoshis
+	msats, fiat, currency, err := parseFiatAmount(raw)
+	if currency != "" {
+		if err != nil {
+			send(ctx, u, t.ERROR, t.T{"Err": err.Error()})
+			return
+		}
+
+		go u.track("convert", map[string]interface{}{"from": currency})
+		send(ctx, t.CONVERTMSG, t.T{
+			"Sats": float64(msats) / 1000,
+			"Fiat": fmt.Sprintf("%.2f %s", fiat, currency),
+		})
+		return
+	}
+
+	// satoshis to fiat
+	msats, err = parseAmountString(ctx, raw)
+	if err != nil {
+		send(ctx, u, t.ERROR, t.T{"Err": err.Error()})
+		return
+	}
+
+	go u.track("convert", map[string]interface{}{"from": "sat"})
+	send(ctx, t.CONVERTMSG, t.T{
+		"Sats": float64(msats) / 1000,
+		"Fiat": getFiatPrice(msats, u.Currency),
+	})
+}
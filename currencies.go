@@ -2,12 +2,19 @@ package main
 
 import (
 	"context"
+	"database/sql"
 	"errors"
+	"fmt"
 	"io/ioutil"
+	"math"
 	"net/http"
+	"regexp"
+	"strconv"
 	"strings"
+	"sync"
 	"time"
 
+	cmap "github.com/orcaman/concurrent-map"
 	"github.com/tidwall/gjson"
 )
 
@@ -178,63 +185,205 @@ var CURRENCIES = []string{
 	"ZWL",
 }
 
+// fiat rates are cached for one hour, each currency on its own entry
+type fiatRate struct {
+	MsatPerFiat int64
+	FetchedAt   time.Time
+}
+
+var fiatRates = cmap.New() // make(map[string]fiatRate)
+
+// a fetch of a rate that isn't cached, concurrent callers asking for the same
+// currency wait for it instead of all hitting the price sources at once
+type fiatRateFetch struct {
+	done        chan struct{}
+	msatPerFiat int64
+	err         error
+}
+
+var (
+	fiatRateFetches      = make(map[string]*fiatRateFetch)
+	fiatRateFetchesMutex sync.Mutex
+)
+
+// currentFiatRate is stored along with transactions, in the currency of the
+// user who made them, so we can later display their fiat value as it was at
+// the time. Both are null if we don't know the rate.
+func currentFiatRate(currency string) (sql.NullString, sql.NullInt64) {
+	currency = strings.ToUpper(currency)
+	if !stringIsIn(currency, CURRENCIES) {
+		currency = "USD"
+	}
+
+	rate, err := getMsatsPerFiatUnit(currency)
+	if err != nil {
+		log.Warn().Err(err).Str("currency", currency).
+			Msg("failed to get rate to store with transaction")
+		return sql.NullString{}, sql.NullInt64{}
+	}
+	return sql.NullString{String: currency, Valid: true},
+		sql.NullInt64{Int64: rate, Valid: true}
+}
+
 func getMsatsPerFiatUnit(currencyCode string) (int64, error) {
 	lower := strings.ToLower(currencyCode)
 	upper := strings.ToUpper(currencyCode)
 
-	ctx, cancel := context.WithCancel(context.Background())
+	if irate, ok := fiatRates.Get(upper); ok {
+		rate := irate.(fiatRate)
+		if time.Since(rate.FetchedAt) < time.Hour {
+			return rate.MsatPerFiat, nil
+		}
+	}
+
+	fiatRateFetchesMutex.Lock()
+	if fetch, ok := fiatRateFetches[upper]; ok {
+		fiatRateFetchesMutex.Unlock()
+		<-fetch.done
+		return fetch.msatPerFiat, fetch.err
+	}
+	fetch := &fiatRateFetch{done: make(chan struct{})}
+	fiatRateFetches[upper] = fetch
+	fiatRateFetchesMutex.Unlock()
 
-	defer func() {
-		cancel()
-	}()
+	fetch.msatPerFiat, fetch.err = fetchMsatsPerFiatUnit(lower, upper)
 
-	bitfinex := getPrice(ctx, "https://api.bitfinex.com/v1/pubticker/btc"+lower, "last_price")
-	bitstamp := getPrice(ctx, "https://www.bitstamp.net/api/v2/ticker/btc"+lower, "last")
-	coinbase := getPrice(ctx, "https://api.coinbase.com/v2/exchange-rates?currency=BTC", "data.rates."+upper)
-	coinmate := getPrice(ctx, "https://coinmate.io/api/ticker?currencyPair=BTC_"+upper, "data.last")
-	kraken := getPrice(ctx, "https://api.kraken.com/0/public/Ticker?pair=XBT"+upper, "result.XXBTZ"+upper+".c.0")
+	fiatRateFetchesMutex.Lock()
+	delete(fiatRateFetches, upper)
+	fiatRateFetchesMutex.Unlock()
+	close(fetch.done)
 
+	return fetch.msatPerFiat, fetch.err
+}
+
+// fetchMsatsPerFiatUnit gets a fresh rate from the price sources and caches it.
+func fetchMsatsPerFiatUnit(lower, upper string) (int64, error) {
+	// try each source in order until one of them gives us a price
 	var fiatPerBTC float64
+	for _, source := range priceSources {
+		ctx, cancel := context.WithTimeout(context.Background(), time.Second*3)
+		price, err := getPrice(ctx, source.url(lower, upper), source.pattern(lower, upper))
+		cancel()
+		if err != nil {
+			log.Debug().Err(err).Str("source", source.name).Str("currency", upper).
+				Msg("failed to get BTC price")
+			continue
+		}
 
-	select {
-	case fiatPerBTC = <-bitfinex:
-	case fiatPerBTC = <-bitstamp:
-	case fiatPerBTC = <-coinbase:
-	case fiatPerBTC = <-coinmate:
-	case fiatPerBTC = <-kraken:
-	case <-time.After(time.Second * 3):
-		return 0, errors.New("couldn't get BTC price for " + currencyCode)
+		log.Debug().Str("source", source.name).Str("currency", upper).
+			Float64("price", price).Msg("got BTC price")
+		fiatPerBTC = price
+		break
+	}
+	if fiatPerBTC == 0 {
+		return 0, errors.New("couldn't get BTC price for " + upper)
 	}
 
-	msatPerFiat := 100000000000 / fiatPerBTC
-	return int64(msatPerFiat), nil
+	msatPerFiat := int64(100000000000 / fiatPerBTC)
+	fiatRates.Set(upper, fiatRate{msatPerFiat, time.Now()})
+	return msatPerFiat, nil
 }
 
-func getPrice(ctx context.Context, url string, pattern string) <-chan float64 {
-	result := make(chan float64)
-	go func() {
-		req, err := http.NewRequestWithContext(ctx, "GET", url, nil)
-		if err != nil {
-			return
-		}
-		resp, err := http.DefaultClient.Do(req)
-		if err != nil {
-			return
-		}
-		if resp.StatusCode >= 300 {
-			return
-		}
-		data, err := ioutil.ReadAll(resp.Body)
-		if err != nil {
-			return
-		}
+type priceSource struct {
+	name    string
+	url     func(lower, upper string) string
+	pattern func(lower, upper string) string
+}
 
-		fiatPerBTC := gjson.GetBytes(data, pattern).Float()
-		if fiatPerBTC <= 0 {
-			return
-		}
+// sources are tried in this order
+var priceSources = []priceSource{
+	{
+		"bitstamp",
+		func(lower, _ string) string { return "https://www.bitstamp.net/api/v2/ticker/btc" + lower },
+		func(_, _ string) string { return "last" },
+	},
+	{
+		"coinbase",
+		func(_, _ string) string { return "https://api.coinbase.com/v2/exchange-rates?currency=BTC" },
+		func(_, upper string) string { return "data.rates." + upper },
+	},
+	{
+		"kraken",
+		func(_, upper string) string { return "https://api.kraken.com/0/public/Ticker?pair=XBT" + upper },
+		func(_, upper string) string { return "result.XXBTZ" + upper + ".c.0" },
+	},
+	{
+		"bitfinex",
+		func(lower, _ string) string { return "https://api.bitfinex.com/v1/pubticker/btc" + lower },
+		func(_, _ string) string { return "last_price" },
+	},
+	{
+		"coinmate",
+		func(_, upper string) string { return "https://coinmate.io/api/ticker?currencyPair=BTC_" + upper },
+		func(_, _ string) string { return "data.last" },
+	},
+}
+
+// priceClient goes through a circuit breaker, set up on main
+var priceClient = &http.Client{}
+
+func getPrice(ctx context.Context, url string, pattern string) (float64, error) {
+	req, err := http.NewRequestWithContext(ctx, "GET", url, nil)
+	if err != nil {
+		return 0, err
+	}
+	resp, err := priceClient.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode >= 300 {
+		return 0, fmt.Errorf("status %d", resp.StatusCode)
+	}
+	data, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return 0, err
+	}
+
+	fiatPerBTC := gjson.GetBytes(data, pattern).Float()
+	if fiatPerBTC <= 0 {
+		return 0, errors.New("no price in response")
+	}
+
+	return fiatPerBTC, nil
+}
+
+var fiatAmountRe = regexp.MustCompile(`^\s*(\d+(?:[.,]\d+)?)\s*([a-zA-Z]{3})\s*$`)
+
+// parseFiatAmount converts amounts like "5 usd" or "2.50eur" to msatoshis.
+// currency will be empty if the text doesn't look like a fiat amount at all.
+// The result is rounded to the satoshi so the same fiat amount always gives
+// the same value for as long as the rate is cached.
+func parseFiatAmount(amt string) (msats int64, fiat float64, currency string, err error) {
+	match := fiatAmountRe.FindStringSubmatch(amt)
+	if match == nil {
+		return
+	}
+
+	currency = strings.ToUpper(match[2])
+	if !stringIsIn(currency, CURRENCIES) {
+		return 0, 0, "", nil
+	}
+
+	fiat, err = strconv.ParseFloat(strings.Replace(match[1], ",", ".", 1), 64)
+	if err != nil || fiat <= 0 {
+		return 0, 0, currency, errors.New("invalid " + currency + " amount.")
+	}
+
+	msatPerFiat, err := getMsatsPerFiatUnit(currency)
+	if err != nil {
+		return 0, 0, currency, err
+	}
+
+	sats := math.Round(fiat * float64(msatPerFiat) / 1000)
+	if sats > math.MaxInt64/1000 {
+		return 0, 0, currency, errors.New("amount too large")
+	}
+
+	msats = int64(sats) * 1000
+	if msats < 1000 {
+		return 0, 0, currency, fmt.Errorf("amount too small: %.2f %s", fiat, currency)
+	}
 
-		result <- fiatPerBTC
-	}()
-	return result
+	return msats, fiat, currency, nil
 }
@@ -0,0 +1,61 @@
+package main
+
+import (
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// countingPriceSource answers every price query with the same price after a
+// delay, counting how many requests it got.
+type countingPriceSource struct {
+	delay    time.Duration
+	requests int32
+}
+
+func (p *countingPriceSource) RoundTrip(r *http.Request) (*http.Response, error) {
+	atomic.AddInt32(&p.requests, 1)
+	time.Sleep(p.delay)
+	return &http.Response{
+		StatusCode: 200,
+		Body:       ioutil.NopCloser(strings.NewReader(`{"last":"50000"}`)),
+		Request:    r,
+	}, nil
+}
+
+func TestGetMsatsPerFiatUnitConcurrent(t *testing.T) {
+	source := &countingPriceSource{delay: 50 * time.Millisecond}
+	oldTransport := priceClient.Transport
+	defer func() { priceClient.Transport = oldTransport }()
+	priceClient.Transport = source
+
+	fiatRates.Remove("EUR")
+	defer fiatRates.Remove("EUR")
+
+	const callers = 20
+	rates := make([]int64, callers)
+	errs := make([]error, callers)
+
+	var wg sync.WaitGroup
+	for i := 0; i < callers; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			rates[i], errs[i] = getMsatsPerFiatUnit("eur")
+		}(i)
+	}
+	wg.Wait()
+
+	if n := atomic.LoadInt32(&source.requests); n != 1 {
+		t.Errorf("%d callers made %d requests to the price sources, want 1", callers, n)
+	}
+	for i := 0; i < callers; i++ {
+		if errs[i] != nil || rates[i] != 2000000 {
+			t.Errorf("caller %d got (%d, %v), want (2000000, nil)", i, rates[i], errs[i])
+		}
+	}
+}
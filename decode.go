@@ -0,0 +1,98 @@
+package main
+
+import (
+	"context"
+
+	"github.com/docopt/docopt-go"
+	"github.com/fiatjaf/go-lnurl"
+	"github.com/fiatjaf/lntxbot/t"
+)
+
+// handleDecode shows what is inside an invoice or an lnurl without acting on
+// it. for lnurls only the first request is made, which never pays or logs in.
+func handleDecode(ctx context.Context, opts docopt.Opts) {
+	u := ctx.Value("initiator").(User)
+
+	text := trimLightningScheme(
+		getVariadicFieldOrReplyToContent(incomingMessage(ctx), opts, "<text>"))
+	if text == "" {
+		handleHelp(ctx, "decode")
+		return
+	}
+
+	go u.track("decode", nil)
+
+	if bolt11, ok := getBolt11(text); ok {
+		inv, err := decodeInvoice(bolt11)
+		if err != nil {
+			send(ctx, u, t.FAILEDDECODE, t.T{"Err": messageFromError(ctx, ErrInvalidInvoice.withDetail(err))})
+			return
+		}
+
+		send(ctx, u, t.DECODEINVOICE, invoiceDetails(ctx, inv), ctx.Value("message"))
+		return
+	}
+
+	lnurltext, ok := lnurl.FindLNURLInText(text)
+	if !ok {
+		lnurltext, ok = findLUD17(text)
+	}
+	if !ok {
+		if name, domain, okW := parseLightningAddress(text); okW {
+			lnurltext, ok = name+"@"+domain, true
+		}
+	}
+	if !ok {
+		send(ctx, u, t.FAILEDDECODE, t.T{"Err": "no invoice or lnurl found."})
+		return
+	}
+
+	_, iparams, err := lnurl.HandleLNURL(lnurltext)
+	if err != nil {
+		if lnurlerr, ok := err.(lnurl.LNURLErrorResponse); ok {
+			send(ctx, u, t.LNURLERROR, t.T{
+				"Host":   lnurlerr.URL.Hostname(),
+				"Reason": lnurlerr.Reason,
+			})
+			return
+		}
+		send(ctx, u, t.FAILEDDECODE, t.T{"Err": err.Error()})
+		return
+	}
+
+	var data t.T
+	switch params := iparams.(type) {
+	case lnurl.LNURLAuthParams:
+		data = t.T{
+			"Kind":     "lnurl-auth",
+			"Host":     params.Host,
+			"Callback": params.Callback,
+		}
+	case lnurl.LNURLWithdrawResponse:
+		data = t.T{
+			"Kind":        "lnurl-withdraw",
+			"Host":        params.CallbackURL.Hostname(),
+			"Callback":    params.Callback,
+			"Min":         float64(params.MinWithdrawable) / 1000,
+			"Max":         float64(params.MaxWithdrawable) / 1000,
+			"Description": params.DefaultDescription,
+		}
+	case lnurl.LNURLPayParams:
+		data = t.T{
+			"Kind":        "lnurl-pay",
+			"Host":        params.CallbackURL().Hostname(),
+			"Callback":    params.Callback,
+			"Min":         float64(params.MinSendable) / 1000,
+			"Max":         float64(params.MaxSendable) / 1000,
+			"Description": params.Metadata.Description,
+			"Long":        params.Metadata.LongDescription,
+			"Identifier":  params.Metadata.LightningAddress,
+			"Comment":     params.CommentAllowed,
+		}
+	default:
+		send(ctx, u, t.LNURLUNSUPPORTED, ctx.Value("message"))
+		return
+	}
+
+	send(ctx, u, t.DECODELNURL, data, ctx.Value("message"))
+}
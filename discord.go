@@ -1,6 +1,7 @@
 package main
 
 import (
+	"encoding/json"
 	"errors"
 	"regexp"
 	"strings"
@@ -96,6 +97,78 @@ func examineDiscordUsername(name string) (u *User, err error) {
 	return &user, nil
 }
 
+// the discordgo version we use doesn't know about interactions, so we call
+// the API directly for these
+const discordAPI = "https://discord.com/api/v8/"
+
+type discordSlashCommand struct {
+	Name        string                      `json:"name"`
+	Description string                      `json:"description"`
+	Options     []discordSlashCommandOption `json:"options,omitempty"`
+}
+
+type discordSlashCommandOption struct {
+	Type        int    `json:"type"`
+	Name        string `json:"name"`
+	Description string `json:"description"`
+	Required    bool   `json:"required"`
+}
+
+const discordOptionString = 3
+
+// discordSlashCommands are turned into the same text commands we parse from
+// messages, so options must be listed in the order the arguments are expected.
+var discordSlashCommands = []discordSlashCommand{
+	{Name: "balance", Description: "Show your balance."},
+	{Name: "pay", Description: "Pay a Lightning invoice.", Options: []discordSlashCommandOption{
+		{discordOptionString, "invoice", "The invoice to pay.", true},
+		{discordOptionString, "satoshis", "Amount to pay, for invoices without one.", false},
+	}},
+	{Name: "invoice", Description: "Generate a Lightning invoice.", Options: []discordSlashCommandOption{
+		{discordOptionString, "satoshis", "Amount to receive, or \"any\".", true},
+		{discordOptionString, "description", "Description of the invoice.", false},
+	}},
+	{Name: "help", Description: "Show help about a command.", Options: []discordSlashCommandOption{
+		{discordOptionString, "command", "The command to get help for.", false},
+	}},
+}
+
+// registerDiscordSlashCommands replaces all global commands of the bot with
+// discordSlashCommands.
+func registerDiscordSlashCommands(appId string) error {
+	_, err := discord.RequestWithBucketID("PUT",
+		discordAPI+"applications/"+appId+"/commands",
+		discordSlashCommands, discordAPI+"applications/commands")
+	return err
+}
+
+// respondDiscordInteraction answers an interaction with a text message and
+// returns that message, as the interaction itself is not a message.
+func respondDiscordInteraction(
+	appId, id, token, content string,
+) (*discordgo.Message, error) {
+	_, err := discord.RequestWithBucketID("POST",
+		discordAPI+"interactions/"+id+"/"+token+"/callback",
+		map[string]interface{}{
+			"type": 4, // channel message with source
+			"data": map[string]interface{}{"content": content},
+		}, discordAPI+"interactions/callback")
+	if err != nil {
+		return nil, err
+	}
+
+	b, err := discord.RequestWithBucketID("GET",
+		discordAPI+"webhooks/"+appId+"/"+token+"/messages/@original",
+		nil, discordAPI+"webhooks/messages")
+	if err != nil {
+		return nil, err
+	}
+
+	var message discordgo.Message
+	err = json.Unmarshal(b, &message)
+	return &message, err
+}
+
 var guildLocaleCache = cmap.New()
 var guildSpamChannelCache = cmap.New()
 
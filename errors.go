@@ -1,9 +1,95 @@
 package main
 
-import "errors"
+import (
+	"context"
+	"errors"
+	"strings"
+
+	"github.com/fiatjaf/lntxbot/t"
+)
 
 var (
-	ErrInsufficientBalance = errors.New("Insufficient balance.")
-	ErrDatabase            = errors.New("Database error.")
-	ErrInvalidAmount       = errors.New("Invalid amount.")
+	ErrInsufficientBalance = &AppError{Key: t.ERRINSUFFICIENTBALANCE}
+	ErrDatabase            = &AppError{Key: t.ERRDATABASE}
+	ErrInvalidAmount       = &AppError{Key: t.ERRINVALIDAMOUNT}
+	ErrInvoiceExpired      = &AppError{Key: t.ERRINVOICEEXPIRED}
+	ErrNoRoute             = &AppError{Key: t.ERRNOROUTE}
+	ErrTimeout             = &AppError{Key: t.ERRTIMEOUT}
+	ErrLightningNode       = &AppError{Key: t.ERRLIGHTNINGNODE}
+	ErrAlreadyPaying       = &AppError{Key: t.ERRALREADYPAYING}
+	ErrInvoiceAmount       = &AppError{Key: t.ERRINVOICEAMOUNT}
+	ErrWrongNetwork        = &AppError{Key: t.ERRWRONGNETWORK}
+	ErrTwoFactorRequired   = &AppError{Key: t.ERRTWOFACTORREQUIRED}
+	ErrAccountFrozen       = &AppError{Key: t.ERRACCOUNTFROZEN}
+	ErrRateLimited         = &AppError{Key: t.ERRRATELIMITED}
+	ErrUnexpected          = &AppError{Key: t.ERRUNEXPECTED}
+	ErrPayYourself         = &AppError{Key: t.ERRPAYYOURSELF}
+	ErrInvalidInvoice      = &AppError{Key: t.ERRINVALIDINVOICE}
+	ErrUnknownInvoice      = &AppError{Key: t.ERRUNKNOWNINVOICE}
+	ErrInternalAmount      = &AppError{Key: t.ERRINTERNALAMOUNT}
+	ErrFeeLimit            = &AppError{Key: t.ERRFEELIMIT}
+	ErrInvalidFeeLimit     = &AppError{Key: t.ERRINVALIDFEELIMIT}
 )
+
+// AppError is an error with a translatable message that is safe to show to
+// users and an internal detail that is only logged.
+type AppError struct {
+	Key    t.Key
+	Data   t.T
+	Detail error
+}
+
+// Error renders the user-facing message in the default language.
+func (e *AppError) Error() string {
+	return translateTemplate(context.Background(), e.Key, e.Data)
+}
+
+func (e *AppError) Unwrap() error { return e.Detail }
+
+// Is makes errors.Is(err, ErrDatabase) work on errors created with withDetail.
+func (e *AppError) Is(target error) bool {
+	other, ok := target.(*AppError)
+	return ok && other.Key == e.Key
+}
+
+// withDetail returns a copy of the error carrying an internal detail.
+func (e *AppError) withDetail(detail error) *AppError {
+	return &AppError{Key: e.Key, Data: e.Data, Detail: detail}
+}
+
+// withData returns a copy of the error with data for its message template.
+func (e *AppError) withData(data t.T) *AppError {
+	return &AppError{Key: e.Key, Data: data, Detail: e.Detail}
+}
+
+// lightningNodeError wraps an error coming from the lightning node, which
+// can't be translated, with a localized prefix.
+func lightningNodeError(err error) *AppError {
+	if strings.Contains(strings.ToLower(err.Error()), "route") {
+		return ErrNoRoute.withDetail(err)
+	}
+	return ErrLightningNode.withData(t.T{"Message": err.Error()}).withDetail(err)
+}
+
+// messageFromError returns the message that should be shown to the user for
+// any error, logging the internal details of an AppError. errors that aren't
+// an AppError aren't meant for users, they are logged and a generic message
+// is shown instead.
+func messageFromError(ctx context.Context, err error) string {
+	var apperr *AppError
+	if errors.As(err, &apperr) {
+		if apperr.Detail != nil {
+			log.Warn().Err(apperr.Detail).Str("key", string(apperr.Key)).
+				Msg("error detail")
+		}
+		return translateTemplate(ctx, apperr.Key, apperr.Data)
+	}
+
+	if errors.Is(err, context.DeadlineExceeded) {
+		log.Warn().Err(err).Msg("timeout error")
+		return translateTemplate(ctx, t.ERRTIMEOUT, nil)
+	}
+
+	log.Warn().Err(err).Msg("unexpected error shown to user")
+	return translateTemplate(ctx, t.ERRUNEXPECTED, nil)
+}
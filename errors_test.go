@@ -0,0 +1,37 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+)
+
+func TestMessageFromError(t *testing.T) {
+	ctx := context.Background()
+	secret := errors.New("pq: duplicate key value violates unique constraint")
+
+	tests := []struct {
+		err     error
+		message string
+	}{
+		{ErrInsufficientBalance, "Insufficient balance."},
+		{ErrDatabase.withDetail(secret), "Database error."},
+		{fmt.Errorf("paying: %w", ErrPayYourself), "Can't pay yourself."},
+		{context.DeadlineExceeded, "Operation has timed out."},
+		{imageTimeoutError("https://example.com/a.png", context.DeadlineExceeded),
+			"Operation has timed out after 20 seconds."},
+		{secret, ErrUnexpected.Error()},
+	}
+
+	for _, test := range tests {
+		message := messageFromError(ctx, test.err)
+		if message != test.message {
+			t.Errorf("messageFromError(%q) = %q, want %q", test.err, message, test.message)
+		}
+		if strings.Contains(message, "pq:") {
+			t.Errorf("messageFromError(%q) leaked the internal error", test.err)
+		}
+	}
+}
@@ -0,0 +1,176 @@
+package main
+
+import (
+	"context"
+	"strconv"
+	"strings"
+
+	"github.com/docopt/docopt-go"
+	"github.com/fiatjaf/lntxbot/t"
+)
+
+// FeeLimit is the maximum routing fee a user accepts to pay, either as an
+// absolute amount or as a percentage of the amount being paid.
+type FeeLimit struct {
+	Msatoshi int64
+	Percent  float64
+}
+
+func parseFeeLimit(raw string) (*FeeLimit, error) {
+	raw = strings.TrimSpace(raw)
+	if raw == "" {
+		return nil, nil
+	}
+
+	if strings.HasSuffix(raw, "%") {
+		percent, err := strconv.ParseFloat(strings.TrimSuffix(raw, "%"), 64)
+		if err != nil || percent < 0 || percent > 100 {
+			return nil, ErrInvalidFeeLimit
+		}
+		return &FeeLimit{Percent: percent}, nil
+	}
+
+	sats, err := strconv.ParseFloat(raw, 64)
+	if err != nil || sats < 0 {
+		return nil, ErrInvalidFeeLimit
+	}
+	return &FeeLimit{Msatoshi: int64(sats * 1000)}, nil
+}
+
+func (fl FeeLimit) String() string {
+	if fl.Percent != 0 {
+		return strconv.FormatFloat(fl.Percent, 'f', -1, 64) + "%"
+	}
+	return strconv.FormatFloat(float64(fl.Msatoshi)/1000, 'f', -1, 64) + " sat"
+}
+
+// maxFee returns the maximum fee allowed for a payment of the given amount.
+func (fl FeeLimit) maxFee(msatoshi int64) int64 {
+	if fl.Percent != 0 {
+		return int64(float64(msatoshi) * fl.Percent / 100)
+	}
+	return fl.Msatoshi
+}
+
+// minPaymentFee is the least we charge for an outgoing payment, whatever the
+// node paid in routing fees.
+func minPaymentFee(msatoshi int64) int64 {
+	return int64(float64(msatoshi) * 0.003)
+}
+
+// checkFeeLimit fails if the limit is below what a payment of this amount
+// costs at least. cliche doesn't take a maximum fee, so the routing fee can't
+// be checked before paying, the limit is enforced on what is charged instead
+// (see chargedFee).
+func checkFeeLimit(limit *FeeLimit, msatoshi int64) error {
+	if limit == nil {
+		return nil
+	}
+
+	if max, min := limit.maxFee(msatoshi), minPaymentFee(msatoshi); max < min {
+		return ErrFeeLimit.withData(t.T{
+			"Min":   float64(min) / 1000,
+			"Max":   float64(max) / 1000,
+			"Limit": limit.String(),
+		})
+	}
+
+	return nil
+}
+
+// heldFee is what is held from the balance for the fee of an external payment
+// while it is in flight: the reserve, or the user limit if it is lower.
+func heldFee(limit *FeeLimit, msatoshi int64) int64 {
+	reserve := feeReserve(msatoshi)
+	if limit != nil {
+		if max := limit.maxFee(msatoshi); max < reserve {
+			return max
+		}
+	}
+	return reserve
+}
+
+// chargedFee is the fee charged once a payment succeeds: what the node paid,
+// not less than minPaymentFee and not more than maxFee when the user had a
+// limit. whatever the node paid above the limit is on us.
+func chargedFee(msatoshi int64, paid int64, maxFee *int64) int64 {
+	fee := paid
+	if min := minPaymentFee(msatoshi); fee < min {
+		fee = min
+	}
+	if maxFee != nil && fee > *maxFee {
+		fee = *maxFee
+	}
+	return fee
+}
+
+// resolveFeeLimit returns the limit given for a payment or, without one, the
+// user default.
+func resolveFeeLimit(given *FeeLimit, userDefault func() *FeeLimit) *FeeLimit {
+	if given != nil {
+		return given
+	}
+	return userDefault()
+}
+
+func (u User) getFeeLimit() *FeeLimit {
+	var raw string
+	err := pg.Get(&raw, "SELECT max_fee FROM account WHERE id = $1", u.Id)
+	if err != nil {
+		log.Warn().Err(err).Stringer("user", &u).Msg("failed to load max fee")
+		return nil
+	}
+
+	limit, _ := parseFeeLimit(raw)
+	return limit
+}
+
+func (u User) setFeeLimit(limit *FeeLimit) (err error) {
+	raw := ""
+	if limit != nil {
+		raw = strings.TrimSuffix(limit.String(), " sat")
+	}
+
+	_, err = pg.Exec("UPDATE account SET max_fee = $2 WHERE id = $1", u.Id, raw)
+	return
+}
+
+func handleSetMaxFee(ctx context.Context, opts docopt.Opts) {
+	u := ctx.Value("initiator").(User)
+
+	switch {
+	case opts["off"].(bool):
+		if err := u.setFeeLimit(nil); err != nil {
+			log.Warn().Err(err).Stringer("user", &u).Msg("failed to unset max fee")
+			send(ctx, u, t.ERROR, t.T{"Err": ErrDatabase.Error()})
+			return
+		}
+		go u.track("setmaxfee", map[string]interface{}{"off": true})
+	default:
+		raw, err := opts.String("<fee>")
+		if err != nil {
+			break
+		}
+
+		limit, err := parseFeeLimit(raw)
+		if err != nil {
+			send(ctx, u, t.ERROR, t.T{"Err": messageFromError(ctx, err)})
+			return
+		}
+
+		if err := u.setFeeLimit(limit); err != nil {
+			log.Warn().Err(err).Stringer("user", &u).Msg("failed to set max fee")
+			send(ctx, u, t.ERROR, t.T{"Err": ErrDatabase.Error()})
+			return
+		}
+		go u.track("setmaxfee", map[string]interface{}{
+			"percent": limit.Percent != 0,
+		})
+	}
+
+	var current string
+	if limit := u.getFeeLimit(); limit != nil {
+		current = limit.String()
+	}
+	send(ctx, u, t.MAXFEEMSG, t.T{"Limit": current})
+}
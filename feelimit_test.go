@@ -0,0 +1,140 @@
+package main
+
+import "testing"
+
+func TestParseFeeLimit(t *testing.T) {
+	tests := []struct {
+		raw   string
+		limit *FeeLimit
+		fails bool
+	}{
+		{"", nil, false},
+		{"  ", nil, false},
+		{"1%", &FeeLimit{Percent: 1}, false},
+		{"0.5%", &FeeLimit{Percent: 0.5}, false},
+		{"100%", &FeeLimit{Percent: 100}, false},
+		{"101%", nil, true},
+		{"-1%", nil, true},
+		{"x%", nil, true},
+		{"10", &FeeLimit{Msatoshi: 10000}, false},
+		{"0.5", &FeeLimit{Msatoshi: 500}, false},
+		{"-10", nil, true},
+		{"ten", nil, true},
+	}
+
+	for _, test := range tests {
+		limit, err := parseFeeLimit(test.raw)
+		if (err != nil) != test.fails {
+			t.Errorf("parseFeeLimit(%q) error = %v, want failure %v", test.raw, err, test.fails)
+			continue
+		}
+		switch {
+		case limit == nil && test.limit == nil:
+		case limit == nil || test.limit == nil || *limit != *test.limit:
+			t.Errorf("parseFeeLimit(%q) = %v, want %v", test.raw, limit, test.limit)
+		}
+	}
+}
+
+func TestFeeLimitMaxFee(t *testing.T) {
+	tests := []struct {
+		limit    FeeLimit
+		msatoshi int64
+		max      int64
+	}{
+		{FeeLimit{Percent: 1}, 1000000, 10000},
+		{FeeLimit{Percent: 0.5}, 200000, 1000},
+		{FeeLimit{Msatoshi: 3000}, 1000000, 3000},
+		{FeeLimit{Msatoshi: 3000}, 10, 3000},
+	}
+
+	for _, test := range tests {
+		if max := test.limit.maxFee(test.msatoshi); max != test.max {
+			t.Errorf("%s maxFee(%d) = %d, want %d", test.limit, test.msatoshi, max, test.max)
+		}
+	}
+}
+
+func TestCheckFeeLimit(t *testing.T) {
+	tests := []struct {
+		limit    *FeeLimit
+		msatoshi int64
+		fails    bool
+	}{
+		{nil, 1000000, false},
+		{&FeeLimit{Percent: 1}, 1000000, false},
+		{&FeeLimit{Percent: 0.3}, 1000000, false},
+		{&FeeLimit{Percent: 0.1}, 1000000, true},
+		{&FeeLimit{Msatoshi: 3000}, 1000000, false},
+		{&FeeLimit{Msatoshi: 2999}, 1000000, true},
+		{&FeeLimit{Msatoshi: 0}, 100000, true},
+	}
+
+	for _, test := range tests {
+		err := checkFeeLimit(test.limit, test.msatoshi)
+		if (err != nil) != test.fails {
+			t.Errorf("checkFeeLimit(%v, %d) = %v, want failure %v", test.limit, test.msatoshi, err, test.fails)
+		}
+	}
+}
+
+func TestHeldFee(t *testing.T) {
+	tests := []struct {
+		limit    *FeeLimit
+		msatoshi int64
+		held     int64
+	}{
+		{nil, 2000000, 10000},
+		{nil, 100000, 5500},
+		{&FeeLimit{Percent: 1}, 2000000, 10000},
+		{&FeeLimit{Percent: 0.3}, 2000000, 6000},
+		{&FeeLimit{Msatoshi: 1000}, 100000, 1000},
+	}
+
+	for _, test := range tests {
+		if held := heldFee(test.limit, test.msatoshi); held != test.held {
+			t.Errorf("heldFee(%v, %d) = %d, want %d", test.limit, test.msatoshi, held, test.held)
+		}
+	}
+}
+
+func TestChargedFee(t *testing.T) {
+	max := func(v int64) *int64 { return &v }
+
+	tests := []struct {
+		msatoshi int64
+		paid     int64
+		maxFee   *int64
+		charged  int64
+	}{
+		{1000000, 0, nil, 3000},
+		{1000000, 5000, nil, 5000},
+		{1000000, 5000, max(4000), 4000},
+		{1000000, 1000, max(4000), 3000},
+		{1000000, 8000, max(8000), 8000},
+	}
+
+	for _, test := range tests {
+		charged := chargedFee(test.msatoshi, test.paid, test.maxFee)
+		if charged != test.charged {
+			t.Errorf("chargedFee(%d, %d, %v) = %d, want %d",
+				test.msatoshi, test.paid, test.maxFee, charged, test.charged)
+		}
+	}
+}
+
+func TestResolveFeeLimit(t *testing.T) {
+	stored := &FeeLimit{Percent: 1}
+	userDefault := func() *FeeLimit { return stored }
+	given := &FeeLimit{Msatoshi: 5000}
+
+	if limit := resolveFeeLimit(given, userDefault); limit != given {
+		t.Errorf("resolveFeeLimit with a --maxfee flag = %v, want %v", limit, given)
+	}
+	if limit := resolveFeeLimit(nil, userDefault); limit != stored {
+		t.Errorf("resolveFeeLimit without a flag = %v, want the stored %v", limit, stored)
+	}
+	if limit := resolveFeeLimit(nil, func() *FeeLimit { return nil }); limit != nil {
+		t.Errorf("resolveFeeLimit without any limit = %v, want nil", limit)
+	}
+}
@@ -21,12 +21,12 @@ func handleFine(ctx context.Context, opts docopt.Opts) {
 			send(ctx, chatOwner, t.MUSTBEGROUP)
 			return
 		}
-		msats, err := parseSatoshis(opts)
+		msats, err := parseSatoshis(ctx, opts)
 		if err != nil || msats == 0 {
 			send(ctx, chatOwner, t.ERROR, t.T{"Err": err.Error()})
 			return
 		}
-		reason := getVariadicFieldOrReplyToContent(ctx, opts, "<reason>")
+		reason := getVariadicFieldOrReplyToContent(incomingMessage(ctx), opts, "<reason>")
 		if message.ReplyToMessage == nil {
 			send(ctx, chatOwner, t.MISSINGRECEIVER)
 			return
@@ -0,0 +1,101 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+
+	"github.com/docopt/docopt-go"
+	"github.com/fiatjaf/lntxbot/t"
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api"
+)
+
+// a frozen account can receive but nothing leaves it: payInvoice, internal
+// transfers, proxied payments, lnurl-withdraw vouchers and everything that
+// goes through checkBalanceFor refuse to run with ErrAccountFrozen.
+
+func (u *User) setFrozen(frozen bool) error {
+	return pg.Get(&u.Frozen,
+		"UPDATE account SET frozen = $2 WHERE id = $1 RETURNING frozen",
+		u.Id, frozen)
+}
+
+func handleFreeze(ctx context.Context) {
+	u := ctx.Value("initiator").(User)
+
+	if err := u.setFrozen(true); err != nil {
+		logger(ctx).Warn().Err(err).Stringer("user", &u).Msg("failed to freeze")
+		send(ctx, u, t.ERROR, t.T{"Err": ErrDatabase.Error()})
+		return
+	}
+
+	logger(ctx).Info().Stringer("user", &u).Msg("account frozen")
+	go u.track("freeze", nil)
+	send(ctx, u, t.FREEZEMSG, t.T{"Frozen": true})
+}
+
+// handleUnfreeze needs the 2fa code when the user has it, otherwise a tap on
+// a confirmation button, so it can't happen by accident.
+func handleUnfreeze(ctx context.Context, opts docopt.Opts) {
+	u := ctx.Value("initiator").(User)
+
+	if !u.Frozen {
+		send(ctx, u, t.FREEZEMSG, t.T{"Frozen": false})
+		return
+	}
+
+	if secret, _ := u.getTwoFactor(); secret != "" {
+		code, _ := opts.String("<code>")
+		if !u.verifyTOTP(secret, code) {
+			send(ctx, u, t.TWOFACTORINVALID, u.twoFactorFailure())
+			return
+		}
+		unfreeze(ctx, u)
+		return
+	}
+
+	send(ctx, u, t.UNFREEZEPROMPT, t.T{}, &tgbotapi.InlineKeyboardMarkup{
+		[][]tgbotapi.InlineKeyboardButton{
+			{
+				tgbotapi.NewInlineKeyboardButtonData(
+					translate(ctx, t.CANCEL),
+					fmt.Sprintf("cancel=%d", u.Id),
+				),
+				tgbotapi.NewInlineKeyboardButtonData(
+					translate(ctx, t.YES),
+					fmt.Sprintf("unfreeze=%d", u.Id),
+				),
+			},
+		},
+	})
+}
+
+func handleUnfreezeCallback(ctx context.Context, data string) {
+	u := ctx.Value("initiator").(User)
+	if strconv.Itoa(u.Id) != data {
+		send(ctx, t.CANTCANCEL, WITHALERT)
+		return
+	}
+
+	// the 2fa may have been turned on after the prompt was sent
+	if secret, _ := u.getTwoFactor(); secret != "" {
+		removeKeyboardButtons(ctx)
+		send(ctx, u, t.UNFREEZEPROMPT, t.T{"TwoFactor": true})
+		return
+	}
+
+	removeKeyboardButtons(ctx)
+	unfreeze(ctx, u)
+}
+
+func unfreeze(ctx context.Context, u User) {
+	if err := u.setFrozen(false); err != nil {
+		logger(ctx).Warn().Err(err).Stringer("user", &u).Msg("failed to unfreeze")
+		send(ctx, u, t.ERROR, t.T{"Err": ErrDatabase.Error()})
+		return
+	}
+
+	logger(ctx).Info().Stringer("user", &u).Msg("account unfrozen")
+	go u.track("unfreeze", nil)
+	send(ctx, u, t.FREEZEMSG, t.T{"Frozen": false})
+}
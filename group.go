@@ -184,6 +184,21 @@ func (g GroupChat) areCoinflipsEnabled() (enabled bool) {
 	return
 }
 
+func (g GroupChat) toggleTreasury() (enabled bool, err error) {
+	err = pg.Get(&enabled, `
+UPDATE groupchat AS g SET treasury = NOT g.treasury
+WHERE telegram_id = $1
+RETURNING treasury
+    `, g.TelegramId)
+	return
+}
+
+func (g GroupChat) isTreasuryEnabled() (enabled bool) {
+	pg.Get(&enabled,
+		"SELECT treasury FROM groupchat WHERE telegram_id = $1", g.TelegramId)
+	return
+}
+
 func (g GroupChat) setTicketPrice(sat int) (err error) {
 	_, err = pg.Exec(`
 UPDATE groupchat SET ticket = $2
@@ -258,6 +273,38 @@ func (g GroupChat) getRenamePrice() (sat int) {
 	return
 }
 
+func (g GroupChat) setTipLimit(sat int) (err error) {
+	_, err = pg.Exec(`
+UPDATE groupchat SET tip_limit = $2
+WHERE telegram_id = $1
+    `, g.TelegramId, sat)
+	return
+}
+
+func (g GroupChat) getTipLimit() (sat int) {
+	pg.Get(&sat,
+		"SELECT tip_limit FROM groupchat WHERE telegram_id = $1",
+		g.TelegramId)
+	return
+}
+
+// checkTipLimit tells the user when the amount is above the maximum allowed
+// by the group admins for tips and giveaways.
+func checkTipLimit(ctx context.Context, msats int64) bool {
+	g, ok := ctx.Value("group").(GroupChat)
+	if !ok || g.TelegramId == 0 {
+		return true
+	}
+
+	limit := g.getTipLimit()
+	if limit == 0 || msats <= int64(limit)*1000 {
+		return true
+	}
+
+	send(ctx, ctx.Value("initiator").(User), t.TIPLIMITEXCEEDED, t.T{"Sat": limit})
+	return false
+}
+
 func (g GroupChat) isSpammy() (spammy bool) {
 	if spammy, ok := spammy_cache.Get(strconv.FormatInt(g.TelegramId, 10)); ok {
 		return spammy.(bool)
@@ -273,6 +320,56 @@ func (g GroupChat) isSpammy() (spammy bool) {
 	return
 }
 
+var menu_cache = cmap.New()
+
+// getMenu returns the custom menu items defined for this chat, as a map of
+// names to satoshi amounts.
+func (g GroupChat) getMenu() (menu map[string]int64) {
+	if imenu, ok := menu_cache.Get(strconv.FormatInt(g.TelegramId, 10)); ok {
+		return imenu.(map[string]int64)
+	}
+
+	var jmenu []byte
+	err := pg.Get(&jmenu,
+		"SELECT menu FROM groupchat WHERE telegram_id = $1", g.TelegramId)
+	if err != nil {
+		return nil
+	}
+
+	menu = make(map[string]int64)
+	json.Unmarshal(jmenu, &menu)
+	menu_cache.Set(strconv.FormatInt(g.TelegramId, 10), menu)
+	return
+}
+
+// setMenuItem adds a custom menu item to this chat, or removes it if sat is 0.
+func (g GroupChat) setMenuItem(name string, sat int64) (err error) {
+	name = strings.ToLower(strings.TrimSpace(name))
+	if !menuItemName.MatchString(name) {
+		return errors.New("invalid menu item name.")
+	}
+
+	if sat == 0 {
+		_, err = pg.Exec(`
+UPDATE groupchat SET menu = menu - $2
+WHERE telegram_id = $1
+    `, g.TelegramId, name)
+	} else {
+		_, err = pg.Exec(`
+UPDATE groupchat SET menu = menu || jsonb_build_object($2::text, $3::bigint)
+WHERE telegram_id = $1
+    `, g.TelegramId, name, sat)
+	}
+	if err != nil {
+		return err
+	}
+
+	menu_cache.Remove(strconv.FormatInt(g.TelegramId, 10))
+	return
+}
+
+var menuItemName = regexp.MustCompile(`^[a-z][a-z_]{1,20}$`)
+
 func setLanguage(chatId int64, lang string) (err error) {
 	if _, languageAvailable := bundle.Translations[lang]; !languageAvailable {
 		return errors.New("language not available.")
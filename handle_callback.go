@@ -3,7 +3,6 @@ package main
 import (
 	"context"
 	"fmt"
-	"math/rand"
 	"strconv"
 	"strings"
 	"time"
@@ -17,14 +16,14 @@ func handleTelegramCallback(ctx context.Context, cb *tgbotapi.CallbackQuery) {
 
 	u, tcase, err := ensureTelegramUser(&tgbotapi.Message{From: cb.From})
 	if err != nil {
-		log.Warn().Err(err).Int("case", tcase).
+		logger(ctx).Warn().Err(err).Int("case", tcase).
 			Str("username", cb.From.UserName).
 			Int("id", cb.From.ID).
 			Msg("failed to ensure user on callback")
 		return
 	}
 
-	log.Debug().Str("d", cb.Data).Stringer("user", &u).Msg("got callback")
+	logger(ctx).Debug().Str("d", cb.Data).Stringer("user", &u).Msg("got callback")
 	ctx = context.WithValue(ctx, "initiator", u)
 
 	if cb.Message != nil {
@@ -62,18 +61,34 @@ func handleTelegramCallback(ctx context.Context, cb *tgbotapi.CallbackQuery) {
 			send(ctx, t.CANTCANCEL, WITHALERT)
 			return
 		}
+		if cb.Message != nil {
+			// forget what was waiting for a reply or a button on this prompt
+			rds.Del(fmt.Sprintf("reply:%d:%d", u.Id, cb.Message.MessageID))
+		}
 		removeKeyboardButtons(ctx)
 		send(ctx, t.CANCELED, APPEND)
 		goto answerEmpty
 	case strings.HasPrefix(cb.Data, "pay="):
 		handlePayCallback(ctx)
 		return
+	case strings.HasPrefix(cb.Data, "didyoumean="):
+		go handleDidYouMeanCallback(ctx)
+		goto answerEmpty
+	case strings.HasPrefix(cb.Data, "lang="):
+		go handleLanguageCallback(ctx)
+		goto answerEmpty
+	case strings.HasPrefix(cb.Data, "onboard="):
+		go handleOnboardingCallback(ctx)
+		goto answerEmpty
+	case strings.HasPrefix(cb.Data, "choosepay="):
+		handlePayChooseCallback(ctx)
+		return
 	case strings.HasPrefix(cb.Data, "lnurlpay="):
 		defer removeKeyboardButtons(ctx)
 		msats, _ := strconv.ParseInt(cb.Data[9:], 10, 64)
 		key := fmt.Sprintf("reply:%d:%d", u.Id, cb.Message.MessageID)
 		if val, err := rds.Get(key).Result(); err == nil {
-			handleLNURLPayAmount(ctx, msats, val)
+			handleLNURLPayAmount(ctx, msats, val, key)
 		}
 		return
 	case strings.HasPrefix(cb.Data, "give="):
@@ -105,8 +120,8 @@ func handleTelegramCallback(ctx context.Context, cb *tgbotapi.CallbackQuery) {
 			"giveaway",
 		)
 		if err != nil {
-			log.Warn().Err(err).Msg("failed to giveaway")
-			send(ctx, claimer, t.ERROR, t.T{"Err": err.Error()}, WITHALERT)
+			logger(ctx).Warn().Err(err).Msg("failed to giveaway")
+			send(ctx, claimer, t.ERROR, t.T{"Err": messageFromError(ctx, err)}, WITHALERT)
 			return
 		}
 
@@ -173,7 +188,7 @@ func handleTelegramCallback(ctx context.Context, cb *tgbotapi.CallbackQuery) {
 
 		nparticipants, err := strconv.Atoi(params[0])
 		if err != nil {
-			log.Error().Err(err).Str("data", cb.Data).
+			logger(ctx).Error().Err(err).Str("data", cb.Data).
 				Msg("failed to parse npartipants  on coinflip")
 			removeKeyboardButtons(ctx)
 			send(ctx, t.CALLBACKERROR, t.T{"BotOp": "Coinflip"}, APPEND)
@@ -184,9 +199,9 @@ func handleTelegramCallback(ctx context.Context, cb *tgbotapi.CallbackQuery) {
 			goto answerEmpty
 		}
 
-		msats, err := parseAmountString(params[1])
+		msats, err := parseAmountString(ctx, params[1])
 		if err != nil {
-			log.Error().Err(err).Str("data", cb.Data).
+			logger(ctx).Error().Err(err).Str("data", cb.Data).
 				Msg("failed to parse amount on coinflip")
 			removeKeyboardButtons(ctx)
 			send(ctx, t.CALLBACKERROR, t.T{"BotOp": "Coinflip"}, APPEND)
@@ -212,9 +227,9 @@ func handleTelegramCallback(ctx context.Context, cb *tgbotapi.CallbackQuery) {
 		}
 
 		if err := rds.SAdd("coinflip:"+coinflipid, joiner.Id).Err(); err != nil {
-			log.Warn().Err(err).Str("coinflip", coinflipid).
+			logger(ctx).Warn().Err(err).Str("coinflip", coinflipid).
 				Msg("error adding participant to coinflip.")
-			send(ctx, t.ERROR, t.T{"Err": err.Error()}, WITHALERT)
+			send(ctx, t.ERROR, t.T{"Err": messageFromError(ctx, err)}, WITHALERT)
 			goto answerEmpty
 		}
 
@@ -240,22 +255,29 @@ func handleTelegramCallback(ctx context.Context, cb *tgbotapi.CallbackQuery) {
 			sparticipants, err := rds.SMembers(rkey).Result()
 			go rds.Del(rkey)
 			if err != nil {
-				log.Warn().Err(err).Msg("failed to get coinflip participants")
+				logger(ctx).Warn().Err(err).Msg("failed to get coinflip participants")
 				removeKeyboardButtons(ctx)
 				send(ctx, t.CALLBACKERROR, t.T{"BotOp": "Coinflip"}, APPEND)
 				goto answerEmpty
 			}
-			log.Debug().Int("nparticipants", len(sparticipants)).Msg("resolving coinflip")
+			logger(ctx).Debug().Int("nparticipants", len(sparticipants)).Msg("resolving coinflip")
 			if len(sparticipants) <= 0 {
 				goto answerEmpty
 			}
 
-			swinnerId := sparticipants[rand.Intn(len(sparticipants))]
+			winnerIndex, err := randomIndex(len(sparticipants))
+			if err != nil {
+				logger(ctx).Error().Err(err).Msg("failed to pick coinflip winner")
+				removeKeyboardButtons(ctx)
+				send(ctx, t.CALLBACKERROR, t.T{"BotOp": "Coinflip"}, APPEND)
+				goto answerEmpty
+			}
+			swinnerId := sparticipants[winnerIndex]
 
 			// winner id
 			winnerId, err := strconv.Atoi(swinnerId)
 			if err != nil {
-				log.Warn().Err(err).Str("winnerId", swinnerId).
+				logger(ctx).Warn().Err(err).Str("winnerId", swinnerId).
 					Msg("winner id is not an int")
 				removeKeyboardButtons(ctx)
 				send(ctx, t.CALLBACKERROR, t.T{"BotOp": "Coinflip"}, APPEND)
@@ -267,7 +289,7 @@ func handleTelegramCallback(ctx context.Context, cb *tgbotapi.CallbackQuery) {
 			for i, spart := range sparticipants {
 				part, err := strconv.Atoi(spart)
 				if err != nil {
-					log.Warn().Err(err).Str("part", spart).
+					logger(ctx).Warn().Err(err).Str("part", spart).
 						Msg("participant id is not an int")
 					removeKeyboardButtons(ctx)
 					send(ctx, t.CALLBACKERROR, t.T{"BotOp": "Coinflip"}, APPEND)
@@ -278,7 +300,7 @@ func handleTelegramCallback(ctx context.Context, cb *tgbotapi.CallbackQuery) {
 
 			winner, err := settleCoinflip(ctx, sats, winnerId, participants)
 			if err != nil {
-				log.Warn().Err(err).Msg("error processing coinflip transactions")
+				logger(ctx).Warn().Err(err).Msg("error processing coinflip transactions")
 				removeKeyboardButtons(ctx)
 				send(ctx, t.CALLBACKERROR, t.T{"BotOp": "Coinflip"}, APPEND)
 				goto answerEmpty
@@ -310,7 +332,7 @@ func handleTelegramCallback(ctx context.Context, cb *tgbotapi.CallbackQuery) {
 		nparticipants, err1 := strconv.Atoi(params[1])
 		sats, err2 := strconv.Atoi(params[2])
 		if err0 != nil || err1 != nil || err2 != nil {
-			log.Warn().Err(err0).Err(err1).Err(err2).Msg("giveflip error")
+			logger(ctx).Warn().Err(err0).Err(err1).Err(err2).Msg("giveflip error")
 			removeKeyboardButtons(ctx)
 			send(ctx, t.CALLBACKERROR, t.T{"BotOp": "Giveflip"}, APPEND)
 			goto answerEmpty
@@ -341,7 +363,7 @@ func handleTelegramCallback(ctx context.Context, cb *tgbotapi.CallbackQuery) {
 		}
 
 		if err := rds.SAdd("giveflip:"+giveflipid, joiner.Id).Err(); err != nil {
-			log.Warn().Err(err).Str("giveflip", giveflipid).
+			logger(ctx).Warn().Err(err).Str("giveflip", giveflipid).
 				Msg("error adding participant to giveflip.")
 			goto answerEmpty
 		}
@@ -365,22 +387,29 @@ func handleTelegramCallback(ctx context.Context, cb *tgbotapi.CallbackQuery) {
 			sparticipants, err := rds.SMembers(rkey).Result()
 			go rds.Del(rkey)
 			if err != nil {
-				log.Warn().Err(err).Msg("failed to get giveflip participants")
+				logger(ctx).Warn().Err(err).Msg("failed to get giveflip participants")
 				removeKeyboardButtons(ctx)
 				send(ctx, t.CALLBACKERROR, t.T{"BotOp": "Giveflip"}, APPEND)
 				goto answerEmpty
 			}
 
-			log.Debug().Int("nparticipants", len(sparticipants)).Msg("resolving giveflip")
+			logger(ctx).Debug().Int("nparticipants", len(sparticipants)).Msg("resolving giveflip")
 			if len(sparticipants) <= 0 {
 				goto answerEmpty
 			}
-			swinnerId := sparticipants[rand.Intn(len(sparticipants))]
+			winnerIndex, err := randomIndex(len(sparticipants))
+			if err != nil {
+				logger(ctx).Error().Err(err).Msg("failed to pick giveflip winner")
+				removeKeyboardButtons(ctx)
+				send(ctx, t.CALLBACKERROR, t.T{"BotOp": "Giveflip"}, APPEND)
+				goto answerEmpty
+			}
+			swinnerId := sparticipants[winnerIndex]
 
 			// winner
 			winnerId, err := strconv.Atoi(swinnerId)
 			if err != nil {
-				log.Warn().Err(err).Str("winnerId", swinnerId).
+				logger(ctx).Warn().Err(err).Str("winnerId", swinnerId).
 					Msg("winner id is not an int")
 				removeKeyboardButtons(ctx)
 				send(ctx, t.CALLBACKERROR, t.T{"BotOp": "Giveflip"}, APPEND)
@@ -388,7 +417,7 @@ func handleTelegramCallback(ctx context.Context, cb *tgbotapi.CallbackQuery) {
 			}
 			winner, err := loadUser(winnerId)
 			if err != nil {
-				log.Warn().Err(err).Int("winnerId", winnerId).
+				logger(ctx).Warn().Err(err).Int("winnerId", winnerId).
 					Msg("failed to load winner on giveflip")
 				removeKeyboardButtons(ctx)
 				send(ctx, t.CALLBACKERROR, t.T{"BotOp": "Giveflip"}, APPEND)
@@ -398,7 +427,7 @@ func handleTelegramCallback(ctx context.Context, cb *tgbotapi.CallbackQuery) {
 			// giver
 			giver, err := loadUser(giverId)
 			if err != nil {
-				log.Warn().Err(err).Int("giverId", giverId).
+				logger(ctx).Warn().Err(err).Int("giverId", giverId).
 					Msg("failed to load giver on giveflip")
 				removeKeyboardButtons(ctx)
 				send(ctx, t.CALLBACKERROR, t.T{"BotOp": "Giveflip"}, APPEND)
@@ -429,9 +458,9 @@ func handleTelegramCallback(ctx context.Context, cb *tgbotapi.CallbackQuery) {
 				"giveflip",
 			)
 			if err != nil {
-				log.Warn().Err(err).Msg("failed to giveflip")
+				logger(ctx).Warn().Err(err).Msg("failed to giveflip")
 				send(ctx, winner, t.CLAIMFAILED,
-					t.T{"BotOp": "giveflip", "Err": err.Error()})
+					t.T{"BotOp": "giveflip", "Err": messageFromError(ctx, err)})
 				goto answerEmpty
 			}
 
@@ -450,6 +479,136 @@ func handleTelegramCallback(ctx context.Context, cb *tgbotapi.CallbackQuery) {
 			}, EDIT)
 		}
 
+		goto answerEmpty
+	case strings.HasPrefix(cb.Data, "unfreeze="):
+		handleUnfreezeCallback(ctx, cb.Data[9:])
+		goto answerEmpty
+	case strings.HasPrefix(cb.Data, "drop="):
+		// claim a share of a drop, which is sent right away from the dropper.
+		// nothing is held anywhere, so an expired drop has nothing to refund.
+		params := strings.Split(cb.Data[5:], "-")
+		if len(params) != 4 {
+			goto answerEmpty
+		}
+
+		dropperId, err1 := strconv.Atoi(params[0])
+		nparticipants, err2 := strconv.Atoi(params[1])
+		share, err3 := strconv.Atoi(params[2])
+		if err1 != nil || err2 != nil || err3 != nil {
+			logger(ctx).Warn().Err(err1).Err(err2).Err(err3).
+				Msg("error parsing params on drop")
+			removeKeyboardButtons(ctx)
+			send(ctx, t.CALLBACKERROR, t.T{"BotOp": "Drop"}, APPEND)
+			goto answerEmpty
+		}
+
+		dropid := params[3]
+		rkey := "drop:" + dropid
+		openkey := "dropopen:" + dropid
+
+		if !rds.Exists(openkey).Val() {
+			removeKeyboardButtons(ctx)
+			send(ctx, t.CALLBACKEXPIRED, t.T{"BotOp": "Drop"}, APPEND)
+			goto answerEmpty
+		}
+
+		claimer := u
+		if claimer.Id == dropperId {
+			send(ctx, t.GIVERCANTJOIN, WITHALERT)
+			return
+		}
+
+		// add the claimer only if there is still a share, all in one step so
+		// two people pressing at once can't both take or both miss the last one
+		result, err := rds.Eval(`
+            local key = KEYS[1]
+            local user = ARGV[1]
+            if redis.call("sismember", key, user) == 1 then
+              return -1
+            end
+            if redis.call("scard", key) >= tonumber(ARGV[2]) then
+              return 0
+            end
+            redis.call("sadd", key, user)
+            redis.call("expire", key, ARGV[3])
+            return 1
+        `,
+			[]string{rkey}, claimer.Id, nparticipants, int(s.GiveAwayTimeout/time.Second),
+		).Result()
+		claimed, ok := result.(int64)
+		if err != nil || !ok {
+			logger(ctx).Warn().Err(err).Str("drop", dropid).Msg("failed to add drop claimer")
+			send(ctx, t.CALLBACKERROR, t.T{"BotOp": "Drop"}, WITHALERT)
+			return
+		}
+		switch claimed {
+		case -1:
+			send(ctx, t.CANTJOINTWICE, WITHALERT)
+			return
+		case 0:
+			send(ctx, t.DROPEMPTY, WITHALERT)
+			return
+		}
+
+		dropper, err := loadUser(dropperId)
+		if err != nil {
+			logger(ctx).Warn().Err(err).Int("dropper", dropperId).
+				Msg("failed to load dropper")
+			rds.SRem(rkey, claimer.Id)
+			send(ctx, t.CALLBACKERROR, t.T{"BotOp": "Drop"}, WITHALERT)
+			return
+		}
+
+		err = dropper.sendInternally(
+			ctx,
+			claimer,
+			false,
+			int64(share)*1000,
+			0,
+			"",
+			hashString("drop:%s:%d", dropid, claimer.Id),
+			"drop",
+		)
+		if err != nil {
+			// most likely the dropper doesn't have the money anymore, so we
+			// end the drop here for everybody
+			logger(ctx).Warn().Err(err).Str("drop", dropid).Msg("failed to claim drop")
+			rds.SRem(rkey, claimer.Id)
+			rds.Del(openkey)
+			removeKeyboardButtons(ctx)
+			send(ctx, t.CLAIMFAILED,
+				t.T{"BotOp": "drop", "Err": messageFromError(ctx, err)}, WITHALERT)
+			return
+		}
+
+		send(ctx, claimer, t.USERSENTYOUSATS, t.T{
+			"User":  dropper.AtName(ctx),
+			"Sats":  share,
+			"BotOp": "/drop", "RawSats": "",
+		})
+
+		var claimers []User
+		for _, spart := range rds.SMembers(rkey).Val() {
+			part, err := strconv.Atoi(spart)
+			if err != nil {
+				continue
+			}
+			if c, err := loadUser(part); err == nil {
+				claimers = append(claimers, c)
+			}
+		}
+
+		if len(claimers) < nparticipants {
+			send(ctx, EDIT, t.DROPMSG,
+				dropAdData(ctx, dropper, claimers, nparticipants, share),
+				dropKeyboard(ctx, dropid, dropperId, nparticipants, share))
+		} else {
+			go rds.Del(rkey, openkey)
+			send(ctx, EDIT, t.DROPMSG,
+				dropAdData(ctx, dropper, claimers, nparticipants, share))
+			removeKeyboardButtons(ctx)
+		}
+
 		goto answerEmpty
 	case strings.HasPrefix(cb.Data, "raise="):
 		// join a new giver in a fundraising event
@@ -471,9 +630,9 @@ func handleTelegramCallback(ctx context.Context, cb *tgbotapi.CallbackQuery) {
 
 		receiverId, err1 := strconv.Atoi(params[0])
 		ngivers, err2 := strconv.Atoi(params[1])
-		msats, err3 := parseAmountString(params[2])
+		msats, err3 := parseAmountString(ctx, params[2])
 		if err1 != nil || err2 != nil || err3 != nil {
-			log.Warn().Err(err1).Err(err2).Err(err3).
+			logger(ctx).Warn().Err(err1).Err(err2).Err(err3).
 				Msg("error parsing params on fundraise")
 			removeKeyboardButtons(ctx)
 			send(ctx, t.CALLBACKERROR, t.T{"BotOp": "Fundraise"}, APPEND)
@@ -499,27 +658,45 @@ func handleTelegramCallback(ctx context.Context, cb *tgbotapi.CallbackQuery) {
 		}
 
 		if err := rds.SAdd("fundraise:"+fundraiseid, joiner.Id).Err(); err != nil {
-			log.Warn().Err(err).Str("fundraise", fundraiseid).
+			logger(ctx).Warn().Err(err).Str("fundraise", fundraiseid).
 				Msg("error adding giver to fundraise.")
-			send(ctx, t.ERROR, t.T{"Err": err.Error()}, WITHALERT)
+			send(ctx, t.ERROR, t.T{"Err": messageFromError(ctx, err)}, WITHALERT)
 			return
 		}
 
 		if nregistered+1 < ngivers {
-			// append @user to the fundraise message (without removing the keyboard)
-			// we don't have to check for cb.Message/messageId here because we don't
-			// allow fundraises as inline messages so we always have access to
-			// cb.Message.
+			// render the fundraise message again with the new contributor and
+			// progress (without removing the keyboard). we don't have to check for
+			// cb.Message/messageId here because we don't allow fundraises as
+			// inline messages so we always have access to cb.Message.
+			receiver, err := loadUser(receiverId)
+			if err != nil {
+				logger(ctx).Warn().Err(err).Int("receiver", receiverId).
+					Msg("failed to load fundraise receiver")
+				goto answerEmpty
+			}
+
+			var contributors []User
+			for _, spart := range rds.SMembers(rkey).Val() {
+				part, err := strconv.Atoi(spart)
+				if err != nil {
+					continue
+				}
+				if contributor, err := loadUser(part); err == nil {
+					contributors = append(contributors, contributor)
+				}
+			}
 
-			send(ctx, ctx.Value("message"), APPEND, joiner.AtName(ctx),
+			send(ctx, EDIT, t.FUNDRAISEAD,
+				fundraiseAdData(ctx, fundraiseid, receiver, contributors, ngivers, sats),
 				fundraiseKeyboard(ctx, fundraiseid, 0, receiverId, ngivers, sats))
 		} else {
 			// commit the fundraise. this is the same as the coinflip,
 			// just without randomness.
 			sgivers, err := rds.SMembers(rkey).Result()
-			go rds.Del(rkey)
+			go rds.Del(rkey, "fundraisetitle:"+fundraiseid)
 			if err != nil {
-				log.Warn().Err(err).Msg("failed to get fundraise givers")
+				logger(ctx).Warn().Err(err).Msg("failed to get fundraise givers")
 				removeKeyboardButtons(ctx)
 				send(ctx, t.CALLBACKERROR, t.T{"BotOp": "Fundraise"}, APPEND)
 				goto answerEmpty
@@ -530,7 +707,7 @@ func handleTelegramCallback(ctx context.Context, cb *tgbotapi.CallbackQuery) {
 			for i, spart := range sgivers {
 				part, err := strconv.Atoi(spart)
 				if err != nil {
-					log.Warn().Err(err).Str("part", spart).
+					logger(ctx).Warn().Err(err).Str("part", spart).
 						Msg("giver id is not an int")
 					removeKeyboardButtons(ctx)
 					send(ctx, t.CALLBACKERROR, t.T{"BotOp": "Fundraise"}, APPEND)
@@ -541,7 +718,7 @@ func handleTelegramCallback(ctx context.Context, cb *tgbotapi.CallbackQuery) {
 
 			receiver, err := settleFundraise(ctx, sats, receiverId, givers)
 			if err != nil {
-				log.Warn().Err(err).Msg("error processing fundraise transactions")
+				logger(ctx).Warn().Err(err).Msg("error processing fundraise transactions")
 				removeKeyboardButtons(ctx)
 				send(ctx, t.CALLBACKERROR, t.T{"BotOp": "Fundraise"}, APPEND)
 				goto answerEmpty
@@ -561,19 +738,19 @@ func handleTelegramCallback(ctx context.Context, cb *tgbotapi.CallbackQuery) {
 		parts := strings.Split(data, "|~|")
 		if len(parts) != 3 {
 			send(ctx, t.ERROR, APPEND)
-			log.Warn().Str("app", "rename").Msg("data isn't split in 3")
+			logger(ctx).Warn().Str("app", "rename").Msg("data isn't split in 3")
 			return
 		}
 		chatId, err := strconv.ParseInt(parts[0], 10, 64)
 		if err != nil {
 			send(ctx, t.ERROR, APPEND)
-			log.Warn().Err(err).Str("app", "rename").Msg("failed to parse chatId")
+			logger(ctx).Warn().Err(err).Str("app", "rename").Msg("failed to parse chatId")
 			return
 		}
 		sats, err := strconv.Atoi(parts[1])
 		if err != nil {
 			send(ctx, t.ERROR, APPEND)
-			log.Warn().Err(err).Str("app", "rename").Msg("failed to parse sats")
+			logger(ctx).Warn().Err(err).Str("app", "rename").Msg("failed to parse sats")
 			return
 		}
 		name := parts[2]
@@ -582,14 +759,14 @@ func handleTelegramCallback(ctx context.Context, cb *tgbotapi.CallbackQuery) {
 		owner, err := getChatOwner(chatId)
 		if err != nil {
 			send(ctx, t.ERROR, APPEND)
-			log.Warn().Err(err).Str("app", "rename").Msg("failed to get chat owner")
+			logger(ctx).Warn().Err(err).Str("app", "rename").Msg("failed to get chat owner")
 			return
 		}
 
 		random, err := randomHex()
 		if err != nil {
 			send(ctx, t.ERROR, APPEND)
-			log.Warn().Err(err).Str("app", "rename").Msg("failed to generate random")
+			logger(ctx).Warn().Err(err).Str("app", "rename").Msg("failed to generate random")
 			return
 		}
 		hash := hashString(random)
@@ -600,7 +777,7 @@ func handleTelegramCallback(ctx context.Context, cb *tgbotapi.CallbackQuery) {
 			hash, "rename",
 		)
 		if err != nil {
-			send(ctx, t.ERROR, t.T{"Err": err.Error()}, APPEND)
+			send(ctx, t.ERROR, t.T{"Err": messageFromError(ctx, err)}, APPEND)
 			return
 		}
 
@@ -625,7 +802,7 @@ WHERE substring(payment_hash from 0 for $2) = $1
   AND is_unclaimed(tx)
         `, hash, len(hash)+1)
 		if err != nil {
-			log.Error().Err(err).Str("hash", hash).
+			logger(ctx).Error().Err(err).Str("hash", hash).
 				Msg("failed to remove pending payment")
 			send(ctx, t.ERROR, APPEND)
 			return
@@ -643,7 +820,7 @@ WHERE substring(payment_hash from 0 for $2) = $1
 
 		sourceUserId, hiddenId, hiddenMessage, err := getHiddenMessage(ctx, hiddenkey)
 		if err != nil {
-			log.Error().Err(err).Str("key", hiddenkey).
+			logger(ctx).Error().Err(err).Str("key", hiddenkey).
 				Msg("error locating hidden message")
 			removeKeyboardButtons(ctx)
 			send(ctx, t.HIDDENMSGNOTFOUND, APPEND)
@@ -676,7 +853,7 @@ WHERE substring(payment_hash from 0 for $2) = $1
 
 		// also don't let users pay twice
 		if alreadyPaid, err := rds.SIsMember(revealedSetKey, u.Id).Result(); err != nil {
-			send(ctx, WITHALERT, t.ERROR, t.T{"Err": err.Error()})
+			send(ctx, WITHALERT, t.ERROR, t.T{"Err": messageFromError(ctx, err)})
 			return
 		} else if alreadyPaid {
 			send(ctx, WITHALERT, t.ERROR, t.T{"Err": "can't reveal twice"})
@@ -695,7 +872,7 @@ WHERE substring(payment_hash from 0 for $2) = $1
 			[]string{revealedSetKey}, u.Id, int(s.HiddenMessageTimeout/time.Second))
 
 		if err := result.Err(); err != nil {
-			send(ctx, WITHALERT, t.ERROR, t.T{"Err": err.Error()})
+			send(ctx, WITHALERT, t.ERROR, t.T{"Err": messageFromError(ctx, err)})
 			return
 		}
 
@@ -705,7 +882,7 @@ WHERE substring(payment_hash from 0 for $2) = $1
 		for i, revealerId := range revealerIdsI {
 			revealerId, err := strconv.Atoi(revealerId.(string))
 			if err != nil {
-				send(ctx, WITHALERT, t.ERROR, t.T{"Err": err.Error()})
+				send(ctx, WITHALERT, t.ERROR, t.T{"Err": messageFromError(ctx, err)})
 				return
 			}
 			revealerIds[i] = revealerId
@@ -730,10 +907,10 @@ WHERE substring(payment_hash from 0 for $2) = $1
 		_, err = settleReveal(ctx, hiddenMessage.Satoshis, hiddenId,
 			sourceUserId, revealerIds)
 		if err != nil {
-			log.Warn().Err(err).Str("id", hiddenId).
+			logger(ctx).Warn().Err(err).Str("id", hiddenId).
 				Int("satoshis", hiddenMessage.Satoshis).
 				Stringer("revealer", &revealer).Msg("failed to pay to reveal")
-			send(ctx, WITHALERT, t.ERROR, t.T{"Err": err.Error()})
+			send(ctx, WITHALERT, t.ERROR, t.T{"Err": messageFromError(ctx, err)})
 			return
 		}
 
@@ -2,28 +2,131 @@ package main
 
 import (
 	"context"
+	"encoding/json"
 	"fmt"
 	"strings"
 
 	"github.com/bwmarrin/discordgo"
 	"github.com/docopt/docopt-go"
 	"github.com/fiatjaf/lntxbot/t"
+	"github.com/tidwall/gjson"
 )
 
 func addDiscordHandlers() {
-	discord.AddHandler(handleDiscordMessage)
+	discord.AddHandler(handleDiscordEvent)
 	discord.AddHandler(handleDiscordReaction)
+	discord.AddHandler(func(dgs *discordgo.Session, r *discordgo.Ready) {
+		if err := registerDiscordSlashCommands(r.User.ID); err != nil {
+			log.Warn().Err(err).Msg("failed to register discord slash commands")
+		}
+	})
 }
 
-func handleDiscordMessage(dgs *discordgo.Session, m *discordgo.MessageCreate) {
-	ctx := context.WithValue(context.Background(), "origin", "discord")
+// handleDiscordEvent reads messages and interactions from the raw events, as
+// our discordgo version doesn't know about replies or interactions.
+func handleDiscordEvent(dgs *discordgo.Session, e *discordgo.Event) {
+	switch e.Type {
+	case "MESSAGE_CREATE":
+		handleDiscordMessage(e.RawData)
+	case "INTERACTION_CREATE":
+		handleDiscordInteraction(e.RawData)
+	}
+}
+
+func handleDiscordMessage(raw json.RawMessage) {
+	var message *discordgo.Message
+	if err := json.Unmarshal(raw, &message); err != nil {
+		log.Warn().Err(err).Msg("failed to parse discord message")
+		return
+	}
+
+	if message.Author == nil || message.Author.Bot ||
+		(len(message.Content) == 0 && len(message.Attachments) == 0) {
+		return
+	}
 
-	message := m.Message
-	if message.Author.Bot || len(message.Content) == 0 {
+	if !s.DiscordTextCommands &&
+		len(message.Content) > 0 && message.Content[0] == '$' {
+		// only slash commands are accepted
 		return
 	}
 
+	// replies come with the message they're replying to
+	var replyTo *discordgo.Message
+	if referenced := gjson.GetBytes(raw, "referenced_message"); referenced.IsObject() {
+		json.Unmarshal([]byte(referenced.Raw), &replyTo)
+	}
+
+	handleDiscordCommand(message, replyTo)
+}
+
+func handleDiscordInteraction(raw json.RawMessage) {
+	interaction := gjson.ParseBytes(raw)
+	if interaction.Get("type").Int() != 2 {
+		// not an application command
+		return
+	}
+
+	// in guilds the user comes inside the member object
+	rawUser := interaction.Get("member.user")
+	if !rawUser.Exists() {
+		rawUser = interaction.Get("user")
+	}
+	var author discordgo.User
+	if err := json.Unmarshal([]byte(rawUser.Raw), &author); err != nil {
+		log.Warn().Err(err).Str("user", rawUser.Raw).
+			Msg("failed to parse discord interaction user")
+		return
+	}
+
+	// rebuild the command as if it was typed
+	name := interaction.Get("data.name").String()
+	values := make(map[string]string)
+	for _, option := range interaction.Get("data.options").Array() {
+		values[option.Get("name").String()] = option.Get("value").String()
+	}
+	content := "$" + name
+	for _, command := range discordSlashCommands {
+		if command.Name != name {
+			continue
+		}
+		for _, option := range command.Options {
+			if value, ok := values[option.Name]; ok {
+				content += " " + value
+			}
+		}
+	}
+
+	// the interaction must be answered, our responses will be linked to that
+	message, err := respondDiscordInteraction(
+		interaction.Get("application_id").String(),
+		interaction.Get("id").String(),
+		interaction.Get("token").String(),
+		"`"+content+"`")
+	if err != nil {
+		log.Warn().Err(err).Str("command", content).
+			Msg("failed to respond to discord interaction")
+		return
+	}
+
+	message.GuildID = interaction.Get("guild_id").String()
+	message.ChannelID = interaction.Get("channel_id").String()
+	message.Author = &author
+	message.Content = content
+	handleDiscordCommand(message, nil)
+}
+
+func handleDiscordCommand(message *discordgo.Message, replyTo *discordgo.Message) {
+	if shuttingDown() {
+		return
+	}
+
+	ctx := context.WithValue(context.Background(), "origin", "discord")
+	ctx = withRequestId(ctx)
 	ctx = context.WithValue(ctx, "message", message)
+	if replyTo != nil {
+		ctx = context.WithValue(ctx, "discordReplyTo", replyTo)
+	}
 
 	// this is just to send to amplitude
 	var groupId *string = nil
@@ -39,8 +142,8 @@ func handleDiscordMessage(dgs *discordgo.Session, m *discordgo.MessageCreate) {
 		commandName string
 	)
 
-	if message.Content[0] != '$' {
-		if bolt11, lnurltext, address, ok := searchForInvoice(ctx); ok {
+	if len(message.Content) == 0 || message.Content[0] != '$' {
+		if bolt11, lnurltext, address, ok := searchForInvoice(ctx, incomingMessage(ctx)); ok {
 			if bolt11 != "" {
 				commandName = "$pay"
 				opts, _, err = parse("/pay " + bolt11)
@@ -73,7 +176,7 @@ func handleDiscordMessage(dgs *discordgo.Session, m *discordgo.MessageCreate) {
 	messageText = "/" + message.Content[1:]
 
 	opts, isCommand, err = parse(messageText)
-	log.Debug().Str("t", messageText).Stringer("user", &u).Err(err).Msg("discord message")
+	logger(ctx).Debug().Str("t", messageText).Stringer("user", &u).Err(err).Msg("discord message")
 	if !isCommand {
 		// is this a reply we're waiting for?
 		// TODO
@@ -107,7 +210,7 @@ parsed:
 		message.Author.Username+"#"+message.Author.Discriminator,
 		message.Author.Locale)
 	if err != nil {
-		log.Warn().Err(err).
+		logger(ctx).Warn().Err(err).
 			Str("username",
 				message.Author.Username+"#"+message.Author.Discriminator).
 			Str("id", message.Author.ID).
@@ -120,7 +223,7 @@ parsed:
 
 	// stop if temporarily banned
 	if _, ok := s.Banned[u.Id]; ok {
-		log.Debug().Int("id", u.Id).Msg("got request from banned user")
+		logger(ctx).Debug().Int("id", u.Id).Msg("got request from banned user")
 		return
 	}
 
@@ -161,11 +264,17 @@ parsed:
 		go handleLightningATM(ctx)
 	case opts["tx"].(bool):
 		go handleSingleTransaction(ctx, opts)
-	case opts["transactions"].(bool):
+	case opts["transactions"].(bool), opts["history"].(bool):
 		go handleTransactionList(ctx, opts)
 	case opts["balance"].(bool):
 		go handleBalance(ctx, opts)
-	case opts["pay"].(bool), opts["withdraw"].(bool), opts["decode"].(bool):
+	case opts["decode"].(bool):
+		go handleDecode(ctx, opts)
+	case opts["pay"].(bool), opts["withdraw"].(bool):
+		if !u.allowPaymentRate(ctx) {
+			break
+		}
+
 		if opts["lnurl"].(bool) {
 			// create an lnurl-withdraw voucher
 			handleCreateLNURLWithdraw(ctx, opts)
@@ -191,7 +300,7 @@ parsed:
 		go u.track("help", map[string]interface{}{"command": command})
 		go handleHelp(ctx, command)
 	case opts["satoshis"].(bool), opts["calc"].(bool):
-		msats, err := parseSatoshis(opts)
+		msats, err := parseSatoshis(ctx, opts)
 		if err == nil {
 			send(ctx, fmt.Sprintf("%.15g sat", float64(msats)/1000))
 		}
@@ -202,6 +311,7 @@ parsed:
 
 func handleDiscordReaction(dgs *discordgo.Session, m *discordgo.MessageReactionAdd) {
 	ctx := context.WithValue(context.Background(), "origin", "discord")
+	ctx = withRequestId(ctx)
 	reaction := m.MessageReaction
 
 	ctx = context.WithValue(ctx,
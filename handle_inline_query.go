@@ -13,6 +13,10 @@ import (
 	"github.com/lucsky/cuid"
 )
 
+// how long telegram can show the same results to the same user again. kept
+// short because invoices can only be paid once.
+const inlineInvoiceCacheTime = 10 // seconds
+
 func handleInlineQuery(ctx context.Context, q *tgbotapi.InlineQuery) {
 	var (
 		u       User
@@ -25,7 +29,7 @@ func handleInlineQuery(ctx context.Context, q *tgbotapi.InlineQuery) {
 
 	u, err = loadTelegramUser(int(q.From.ID))
 	if err != nil {
-		log.Debug().Err(err).
+		logger(ctx).Debug().Err(err).
 			Str("username", q.From.UserName).
 			Int("id", q.From.ID).
 			Msg("unregistered user trying to use inline query")
@@ -44,13 +48,21 @@ func handleInlineQuery(ctx context.Context, q *tgbotapi.InlineQuery) {
 		command = command[1:]
 	}
 
+	if len(argv) == 1 {
+		// just an amount is a request for payment
+		if _, err := parseAmountString(ctx, command); err == nil {
+			argv = []string{"invoice", command}
+			command = "invoice"
+		}
+	}
+
 	if len(argv) < 2 {
 		goto answerEmpty
 	}
 
 	switch command {
 	case "invoice", "receive", "fund":
-		msats, err := parseAmountString(argv[1])
+		msats, err := parseAmountString(ctx, argv[1])
 		if err != nil {
 			goto answerEmpty
 		}
@@ -61,20 +73,23 @@ func handleInlineQuery(ctx context.Context, q *tgbotapi.InlineQuery) {
 				u.Username, msats/1000),
 		})
 		if err != nil {
-			log.Warn().Err(err).Msg("error making invoice on inline query.")
+			logger(ctx).Warn().Err(err).Msg("error making invoice on inline query.")
 			goto answerEmpty
 		}
 
-		result := tgbotapi.NewInlineQueryResultArticleHTML(
-			"inv-"+argv[1],
-			translateTemplate(ctx, t.INLINEINVOICERESULT, t.T{"Sats": argv[1]}),
-			bolt11,
-		)
+		results := []interface{}{
+			tgbotapi.NewInlineQueryResultArticleHTML(
+				"inv-"+argv[1],
+				translateTemplate(ctx, t.INLINEINVOICERESULT, t.T{"Sats": argv[1]}),
+				bolt11,
+			),
+		}
 
 		resp, err = bot.AnswerInlineQuery(tgbotapi.InlineConfig{
 			InlineQueryID: q.ID,
-			Results:       []interface{}{result},
+			Results:       results,
 			IsPersonal:    true,
+			CacheTime:     inlineInvoiceCacheTime,
 		})
 
 		go u.track("make invoice", map[string]interface{}{
@@ -82,6 +97,49 @@ func handleInlineQuery(ctx context.Context, q *tgbotapi.InlineQuery) {
 			"inline": true,
 		})
 		goto responded
+	case "lnurlw":
+		// vouchers are only made when asked for by name. each one has its own
+		// challenge, those made while typing are never shown to anyone else.
+		msats, err := parseAmountString(ctx, argv[1])
+		if err != nil || msats == 0 {
+			goto answerEmpty
+		}
+
+		// not using checkBalanceFor as it would notify the user on every keystroke
+		if getBalance(pg, u.Id) < msats {
+			goto answerEmpty
+		}
+
+		// vouchers over the 2fa threshold are refused, there is no way to
+		// ask for the code here
+		enc, err := u.lnurlWithdrawVoucher(ctx, msats/1000)
+		if err != nil {
+			logger(ctx).Warn().Err(err).Msg("error making voucher on inline query.")
+			goto answerEmpty
+		}
+
+		resp, err = bot.AnswerInlineQuery(tgbotapi.InlineConfig{
+			InlineQueryID: q.ID,
+			Results: []interface{}{
+				tgbotapi.NewInlineQueryResultArticleHTML(
+					"lnurlw-"+argv[1],
+					translateTemplate(ctx, t.INLINEVOUCHERRESULT, t.T{"Sats": msats / 1000}),
+					translateTemplate(ctx, t.INLINEVOUCHERMSG, t.T{
+						"User":  u.AtName(ctx),
+						"Sats":  msats / 1000,
+						"LNURL": enc,
+					}),
+				),
+			},
+			IsPersonal: true,
+			CacheTime:  inlineInvoiceCacheTime,
+		})
+
+		go u.track("lnurl generate", map[string]interface{}{
+			"sats":   msats / 1000,
+			"inline": true,
+		})
+		goto responded
 	case "give", "giveaway":
 		if len(argv) != 2 && len(argv) != 3 {
 			goto answerEmpty
@@ -90,7 +148,7 @@ func handleInlineQuery(ctx context.Context, q *tgbotapi.InlineQuery) {
 			goto answerEmpty
 		}
 
-		msats, err := parseAmountString(argv[1])
+		msats, err := parseAmountString(ctx, argv[1])
 		if err != nil {
 			break
 		}
@@ -192,7 +250,7 @@ func handleInlineQuery(ctx context.Context, q *tgbotapi.InlineQuery) {
 			goto answerEmpty
 		}
 
-		msats, err := parseAmountString(argv[1])
+		msats, err := parseAmountString(ctx, argv[1])
 		if err != nil {
 			break
 		}
@@ -300,7 +358,7 @@ func handleInlineQuery(ctx context.Context, q *tgbotapi.InlineQuery) {
 
 responded:
 	if err != nil || !resp.Ok {
-		log.Warn().Err(err).
+		logger(ctx).Warn().Err(err).
 			Str("resp", resp.Description).
 			Msg("error answering inline query")
 	}
@@ -3,6 +3,7 @@ package main
 import (
 	"context"
 	"fmt"
+	"time"
 
 	"github.com/fiatjaf/lntxbot/t"
 	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api"
@@ -15,29 +16,78 @@ func handleReply(ctx context.Context) {
 	inreplyto := message.ReplyToMessage.MessageID
 
 	key := fmt.Sprintf("reply:%d:%d", u.Id, inreplyto)
-	if val, err := rds.Get(key).Result(); err != nil {
-		log.Debug().Int("userId", u.Id).Int("message", inreplyto).
+	val, err := rds.Get(key).Result()
+	if err != nil {
+		logger(ctx).Debug().Int("userId", u.Id).Int("message", inreplyto).
 			Msg("reply to bot message doesn't have a stored procedure")
-	} else {
-		switch gjson.Parse(val).Get("type").String() {
-		case "pay":
-			msats, err := parseAmountString(message.Text)
-			if err != nil {
-				send(ctx, u, t.ERROR, t.T{"Err": "Invalid satoshi amount."})
-			}
-			handlePayVariableAmount(ctx, msats, val)
-		case "lnurlpay-amount":
-			msats, err := parseAmountString(message.Text)
+		return
+	}
+
+	// entries may outlive their prompt if the timeout was changed
+	promptTime := time.Unix(int64(message.ReplyToMessage.Date), 0)
+	if time.Since(promptTime) > s.ReplyPromptTimeout {
+		logger(ctx).Debug().Int("userId", u.Id).Int("message", inreplyto).
+			Msg("reply to an expired bot prompt")
+		rds.Del(key)
+		return
+	}
+
+	if !gjson.Valid(val) {
+		logger(ctx).Warn().Int("userId", u.Id).Int("message", inreplyto).
+			Str("val", val).Msg("reply to bot message has invalid stored data")
+		rds.Del(key)
+		return
+	}
+
+	// each prompt is answered only once, but invalid answers can be fixed in
+	// another reply so the entry is only deleted after parsing
+	switch gjson.Parse(val).Get("type").String() {
+	case "pay":
+		msats, err := parseAmountString(ctx, message.Text)
+		if err != nil {
+			send(ctx, u, t.ERROR, t.T{"Err": "Invalid satoshi amount."})
+			break
+		}
+		rds.Del(key)
+		handlePayVariableAmount(ctx, msats, val)
+	case "lnurlpay-amount":
+		// amounts like "5 usd" are converted and must be confirmed
+		msats, fiat, currency, err := parseFiatAmount(message.Text)
+		if currency != "" {
 			if err != nil {
-				send(ctx, u, t.ERROR, t.T{"Err": "Invalid satoshi amount."})
+				send(ctx, u, t.ERROR, t.T{"Err": err.Error()})
+				break
 			}
-			handleLNURLPayAmount(ctx, msats, val)
-		case "lnurlpay-comment":
-			handleLNURLPayComment(ctx, message.Text, val)
-		default:
-			log.Debug().Int("userId", u.Id).Int("message", inreplyto).
-				Str("type", gjson.Parse(val).Get("type").String()).
-				Msg("reply to bot message unhandled procedure")
+			handleLNURLPayFiatAmount(ctx, msats, fiat, currency, val, key)
+			break
+		}
+
+		msats, err = parseAmountString(ctx, message.Text)
+		if err != nil {
+			send(ctx, u, t.ERROR, t.T{"Err": "Invalid satoshi amount."})
+			break
+		}
+		handleLNURLPayAmount(ctx, msats, val, key)
+	case "lnurlwithdraw-amount":
+		msats, err := parseAmountString(ctx, message.Text)
+		if err != nil {
+			send(ctx, u, t.ERROR, t.T{"Err": "Invalid satoshi amount."})
+			break
+		}
+		rds.Del(key)
+		handleLNURLWithdrawAmount(ctx, msats, val)
+	case "2fa":
+		// a wrong code can be fixed with another reply
+		if handleTwoFactorReply(ctx, message.Text, val) {
+			rds.Del(key)
 		}
+	case "lnurlpay-comment":
+		rds.Del(key)
+		handleLNURLPayComment(ctx, message.Text, val)
+	default:
+		logger(ctx).Debug().Int("userId", u.Id).Int("message", inreplyto).
+			Str("type", gjson.Parse(val).Get("type").String()).
+			Msg("reply to bot message unhandled procedure")
+		rds.Del(key)
 	}
 }
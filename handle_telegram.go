@@ -8,6 +8,7 @@ import (
 
 func handle(upd tgbotapi.Update) {
 	ctx := context.WithValue(context.Background(), "origin", "telegram")
+	ctx = withRequestId(ctx)
 
 	switch {
 	case upd.Message != nil:
@@ -24,7 +24,7 @@ func handleTelegramMessage(ctx context.Context, message *tgbotapi.Message) {
 
 	u, tcase, err := ensureTelegramUser(message)
 	if err != nil {
-		log.Warn().Err(err).Int("case", tcase).
+		logger(ctx).Warn().Err(err).Int("case", tcase).
 			Str("username", message.From.UserName).
 			Int("id", message.From.ID).
 			Msg("failed to ensure telegram user")
@@ -33,7 +33,7 @@ func handleTelegramMessage(ctx context.Context, message *tgbotapi.Message) {
 
 	// stop if temporarily banned
 	if _, ok := s.Banned[u.Id]; ok {
-		log.Debug().Stringer("id", &u).Msg("got request from banned user")
+		logger(ctx).Debug().Stringer("id", &u).Msg("got request from banned user")
 		return
 	}
 
@@ -64,7 +64,7 @@ func handleTelegramMessage(ctx context.Context, message *tgbotapi.Message) {
 		loadedGroup, err := loadTelegramGroup(message.Chat.ID)
 		if err != nil {
 			if err != sql.ErrNoRows {
-				log.Warn().Err(err).Int64("id", message.Chat.ID).Msg("failed to load group")
+				logger(ctx).Warn().Err(err).Int64("id", message.Chat.ID).Msg("failed to load group")
 			}
 			// proceed with an empty group (manually defined before)
 		} else {
@@ -98,9 +98,13 @@ func handleTelegramMessage(ctx context.Context, message *tgbotapi.Message) {
 	// when receiving a forwarded invoice (from messages from other people?)
 	// or just the full text of a an invoice (shared from a phone wallet?)
 	if !strings.HasPrefix(messageText, "/") {
-		if bolt11, lnurltext, address, ok := searchForInvoice(ctx); ok {
-			if bolt11 != "" {
-				opts, _, err = parse("/pay " + bolt11)
+		if bolt11s, lnurltext, address, ok := searchForInvoices(ctx, incomingMessage(ctx)); ok {
+			if len(bolt11s) > 1 {
+				handlePayChoose(ctx, u, bolt11s)
+				return
+			}
+			if len(bolt11s) == 1 {
+				opts, _, err = parse("/pay " + bolt11s[0])
 				if err != nil {
 					return
 				}
@@ -124,6 +128,24 @@ func handleTelegramMessage(ctx context.Context, message *tgbotapi.Message) {
 		}
 	}
 
+	// check the ledger
+	if message.Chat.Type == "private" &&
+		s.AdminAccount > 0 &&
+		u.Id == s.AdminAccount &&
+		messageText == "/audit" {
+		go handleAudit(ctx)
+		return
+	}
+
+	// change the invoice limits of a user
+	if message.Chat.Type == "private" &&
+		s.AdminAccount > 0 &&
+		u.Id == s.AdminAccount &&
+		strings.HasPrefix(messageText, "/invoicelimits ") {
+		go handleInvoiceLimits(ctx, strings.Fields(messageText[15:]))
+		return
+	}
+
 	// manage the underlying node
 	if message.Chat.Type == "private" &&
 		s.AdminAccount > 0 &&
@@ -154,7 +176,7 @@ func handleTelegramMessage(ctx context.Context, message *tgbotapi.Message) {
 			}
 		}
 
-		resp, err := ln.Call(method, params)
+		resp, err := ln().Call(method, params)
 		if err != nil {
 			send(ctx, u, t.ERROR, t.T{"Err": err.Error()})
 			return
@@ -166,7 +188,7 @@ func handleTelegramMessage(ctx context.Context, message *tgbotapi.Message) {
 
 	// otherwise parse the slash command
 	opts, isCommand, err = parse(messageText)
-	log.Debug().Str("t", messageText).Stringer("user", &u).Err(err).
+	logger(ctx).Debug().Str("t", messageText).Stringer("user", &u).Err(err).
 		Msg("telegram message")
 	if !isCommand {
 		if message.ReplyToMessage != nil &&
@@ -182,8 +204,7 @@ func handleTelegramMessage(ctx context.Context, message *tgbotapi.Message) {
 			// only tell we don't understand commands when in a private chat
 			// because these commands we're not understanding
 			// may be targeting other bots in a group, so we're spamming people.
-			method := strings.Split(messageText, " ")[0][1:]
-			handled := handleHelp(ctx, method)
+			handled := handleDidYouMean(ctx, messageText)
 			if !handled {
 				send(ctx, u, t.WRONGCOMMAND)
 			}
@@ -202,6 +223,17 @@ parsed:
 		opts["now"] = true
 	}
 
+	// admins of groups with a shared account spend from it
+	if account, ok := treasuryUser(message, g, opts); ok {
+		logger(ctx).Debug().Stringer("user", &u).Stringer("group", &g).
+			Msg("using the group account")
+		u = account
+		ctx = context.WithValue(ctx, "initiator", u)
+
+		// a confirmation button could be pressed by anyone in the group
+		opts["now"] = true
+	}
+
 	switch {
 	case opts["start"].(bool):
 		handleStart(ctx)
@@ -228,13 +260,15 @@ parsed:
 			"reply-tip": message.ReplyToMessage != nil,
 		})
 		handleSend(ctx, opts)
+	case opts["sendmany"].(bool):
+		go handleSendMany(ctx, opts)
 	case opts["giveaway"].(bool):
-		msats, err := parseSatoshis(opts)
+		msats, err := parseSatoshis(ctx, opts)
 		if err != nil {
 			send(ctx, u, t.ERROR, t.T{"Err": err.Error()})
 			break
 		}
-		if !u.checkBalanceFor(ctx, msats, "giveaway") {
+		if !checkTipLimit(ctx, msats) || !u.checkBalanceFor(ctx, msats, "giveaway") {
 			break
 		}
 
@@ -251,12 +285,12 @@ parsed:
 		})
 		break
 	case opts["giveflip"].(bool):
-		msats, err := parseSatoshis(opts)
+		msats, err := parseSatoshis(ctx, opts)
 		if err != nil {
 			send(ctx, u, t.ERROR, t.T{"Err": err.Error()})
 			break
 		}
-		if !u.checkBalanceFor(ctx, msats, "giveflip") {
+		if !checkTipLimit(ctx, msats) || !u.checkBalanceFor(ctx, msats, "giveflip") {
 			break
 		}
 
@@ -297,7 +331,7 @@ parsed:
 		}
 
 		// open a lottery between a number of users in a group
-		msats, err := parseSatoshis(opts)
+		msats, err := parseSatoshis(ctx, opts)
 		if err != nil {
 			send(ctx, u, t.ERROR, t.T{"Err": err.Error()})
 			break
@@ -331,9 +365,51 @@ parsed:
 			"n":     nparticipants,
 		})
 		rds.Set(fmt.Sprintf("recentcoinflip:%d", u.Id), "t", time.Minute*30)
+	case opts["freeze"].(bool):
+		go handleFreeze(ctx)
+	case opts["unfreeze"].(bool):
+		go handleUnfreeze(ctx, opts)
+	case opts["twofactor"].(bool), opts["2fa"].(bool):
+		go handleTwoFactor(ctx, opts)
+	case opts["drop"].(bool), opts["tipball"].(bool):
+		// the first n to click get a share each, straight from the dropper
+		msats, err := parseSatoshis(ctx, opts)
+		if err != nil {
+			send(ctx, u, t.ERROR, t.T{"Err": err.Error()})
+			break
+		}
+
+		nparticipants, err := opts.Int("<num_participants>")
+		if err != nil || nparticipants < 1 || nparticipants > 100 {
+			send(ctx, u, t.INVALIDPARTNUMBER, t.T{"Number": nparticipants})
+			break
+		}
+
+		share := int(msats/1000) / nparticipants
+		if share < 1 {
+			send(ctx, u, t.ERROR, t.T{"Err": messageFromError(ctx, ErrInvalidAmount)})
+			break
+		}
+		if !checkTipLimit(ctx, int64(share)*1000) ||
+			!u.checkBalanceFor(ctx, int64(share*nparticipants)*1000, "drop") {
+			break
+		}
+
+		dropid := cuid.Slug()
+		rds.Set("dropopen:"+dropid, "t", s.GiveAwayTimeout)
+
+		send(ctx, g, t.DROPMSG, FORCESPAMMY,
+			dropAdData(ctx, u, nil, nparticipants, share),
+			dropKeyboard(ctx, dropid, u.Id, nparticipants, share))
+
+		go u.track("drop created", map[string]interface{}{
+			"group": groupId,
+			"sats":  share * nparticipants,
+			"n":     nparticipants,
+		})
 	case opts["fundraise"].(bool), opts["crowdfund"].(bool):
 		// many people join, we get all the money and transfer to the target
-		msats, err := parseSatoshis(opts)
+		msats, err := parseSatoshis(ctx, opts)
 		if err != nil {
 			send(ctx, u, t.ERROR, t.T{"Err": err.Error()})
 			break
@@ -351,18 +427,21 @@ parsed:
 
 		receiver, err := examineTelegramUsername(opts["<receiver>"].(string))
 		if err != nil {
-			log.Warn().Err(err).Msg("parsing fundraise receiver")
+			logger(ctx).Warn().Err(err).Msg("parsing fundraise receiver")
 			send(ctx, u, t.FAILEDUSER)
 			break
 		}
 
-		send(ctx, g, t.FUNDRAISEAD, FORCESPAMMY, t.T{
-			"ToUser":       receiver.AtName(ctx),
-			"Participants": nparticipants,
-			"Sats":         sats,
-			"Fund":         sats * nparticipants,
-			"Registered":   u.AtName(ctx),
-		}, fundraiseKeyboard(ctx, "", u.Id, receiver.Id, nparticipants, sats))
+		fundraiseid := cuid.Slug()
+		title := strings.TrimSpace(
+			strings.Join(opts["<title>"].([]string), " "))
+		if title != "" {
+			rds.Set("fundraisetitle:"+fundraiseid, title, s.GiveAwayTimeout)
+		}
+
+		send(ctx, g, t.FUNDRAISEAD, FORCESPAMMY,
+			fundraiseAdData(ctx, fundraiseid, *receiver, []User{u}, nparticipants, sats),
+			fundraiseKeyboard(ctx, fundraiseid, u.Id, receiver.Id, nparticipants, sats))
 
 		go u.track("fundraise created", map[string]interface{}{
 			"group": groupId,
@@ -410,7 +489,7 @@ parsed:
 			return
 		}
 
-		msats, err := parseSatoshis(opts)
+		msats, err := parseSatoshis(ctx, opts)
 		if err != nil || msats == 0 {
 			send(ctx, u, t.ERROR, t.T{"Err": err.Error()})
 			return
@@ -502,11 +581,17 @@ parsed:
 			send(ctx, u, g, FORCESPAMMY,
 				hidden.Preview, revealKeyboard(ctx, redisKey, hidden, 0))
 		}()
-	case opts["transactions"].(bool):
+	case opts["transactions"].(bool), opts["history"].(bool):
 		go handleTransactionList(ctx, opts)
 	case opts["balance"].(bool):
 		go handleBalance(ctx, opts)
-	case opts["pay"].(bool), opts["withdraw"].(bool), opts["decode"].(bool):
+	case opts["decode"].(bool):
+		go handleDecode(ctx, opts)
+	case opts["pay"].(bool), opts["withdraw"].(bool):
+		if !u.allowPaymentRate(ctx) {
+			break
+		}
+
 		if opts["lnurl"].(bool) {
 			// create an lnurl-withdraw voucher
 			handleCreateLNURLWithdraw(ctx, opts)
@@ -515,8 +600,10 @@ parsed:
 			handlePay(ctx, u, opts)
 		}
 	case opts["receive"].(bool), opts["invoice"].(bool), opts["fund"].(bool):
-		desc := getVariadicFieldOrReplyToContent(ctx, opts, "<description>")
+		desc := getVariadicFieldOrReplyToContent(incomingMessage(ctx), opts, "<description>")
 		go handleInvoice(ctx, opts, desc)
+	case opts["lnurlauth"].(bool):
+		go handleLNURLAuthKey(ctx, opts)
 	case opts["lnurl"].(bool):
 		go handleLNURL(ctx, opts["<lnurl>"].(string), handleLNURLOpts{
 			anonymous: opts["--anonymous"].(bool),
@@ -530,7 +617,7 @@ parsed:
 				return
 			}
 
-			name := getVariadicFieldOrReplyToContent(ctx, opts, "<name>")
+			name := getVariadicFieldOrReplyToContent(incomingMessage(ctx), opts, "<name>")
 
 			price := g.getRenamePrice()
 			if price == 0 {
@@ -570,11 +657,11 @@ parsed:
 							"lang":     lang,
 							"personal": true,
 						})
-						log.Info().Stringer("user", &u).Str("language", lang).
+						logger(ctx).Info().Stringer("user", &u).Str("language", lang).
 							Msg("toggling language")
 						err := setLanguage(u.TelegramChatId, lang)
 						if err != nil {
-							log.Warn().Err(err).Msg("failed to toggle language")
+							logger(ctx).Warn().Err(err).Msg("failed to toggle language")
 							send(ctx, u, t.ERROR, t.T{"Err": err.Error()})
 							break
 						}
@@ -582,6 +669,95 @@ parsed:
 					} else {
 						send(ctx, u, t.LANGUAGEMSG, t.T{"Language": u.Locale})
 					}
+				case opts["currency"].(bool):
+					if currency, err := opts.String("<currency>"); err == nil {
+						go u.track("toggle currency", map[string]interface{}{
+							"currency": currency,
+						})
+						logger(ctx).Info().Stringer("user", &u).Str("currency", currency).
+							Msg("toggling currency")
+						err := u.setCurrency(currency)
+						if err != nil {
+							logger(ctx).Warn().Err(err).Msg("failed to toggle currency")
+							send(ctx, u, t.ERROR, t.T{"Err": err.Error()})
+							break
+						}
+					}
+					send(ctx, u, t.CURRENCYMSG, t.T{"Currency": u.Currency})
+				case opts["confirm"].(bool):
+					msats, _ := parseSatoshis(ctx, opts)
+					sats := msats / 1000
+
+					go u.track("toggle confirm", map[string]interface{}{
+						"sats": sats,
+					})
+					logger(ctx).Info().Stringer("user", &u).Int64("sats", sats).
+						Msg("toggling pay confirmation threshold")
+
+					if err := u.setPayConfirmThreshold(sats); err != nil {
+						logger(ctx).Warn().Err(err).Msg("failed to toggle confirm")
+						send(ctx, u, t.ERROR, t.T{"Err": ErrDatabase.Error()})
+						break
+					}
+					send(ctx, u, t.PAYCONFIRMMSG, t.T{"Sats": sats})
+				case opts["alert"].(bool):
+					msats, _ := parseSatoshis(ctx, opts)
+					sats := msats / 1000
+
+					go u.track("toggle alert", map[string]interface{}{
+						"sats": sats,
+					})
+					logger(ctx).Info().Stringer("user", &u).Int64("sats", sats).
+						Msg("toggling spend alert threshold")
+
+					if err := u.setSpendAlert(sats); err != nil {
+						logger(ctx).Warn().Err(err).Msg("failed to toggle alert")
+						send(ctx, u, t.ERROR, t.T{"Err": ErrDatabase.Error()})
+						break
+					}
+					send(ctx, u, t.SPENDALERTMSG, t.T{"Sats": u.SpendAlert})
+				case opts["description"].(bool):
+					template := strings.Join(opts["<description>"].([]string), " ")
+
+					go u.track("toggle description", map[string]interface{}{
+						"custom": template != "",
+					})
+					logger(ctx).Info().Stringer("user", &u).Str("template", template).
+						Msg("toggling invoice description")
+
+					if err := u.setInvoiceDescription(template); err != nil {
+						logger(ctx).Warn().Err(err).Msg("failed to toggle description")
+						send(ctx, u, t.ERROR, t.T{"Err": ErrDatabase.Error()})
+						break
+					}
+					send(ctx, u, t.INVOICEDESCRIPTIONMSG, t.T{
+						"Description": escapeHTML(u.invoiceDescription()),
+						"Custom":      template != "",
+					})
+				case opts["qr"].(bool):
+					if err := u.toggleSkipQR(); err != nil {
+						logger(ctx).Warn().Err(err).Msg("failed to toggle qr")
+						send(ctx, u, t.ERROR, t.T{"Err": ErrDatabase.Error()})
+						break
+					}
+
+					go u.track("toggle qr", map[string]interface{}{
+						"skip": u.SkipQR,
+					})
+
+					send(ctx, u, t.SKIPQRMSG, t.T{"Skip": u.SkipQR})
+				case opts["roman"].(bool):
+					if err := u.toggleRoman(); err != nil {
+						logger(ctx).Warn().Err(err).Msg("failed to toggle roman")
+						send(ctx, u, t.ERROR, t.T{"Err": ErrDatabase.Error()})
+						break
+					}
+
+					go u.track("toggle roman", map[string]interface{}{
+						"roman": u.Roman,
+					})
+
+					send(ctx, u, t.ROMANMSG, t.T{"Roman": u.Roman})
 				default:
 					send(ctx, u, t.MUSTBEGROUP)
 					return
@@ -596,7 +772,7 @@ parsed:
 
 			g, err := ensureTelegramGroup(message.Chat.ID, u.Locale)
 			if err != nil {
-				log.Warn().Err(err).Stringer("user", &u).Int64("group", message.Chat.ID).
+				logger(ctx).Warn().Err(err).Stringer("user", &u).Int64("group", message.Chat.ID).
 					Msg("failed to ensure group")
 				return
 			}
@@ -604,8 +780,8 @@ parsed:
 			ctx = context.WithValue(ctx, "spammy", true)
 			switch {
 			case opts["ticket"].(bool):
-				log.Info().Stringer("group", &g).Msg("toggling ticket")
-				msats, err := parseSatoshis(opts)
+				logger(ctx).Info().Stringer("group", &g).Msg("toggling ticket")
+				msats, err := parseSatoshis(ctx, opts)
 				if err != nil {
 					g.setTicketPrice(0)
 					send(ctx, g, t.FREEJOIN)
@@ -622,8 +798,8 @@ parsed:
 					send(ctx, g, t.TICKETSET, t.T{"Sat": sats})
 				}
 			case opts["expensive"].(bool):
-				log.Info().Stringer("group", &g).Msg("toggling expensive")
-				msats, _ := parseSatoshis(opts)
+				logger(ctx).Info().Stringer("group", &g).Msg("toggling expensive")
+				msats, _ := parseSatoshis(ctx, opts)
 				pattern, _ := opts.String("<pattern>")
 				pattern = strings.ToLower(pattern)
 				sats := int(msats / 1000)
@@ -656,8 +832,8 @@ parsed:
 					})
 				}
 			case opts["renamable"].(bool):
-				log.Info().Stringer("group", &g).Msg("toggling renamable")
-				msats, err := parseSatoshis(opts)
+				logger(ctx).Info().Stringer("group", &g).Msg("toggling renamable")
+				msats, err := parseSatoshis(ctx, opts)
 				if err != nil {
 					g.setTicketPrice(0)
 					send(ctx, g, t.FREEJOIN)
@@ -673,11 +849,27 @@ parsed:
 				if sats > 0 {
 					send(ctx, g, t.RENAMABLEMSG, t.T{"Sat": sats})
 				}
+			case opts["tiplimit"].(bool):
+				logger(ctx).Info().Stringer("group", &g).Msg("toggling tiplimit")
+				msats, _ := parseSatoshis(ctx, opts)
+				sats := int(msats / 1000)
+
+				go u.track("toggle tiplimit", map[string]interface{}{
+					"group": groupId,
+					"sats":  sats,
+				})
+
+				if err := g.setTipLimit(sats); err != nil {
+					logger(ctx).Warn().Err(err).Stringer("group", &g).Msg("failed to set tip limit")
+					send(ctx, g, t.ERROR, t.T{"Err": ErrDatabase.Error()})
+					break
+				}
+				send(ctx, g, t.TIPLIMITMSG, t.T{"Sat": sats})
 			case opts["spammy"].(bool):
-				log.Debug().Stringer("group", &g).Msg("toggling spammy")
+				logger(ctx).Debug().Stringer("group", &g).Msg("toggling spammy")
 				spammy, err := g.toggleSpammy()
 				if err != nil {
-					log.Warn().Err(err).Msg("failed to toggle spammy")
+					logger(ctx).Warn().Err(err).Msg("failed to toggle spammy")
 					send(ctx, g, t.ERROR, t.T{"Err": err.Error()})
 					break
 				}
@@ -689,10 +881,10 @@ parsed:
 
 				send(ctx, g, t.SPAMMYMSG, t.T{"Spammy": spammy})
 			case opts["coinflips"].(bool):
-				log.Debug().Stringer("group", &g).Msg("toggling coinflips")
+				logger(ctx).Debug().Stringer("group", &g).Msg("toggling coinflips")
 				enabled, err := g.toggleCoinflips()
 				if err != nil {
-					log.Warn().Err(err).Msg("failed to toggle coinflips")
+					logger(ctx).Warn().Err(err).Msg("failed to toggle coinflips")
 					send(ctx, g, t.ERROR, t.T{"Err": err.Error()})
 					break
 				}
@@ -703,13 +895,28 @@ parsed:
 				})
 
 				send(ctx, g, t.COINFLIPSENABLEDMSG, t.T{"Enabled": enabled})
+			case opts["treasury"].(bool):
+				logger(ctx).Debug().Stringer("group", &g).Msg("toggling treasury")
+				enabled, err := g.toggleTreasury()
+				if err != nil {
+					logger(ctx).Warn().Err(err).Msg("failed to toggle treasury")
+					send(ctx, g, t.ERROR, t.T{"Err": err.Error()})
+					break
+				}
+
+				go u.track("toggle treasury", map[string]interface{}{
+					"group":   groupId,
+					"enabled": enabled,
+				})
+
+				send(ctx, g, t.TREASURYENABLEDMSG, t.T{"Enabled": enabled})
 			case opts["language"].(bool):
 				if lang, err := opts.String("<lang>"); err == nil {
-					log.Info().Stringer("group", &g).Str("language", lang).
+					logger(ctx).Info().Stringer("group", &g).Str("language", lang).
 						Msg("toggling language")
 					err := setLanguage(message.Chat.ID, lang)
 					if err != nil {
-						log.Warn().Err(err).Msg("failed to toggle language")
+						logger(ctx).Warn().Err(err).Msg("failed to toggle language")
 						send(ctx, u, t.ERROR, t.T{"Err": err.Error()})
 						break
 					}
@@ -728,8 +935,36 @@ parsed:
 		}()
 	case opts["sats4ads"].(bool):
 		handleSats4Ads(ctx, u, opts)
+	case opts["address"].(bool), opts["lightningaddress"].(bool):
+		go handleLightningAddress(ctx, opts)
+	case opts["tipjar"].(bool):
+		go handleTipJar(ctx, opts)
+	case opts["nodeinfo"].(bool):
+		go handleNodeInfo(ctx, opts)
+	case opts["payquote"].(bool):
+		go handlePayQuote(ctx, opts)
+	case opts["setmaxfee"].(bool):
+		go handleSetMaxFee(ctx, opts)
+	case opts["webhook"].(bool):
+		go handleWebhook(ctx, opts)
+	case opts["schedule"].(bool):
+		go handleSchedule(ctx, opts)
+	case opts["pricealert"].(bool):
+		go handlePriceAlert(ctx, opts)
+	case opts["menu"].(bool):
+		go handleMenu(ctx, opts)
+	case opts["treasury"].(bool):
+		go handleTreasury(ctx, opts)
+	case opts["stats"].(bool):
+		go handleStats(ctx)
+	case opts["language"].(bool):
+		go handleLanguage(ctx, opts)
+	case opts["qr"].(bool):
+		go handleQR(ctx, opts)
+	case opts["convert"].(bool):
+		go handleConvert(ctx, opts)
 	case opts["satoshis"].(bool), opts["calc"].(bool):
-		msats, err := parseSatoshis(opts)
+		msats, err := parseSatoshis(ctx, opts)
 		if err == nil {
 			send(ctx, fmt.Sprintf("%.15g sat", float64(msats)/1000))
 		}
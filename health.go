@@ -0,0 +1,86 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"sync"
+	"time"
+)
+
+const healthCacheTime = 10 * time.Second
+
+type healthStatus struct {
+	OK          bool      `json:"ok"`
+	Database    string    `json:"database"`
+	Redis       string    `json:"redis"`
+	Node        string    `json:"node"`
+	BlockHeight int       `json:"block_height"`
+	Channels    int       `json:"channels"`
+	CheckedAt   time.Time `json:"checked_at"`
+}
+
+var (
+	lastHealth      healthStatus
+	lastHealthMutex sync.Mutex
+)
+
+// checkHealth queries postgres, redis and cliche, but at most once every
+// healthCacheTime so probes don't keep hammering the node.
+func checkHealth() healthStatus {
+	lastHealthMutex.Lock()
+	defer lastHealthMutex.Unlock()
+
+	if time.Since(lastHealth.CheckedAt) < healthCacheTime {
+		return lastHealth
+	}
+
+	status := healthStatus{
+		OK:        true,
+		Database:  "ok",
+		Redis:     "ok",
+		Node:      "ok",
+		CheckedAt: time.Now(),
+	}
+
+	var one int
+	if err := pg.Get(&one, "SELECT 1"); err != nil {
+		status.OK = false
+		status.Database = err.Error()
+	}
+
+	if err := rds.Ping().Err(); err != nil {
+		status.OK = false
+		status.Redis = err.Error()
+	}
+
+	info, err := ln().GetInfo()
+	if retryAfterReconnect(err) {
+		info, err = ln().GetInfo()
+	}
+	if err != nil {
+		status.OK = false
+		status.Node = err.Error()
+	} else {
+		status.BlockHeight = info.BlockHeight
+		status.Channels = len(info.Channels)
+	}
+
+	if !status.OK {
+		log.Warn().Interface("status", status).Msg("health check failed")
+	}
+
+	lastHealth = status
+	return status
+}
+
+func serveHealth() {
+	router.Path("/health").HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		status := checkHealth()
+
+		w.Header().Set("Content-Type", "application/json")
+		if !status.OK || shuttingDown() {
+			w.WriteHeader(http.StatusServiceUnavailable)
+		}
+		json.NewEncoder(w).Encode(status)
+	})
+}
@@ -11,18 +11,23 @@ import (
 	"fmt"
 	"image"
 	"image/jpeg"
+	"io"
 	"io/ioutil"
+	"math"
 	"math/big"
 	"net/http"
+	"os"
 	"regexp"
+	"sort"
 	"strconv"
 	"strings"
+	"sync"
+	"time"
+	"unicode"
 
-	"github.com/bwmarrin/discordgo"
 	"github.com/docopt/docopt-go"
 	"github.com/fiatjaf/go-lnurl"
 	"github.com/fiatjaf/lntxbot/t"
-	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api"
 	"github.com/lithammer/fuzzysearch/fuzzy"
 	"github.com/nfnt/resize"
 	cmap "github.com/orcaman/concurrent-map"
@@ -30,7 +35,10 @@ import (
 	"github.com/tidwall/gjson"
 )
 
-var bolt11regex = regexp.MustCompile(`.*?((lnbcrt|lntb|lnbc)([0-9]{1,}[a-z0-9]+){1})`)
+// bolt11regex matches a lowercased invoice: the human-readable part with an
+// optional amount, the "1" separator and then only bech32 characters, so quotes,
+// parentheses and trailing periods around the invoice are left out.
+var bolt11regex = regexp.MustCompile(`\b((?:lnbcrt|lntbs|lntb|lnsb|lnbc)(?:\d+[munp]?)?1[qpzry9x8gf2tvdw0s3jn54khce6mua7l]+)`)
 
 var menuItems = map[string]*big.Rat{
 	"msat":  big.NewRat(1, 1),
@@ -54,16 +62,30 @@ var menuItems = map[string]*big.Rat{
 	"crown":      big.NewRat(10000000, 1),
 }
 
-func parseSatoshis(opts docopt.Opts) (msats int64, err error) {
+// amountSuffixes are the units accepted right after a number, like "100k" or
+// "0.0001btc", with their values in msatoshis.
+var amountSuffixes = map[string]int64{
+	"msat":  1,
+	"msats": 1,
+	"sat":   1000,
+	"sats":  1000,
+	"k":     1000 * 1000,
+	"m":     1000000 * 1000,
+	"btc":   100000000000,
+}
+
+var amountWithSuffixRe = regexp.MustCompile(`^(\d*\.?\d+)\s*(msats?|sats?|k|m|btc)$`)
+
+func parseSatoshis(ctx context.Context, opts docopt.Opts) (msats int64, err error) {
 	amt, ok := opts["<satoshis>"].(string)
 	if !ok {
 		return 0, errors.New("'satoshis' param missing")
 	}
 
-	return parseAmountString(amt)
+	return parseAmountString(ctx, amt)
 }
 
-func parseAmountString(amt string) (msats int64, err error) {
+func parseAmountString(ctx context.Context, amt string) (msats int64, err error) {
 	defer func() {
 		if err == nil && msats < 1000 {
 			err = fmt.Errorf("amount too small: %dmsat", msats)
@@ -73,9 +95,30 @@ func parseAmountString(amt string) (msats int64, err error) {
 	// is a number
 	sats, err := strconv.ParseFloat(amt, 64)
 	if err == nil {
+		if sats < 0 {
+			return 0, errors.New("'satoshis' param invalid")
+		}
+		if sats*1000 > math.MaxInt64 {
+			return 0, errors.New("amount too large")
+		}
 		return int64(sats * 1000), nil
 	}
 
+	// is a number with a unit suffix, always converted to whole satoshis
+	if match := amountWithSuffixRe.FindStringSubmatch(
+		strings.ToLower(strings.TrimSpace(amt))); match != nil {
+		value, ok := new(big.Rat).SetString(match[1])
+		if !ok {
+			return 0, errors.New("'satoshis' param invalid")
+		}
+		value.Mul(value, big.NewRat(amountSuffixes[match[2]], 1000))
+		sats := new(big.Int).Quo(value.Num(), value.Denom())
+		if !sats.IsInt64() || sats.Int64() > math.MaxInt64/1000 {
+			return 0, errors.New("amount too large")
+		}
+		return sats.Int64() * 1000, nil
+	}
+
 	// replace emojis
 	amt = strings.ReplaceAll(amt, "🍌", "banana")
 	amt = strings.ReplaceAll(amt, "🍉", "watermelon")
@@ -100,6 +143,15 @@ func parseAmountString(amt string) (msats int64, err error) {
 		p.Variables[k] = v
 	}
 
+	// add chat-specific menu items, these take precedence over the defaults
+	if ig := ctx.Value("group"); ig != nil {
+		if g, ok := ig.(GroupChat); ok && g.TelegramId != 0 {
+			for k, sats := range g.getMenu() {
+				p.Variables[k] = big.NewRat(sats*1000, 1)
+			}
+		}
+	}
+
 	// add currency values
 	for _, currencyCode := range CURRENCIES {
 		lower := strings.ToLower(currencyCode)
@@ -120,6 +172,9 @@ func parseAmountString(amt string) (msats int64, err error) {
 		if f < 1000 {
 			return 0, errors.New("'satoshis' param invalid")
 		}
+		if f > math.MaxInt64 {
+			return 0, errors.New("amount too large")
+		}
 		return int64(f), nil
 	} else {
 		return 0, fmt.Errorf("invalid math expression '%s': %w", amt, err)
@@ -127,34 +182,51 @@ func parseAmountString(amt string) (msats int64, err error) {
 }
 
 func getDollarPrice(msat int64) string {
-	rate, err := getMsatsPerFiatUnit("USD")
+	return getFiatPrice(msat, "USD")
+}
+
+func getFiatPrice(msat int64, currency string) string {
+	return fiatPricer(currency)(msat)
+}
+
+// fiatPricer looks up the rate only once, so all amounts formatted with the
+// returned function are consistent with each other.
+func fiatPricer(currency string) func(msat int64) string {
+	// unsupported currencies are shown in USD, but marked with a "~"
+	currency = strings.ToUpper(currency)
+	suffix := ""
+	if !stringIsIn(currency, CURRENCIES) {
+		currency = "USD"
+		suffix = "~"
+	}
+
+	rate, err := getMsatsPerFiatUnit(currency)
 	if err != nil {
-		return "~ USD"
+		return func(int64) string { return "~ " + currency }
+	}
+	return func(msat int64) string {
+		return fmt.Sprintf("%.2f %s%s", float64(msat)/float64(rate), currency, suffix)
 	}
-	return fmt.Sprintf("%.2f USD", float64(msat)/float64(rate))
 }
 
-func searchForInvoice(ctx context.Context) (bolt11, lnurltext, address string, ok bool) {
-	var message interface{}
-	if imessage := ctx.Value("message"); imessage != nil {
-		message = imessage
-	} else {
-		return "", "", "", false
+func searchForInvoice(ctx context.Context, message IncomingMessage) (bolt11, lnurltext, address string, ok bool) {
+	bolt11s, lnurltext, address, ok := searchForInvoices(ctx, message)
+	if len(bolt11s) > 0 {
+		bolt11 = bolt11s[0]
 	}
+	return
+}
 
-	var text string
-
-	switch m := message.(type) {
-	case *tgbotapi.Message:
-		text = m.Text
-		if text == "" {
-			text = m.Caption
-		}
-	case *discordgo.Message:
-		text = m.Content
+// searchForInvoices is like searchForInvoice, but returns all the bolt11
+// invoices found in the message, deduplicated and in the order they appeared.
+func searchForInvoices(ctx context.Context, message IncomingMessage) (bolt11s []string, lnurltext, address string, ok bool) {
+	if message == nil {
+		return nil, "", "", false
 	}
 
-	if bolt11, ok = getBolt11(text); ok {
+	text := message.Text()
+
+	if bolt11s, ok = getBolt11s(text); ok {
 		return
 	}
 
@@ -162,52 +234,119 @@ func searchForInvoice(ctx context.Context) (bolt11, lnurltext, address string, o
 		return
 	}
 
-	if name, domain, okW := lnurl.ParseInternetIdentifier(text); okW {
+	if lnurltext, ok = findLUD17(text); ok {
+		return
+	}
+
+	if name, domain, okW := parseLightningAddress(text); okW {
 		address = name + "@" + domain
 		ok = okW
 		return
 	}
 
 	// receiving a picture, try to decode the qr code
-	if m, tk := message.(*tgbotapi.Message); tk && m.Photo != nil && len(*m.Photo) > 0 {
-		log.Debug().Msg("got photo, looking for qr code.")
-
-		photos := *m.Photo
-		photo := photos[len(photos)-1]
+	imageURLs, err := message.Attachments()
+	if err != nil {
+		send(ctx, t.QRCODEFAIL, t.T{"Err": err.Error()}, message.Ref())
+		return
+	}
+	if len(imageURLs) == 0 {
+		return
+	}
+	log.Debug().Int("n", len(imageURLs)).
+		Msg("got images, looking for qr code.")
 
-		photourl, err := bot.GetFileDirectURL(photo.FileID)
-		if err != nil {
-			log.Warn().Err(err).Str("fileid", photo.FileID).
-				Msg("failed to get photo URL.")
-			send(ctx, t.QRCODEFAIL, t.T{"Err": err.Error()}, m.MessageID)
-			return
+	// try each image until one of them decodes
+	for _, imageURL := range imageURLs {
+		text, err = decodeQR(imageURL)
+		if err == nil {
+			break
 		}
+	}
+	if err != nil {
+		send(ctx, t.QRCODEFAIL, t.T{"Err": err.Error()}, message.Ref())
+		return
+	}
 
-		text, err := decodeQR(photourl)
-		if err != nil {
-			send(ctx, t.QRCODEFAIL, t.T{"Err": err.Error()}, m.MessageID)
-			return
-		}
+	log.Debug().Str("data", text).Msg("got qr code data")
+	send(ctx, text)
 
-		user := ctx.Value("initiator").(User)
-		log.Debug().Str("data", text).Stringer("user", &user).
-			Msg("got qr code data")
-		send(ctx, text)
+	if bolt11s, ok = getBolt11s(text); ok {
+		return
+	}
 
-		if bolt11, ok = getBolt11(text); ok {
-			return
-		}
+	if lnurltext, ok = lnurl.FindLNURLInText(text); ok {
+		return
+	}
 
-		if lnurltext, ok = lnurl.FindLNURLInText(text); ok {
-			return
-		}
+	if lnurltext, ok = findLUD17(text); ok {
+		return
+	}
+
+	if name, domain, okW := parseLightningAddress(text); okW {
+		address = name + "@" + domain
+		ok = okW
+		return
 	}
 
 	return
 }
 
+var lightningAddressRe = regexp.MustCompile(`^([a-z0-9_.+-]+)@([a-z0-9-]+(?:\.[a-z0-9-]+)+)$`)
+
+// parseLightningAddress recognizes addresses like name@domain.com, optionally
+// prefixed with "lightning:" or with "₿" as in BIP-353. the address must be the
+// entire text, so we don't act on emails mentioned in the middle of a message.
+func parseLightningAddress(text string) (name, domain string, ok bool) {
+	text = strings.ToLower(trimLightningScheme(text))
+	text = strings.TrimPrefix(text, "₿")
+
+	match := lightningAddressRe.FindStringSubmatch(text)
+	if match == nil {
+		return "", "", false
+	}
+
+	return match[1], match[2], true
+}
+
+// trimLightningScheme removes the "lightning:" prefix of the URIs wallets
+// produce, in any case, as in "LIGHTNING:LNBC..." from QR codes.
+func trimLightningScheme(text string) string {
+	text = strings.TrimSpace(text)
+	if len(text) >= 10 && strings.EqualFold(text[:10], "lightning:") {
+		return text[10:]
+	}
+	return text
+}
+
+// lud17Re matches the lnurlp://, lnurlw://, lnurlc:// and keyauth:// urls
+// from LUD-17, which are normal urls with the protocol replaced.
+var lud17Re = regexp.MustCompile(`(?i)\b(?:lnurl[pwc]|keyauth)://[^\s]+`)
+
+// findLUD17 returns the first LUD-17 url in the text encoded as a normal
+// bech32 lnurl.
+func findLUD17(text string) (lnurltext string, ok bool) {
+	match := lud17Re.FindString(text)
+	if match == "" {
+		return "", false
+	}
+
+	rest := match[strings.Index(match, "://")+3:]
+	scheme := "https://"
+	if host := strings.SplitN(rest, "/", 2)[0]; strings.HasSuffix(
+		strings.ToLower(host), ".onion") {
+		scheme = "http://"
+	}
+
+	lnurltext, err := lnurl.LNURLEncode(scheme + rest)
+	if err != nil {
+		return "", false
+	}
+	return lnurltext, true
+}
+
 func getBolt11(text string) (bolt11 string, ok bool) {
-	text = strings.ToLower(text)
+	text = strings.ToLower(trimLightningScheme(text))
 	results := bolt11regex.FindStringSubmatch(text)
 
 	if len(results) == 0 {
@@ -217,6 +356,22 @@ func getBolt11(text string) (bolt11 string, ok bool) {
 	return results[1], true
 }
 
+func getBolt11s(text string) (bolt11s []string, ok bool) {
+	text = strings.ToLower(trimLightningScheme(text))
+	results := bolt11regex.FindAllStringSubmatch(text, -1)
+
+	seen := make(map[string]bool, len(results))
+	for _, result := range results {
+		if seen[result[1]] {
+			continue
+		}
+		seen[result[1]] = true
+		bolt11s = append(bolt11s, result[1])
+	}
+
+	return bolt11s, len(bolt11s) > 0
+}
+
 func nodeLink(nodeId string) string {
 	if nodeId == "" {
 		return "{}"
@@ -228,8 +383,8 @@ func nodeLink(nodeId string) string {
 		return `<code>` + nodeId + `</code>`
 	}
 
-	return fmt.Sprintf(`<a href="http://ln.fiatjaf.com/%s">%s…%s</a>`,
-		nodeId, nodeId[:4], nodeId[len(nodeId)-4:])
+	return fmt.Sprintf(`<a href="http://ln.fiatjaf.com/%s">%s</a>`,
+		nodeId, shortNodeId(nodeId))
 }
 
 func nodeAliasLink(nodeId string) string {
@@ -237,19 +392,42 @@ func nodeAliasLink(nodeId string) string {
 		return "{}"
 	}
 
-	nodeIdShortened := nodeId[:10]
-	alias := getNodeAlias(nodeId)
+	nodeIdShortened := nodeId
+	if len(nodeId) > 10 {
+		nodeIdShortened = nodeId[:10]
+	}
+	// aliases are set by anyone, so they can't be trusted
+	alias := stripControlChars(getNodeAlias(nodeId))
 	if alias == "" {
-		alias = fmt.Sprintf("%s…%s", nodeId[:4], nodeId[len(nodeId)-4:])
+		alias = shortNodeId(nodeId)
 		nodeIdShortened = nodeId
-	} else if len(alias) > 16 {
-		alias = alias[:15] + "…"
+	} else if ralias := []rune(alias); len(ralias) > 16 {
+		alias = string(ralias[:15]) + "…"
 	}
+	alias = escapeHTML(alias)
 
 	return fmt.Sprintf(`<a href="http://ln.fiatjaf.com/%s">%s</a>`,
 		nodeIdShortened, alias)
 }
 
+func stripControlChars(s string) string {
+	return strings.Map(func(r rune) rune {
+		if unicode.IsControl(r) {
+			return -1
+		}
+		return r
+	}, s)
+}
+
+// shortNodeId returns the first and last 4 chars of a node id, or the full id
+// if it is too short for that.
+func shortNodeId(nodeId string) string {
+	if len(nodeId) <= 8 {
+		return nodeId
+	}
+	return nodeId[:4] + "…" + nodeId[len(nodeId)-4:]
+}
+
 func channelLink(scid string) string {
 	return fmt.Sprintf(`<a href="http://ln.fiatjaf.com/%s">%s</a>`, scid, scid)
 }
@@ -257,11 +435,18 @@ func channelLink(scid string) string {
 var scidRe = regexp.MustCompile(`\d+x\d+x\d+`)
 var nodeRe = regexp.MustCompile(`[0-9a-f]{66}`)
 
+// makeLinks escapes the text and then turns node ids and channel ids into links,
+// so its output must not be escaped again.
 func makeLinks(e string) string {
+	e = escapeHTML(e)
 	for _, match := range scidRe.FindAllString(e, -1) {
 		e = strings.ReplaceAll(e, match, channelLink(match))
 	}
-	for _, match := range nodeRe.FindAllString(e, -1) {
+
+	// fetch all aliases at once so a long route doesn't take a request per hop
+	nodeIds := nodeRe.FindAllString(e, -1)
+	prefetchNodeAliases(nodeIds)
+	for _, match := range nodeIds {
 		e = strings.ReplaceAll(e, match, nodeAliasLink(match))
 	}
 
@@ -277,49 +462,88 @@ func randomHex() (string, error) {
 	return hex.EncodeToString(data), nil
 }
 
+// randomIndex picks an index in [0, n) using crypto/rand, so games can't be
+// predicted.
+func randomIndex(n int) (int, error) {
+	i, err := rand.Int(rand.Reader, big.NewInt(int64(n)))
+	if err != nil {
+		return 0, fmt.Errorf("can't create random number: %w", err)
+	}
+	return int(i.Int64()), nil
+}
+
 func hashString(format string, a ...interface{}) string {
 	str := fmt.Sprintf(format, a...)
 	return fmt.Sprintf("%x", sha256.Sum256([]byte(str)))
 }
 
-func findSimilar(source string, targets []string) (result []string) {
-	var (
-		first  []string
-		second []string
-		third  []string
-		fourth []string
-	)
+// findSimilar returns up to max targets (all if max is 0) that look like the
+// source, best first. targets that contain the letters of the source in order
+// (fuzzy.Match) come first, then the others by their Levenshtein distance to
+// the source divided by the length of the longest of the two, up to 0.5.
+// equal scores are ordered alphabetically so the result is always the same.
+func findSimilar(source string, targets []string, max int) (result []string) {
+	type candidate struct {
+		target string
+		match  bool
+		score  float64
+	}
 
+	var candidates []candidate
 	for _, target := range targets {
 		if fuzzy.Match(source, target) {
-			first = append(first, target)
+			candidates = append(candidates, candidate{target, true, 0})
 			continue
 		}
 
-		score := fuzzy.LevenshteinDistance(source, target)
-		if score < 1 {
-			second = append(result, target)
-			continue
+		// normalize the distance by length so long names aren't penalized
+		length := len(source)
+		if len(target) > length {
+			length = len(target)
 		}
-		if score < 2 {
-			third = append(result, target)
+		if length == 0 {
 			continue
 		}
-		if score < 3 {
-			fourth = append(result, target)
-			continue
+		score := float64(fuzzy.LevenshteinDistance(source, target)) / float64(length)
+		if score <= 0.5 {
+			candidates = append(candidates, candidate{target, false, score})
 		}
 	}
 
-	res := first
-	res = append(first, second...)
-	res = append(res, third...)
-	res = append(res, fourth...)
+	sort.Slice(candidates, func(i, j int) bool {
+		a, b := candidates[i], candidates[j]
+		if a.match != b.match {
+			return a.match
+		}
+		if a.score != b.score {
+			return a.score < b.score
+		}
+		return a.target < b.target
+	})
 
-	return res
+	if max > 0 && len(candidates) > max {
+		candidates = candidates[:max]
+	}
+
+	result = make([]string, len(candidates))
+	for i, c := range candidates {
+		result[i] = c.target
+	}
+	return result
 }
 
+// roman writes a number in roman numerals. Zero is "N" (nulla), negatives get
+// a minus sign and numbers too big to be written sensibly stay in decimal.
 func roman(number int) string {
+	switch {
+	case number == 0:
+		return "N"
+	case number < 0:
+		return "-" + roman(-number)
+	case number >= 4000:
+		return strconv.Itoa(number)
+	}
+
 	conversions := []struct {
 		value int
 		digit string
@@ -366,6 +590,26 @@ func translateTemplate(ctx context.Context, key t.Key, data t.T) string {
 		}
 	}
 
+	// the defaults below are added to a copy, the caller may reuse its map
+	params := make(t.T, len(data)+2)
+	for k, v := range data {
+		params[k] = v
+	}
+	data = params
+
+	if _, ok := data["FiatCurrency"]; !ok {
+		data["FiatCurrency"] = displayCurrency(ctx)
+	}
+
+	// counters are written in roman numerals for users who asked for it
+	if _, ok := data["Roman"]; !ok {
+		if itarget := ctx.Value("initiator"); itarget != nil {
+			if target, ok := itarget.(User); ok {
+				data["Roman"] = target.Roman
+			}
+		}
+	}
+
 	msg, err := bundle.Render(locale, key, data)
 
 	if err != nil {
@@ -376,16 +620,40 @@ func translateTemplate(ctx context.Context, key t.Key, data t.T) string {
 	return msg
 }
 
+// displayCurrency is the currency fiat values are displayed in: the one chosen
+// by the user or USD if they never chose one.
+func displayCurrency(ctx context.Context) string {
+	if icur := ctx.Value("currency"); icur != nil {
+		if currency, ok := icur.(string); ok && currency != "" {
+			return currency
+		}
+	} else if itarget := ctx.Value("initiator"); itarget != nil {
+		if target, ok := itarget.(User); ok && target.Currency != "" {
+			return target.Currency
+		}
+	}
+	return "USD"
+}
+
+var htmlEscapeRe = regexp.MustCompile(`&(?:amp|lt|gt|quot|#\d+|#x[0-9a-fA-F]+);|[&<>"]`)
+
+// escapeHTML is idempotent: entities that are already escaped are left as
+// they are, so text can't end up escaped twice.
 func escapeHTML(m string) string {
-	return strings.Replace(
-		strings.Replace(
-			strings.Replace(
-				strings.Replace(
-					m,
-					"&", "&amp;", -1),
-				"<", "&lt;", -1),
-			">", "&gt;", -1),
-		"\"", "&quot;", -1)
+	return htmlEscapeRe.ReplaceAllStringFunc(m, func(match string) string {
+		switch match {
+		case "&":
+			return "&amp;"
+		case "<":
+			return "&lt;"
+		case ">":
+			return "&gt;"
+		case "\"":
+			return "&quot;"
+		default:
+			return match
+		}
+	})
 }
 
 func stringIsIn(needle string, haystack []string) bool {
@@ -397,34 +665,60 @@ func stringIsIn(needle string, haystack []string) bool {
 	return false
 }
 
-func getVariadicFieldOrReplyToContent(ctx context.Context, opts docopt.Opts, optsField string) string {
-	if text, ok := opts[optsField]; ok {
-		return strings.Join(text.([]string), " ")
+func getVariadicFieldOrReplyToContent(message IncomingMessage, opts docopt.Opts, optsField string) string {
+	if text, ok := opts[optsField].([]string); ok && len(text) > 0 {
+		return strings.Join(text, " ")
 	}
 
-	if imessage := ctx.Value("message"); imessage != nil {
-		if message, ok := imessage.(*tgbotapi.Message); ok {
-			if message.ReplyToMessage != nil {
-				return message.ReplyToMessage.Text
-			}
-		}
+	if message != nil {
+		return message.ReplyToText()
 	}
 
 	return ""
 }
 
-func imageBytesFromURL(url string) ([]byte, error) {
-	resp, err := http.Get(url)
+const maxImageSize = 10 * 1024 * 1024 // 10MB
+
+var imageClient = &http.Client{Timeout: time.Second * 20}
+
+func imageTimeoutError(url string, err error) error {
+	return ErrTimeout.withData(t.T{"Seconds": int(imageClient.Timeout / time.Second)}).
+		withDetail(fmt.Errorf("fetching image from %s: %w", url, err))
+}
+
+func imageBytesFromURL(ctx context.Context, url string) ([]byte, error) {
+	req, err := http.NewRequestWithContext(ctx, "GET", url, nil)
 	if err != nil {
 		return nil, err
 	}
+
+	resp, err := imageClient.Do(req)
+	if err != nil {
+		if errors.Is(err, context.DeadlineExceeded) || os.IsTimeout(err) {
+			return nil, imageTimeoutError(url, err)
+		}
+		return nil, err
+	}
 	defer resp.Body.Close()
 
 	if resp.StatusCode >= 300 {
 		return nil, errors.New("image returned status " + strconv.Itoa(resp.StatusCode))
 	}
 
-	img, _, err := image.Decode(resp.Body)
+	// read one byte more than the limit so we know if it was exceeded
+	data, err := ioutil.ReadAll(io.LimitReader(resp.Body, maxImageSize+1))
+	if err != nil {
+		if os.IsTimeout(err) {
+			return nil, imageTimeoutError(url, err)
+		}
+		return nil, fmt.Errorf("failed to read image from %s: %w", url, err)
+	}
+	if len(data) > maxImageSize {
+		return nil, fmt.Errorf("image from %s is larger than %dMB", url,
+			maxImageSize/1024/1024)
+	}
+
+	img, _, err := image.Decode(bytes.NewReader(data))
 	if err != nil {
 		return nil, fmt.Errorf("failed to decode image from %s: %w", url, err)
 	}
@@ -455,6 +749,22 @@ func getBalance(txn BalanceGetter, userId int) int64 {
 	return balance
 }
 
+// getPendingBalance is the sum of the incoming payments that aren't settled
+// yet. they are not part of getBalance, as they can't be spent.
+func getPendingBalance(txn BalanceGetter, userId int) int64 {
+	var pending int64
+	err := txn.Get(&pending, `
+SELECT coalesce(sum(amount), 0)::numeric(13)
+FROM lightning.account_txn
+WHERE account_id = $1 AND amount > 0 AND pending
+    `, userId)
+	if err != nil {
+		log.Warn().Err(err).Int("account", userId).Msg("failed to fetch pending balance")
+		return 0
+	}
+	return pending
+}
+
 func checkProxyBalance(txn BalanceGetter) error {
 	// check proxy balance (should be always zero)
 	var proxybalance int64
@@ -470,17 +780,31 @@ func checkProxyBalance(txn BalanceGetter) error {
 
 var nodeAliases = cmap.New()
 
+// explorerClient queries the node explorer at ln.fiatjaf.com for aliases and
+// graph data, it goes through a circuit breaker, set up on main
+var explorerClient = &http.Client{Timeout: time.Second * 10}
+
+type nodeAlias struct {
+	Alias     string
+	FetchedAt time.Time
+}
+
 func getNodeAlias(id string) string {
 begin:
-	if alias, ok := nodeAliases.Get(id); ok {
-		return alias.(string)
+	if ialias, ok := nodeAliases.Get(id); ok {
+		alias := ialias.(nodeAlias)
+		if s.NodeAliasTTL == 0 || time.Since(alias.FetchedAt) < s.NodeAliasTTL {
+			return alias.Alias
+		}
+		// expired, treat as a miss
+		nodeAliases.Remove(id)
 	}
 
 	if id == "" {
 		return "~"
 	}
 
-	resp, err := http.Get("https://ln.fiatjaf.com/nodes?select=alias&pubkey=eq." + id)
+	resp, err := explorerClient.Get("https://ln.fiatjaf.com/nodes?select=alias&pubkey=eq." + id)
 	if err != nil {
 		return "~"
 	}
@@ -496,10 +820,69 @@ begin:
 		alias = "~"
 	}
 
-	nodeAliases.Set(id, alias)
+	nodeAliases.Set(id, nodeAlias{alias, time.Now()})
 	goto begin
 }
 
+// maximum concurrent requests made by prefetchNodeAliases
+const nodeAliasWorkers = 5
+
+// prefetchNodeAliases fills the nodeAliases cache for the given ids, fetching
+// the missing ones concurrently.
+func prefetchNodeAliases(ids []string) {
+	sem := make(chan struct{}, nodeAliasWorkers)
+	seen := make(map[string]bool, len(ids))
+
+	var wg sync.WaitGroup
+	for _, id := range ids {
+		if seen[id] {
+			continue
+		}
+		seen[id] = true
+
+		wg.Add(1)
+		sem <- struct{}{}
+		go func(id string) {
+			defer wg.Done()
+			defer func() { <-sem }()
+			getNodeAlias(id)
+		}(id)
+	}
+	wg.Wait()
+}
+
+// nodeAliasWarmupRoutine keeps the aliases of the nodes we've paid to recently
+// in the cache, so they show up instantly.
+func nodeAliasWarmupRoutine() {
+	for {
+		var ids []string
+		err := pg.Select(&ids, `
+SELECT remote_node
+FROM lightning.transaction
+WHERE remote_node IS NOT NULL AND time > now() - interval '7 days'
+GROUP BY remote_node
+ORDER BY count(*) DESC
+LIMIT 500
+        `)
+		if err != nil && err != sql.ErrNoRows {
+			log.Error().Err(err).Msg("failed to fetch nodes on alias warmup routine")
+		}
+
+		// refetch the ones that would expire before the next run
+		for _, id := range ids {
+			if ialias, ok := nodeAliases.Get(id); ok && s.NodeAliasTTL != 0 &&
+				time.Since(ialias.(nodeAlias).FetchedAt) > s.NodeAliasTTL-s.NodeAliasWarmup {
+				nodeAliases.Remove(id)
+			}
+		}
+
+		log.Debug().Int("nodes", len(ids)).Msg("warming up node aliases")
+		prefetchNodeAliases(ids)
+
+		time.Sleep(s.NodeAliasWarmup)
+	}
+}
+
 func savePaymentAttemptLog(hash, bolt11 string) {
 	// TODO
 }
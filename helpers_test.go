@@ -0,0 +1,442 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/fiatjaf/go-lnurl"
+	tr "github.com/fiatjaf/lntxbot/t"
+)
+
+func TestDisplayCurrency(t *testing.T) {
+	background := context.Background()
+	withUser := func(currency string) context.Context {
+		return context.WithValue(background, "initiator", User{Currency: currency})
+	}
+
+	tests := []struct {
+		name     string
+		ctx      context.Context
+		currency string
+	}{
+		{"no user", background, "USD"},
+		{"never chose one", withUser(""), "USD"},
+		{"chose one", withUser("EUR"), "EUR"},
+		{"set for the message", context.WithValue(withUser("EUR"), "currency", "BRL"), "BRL"},
+		{"empty on the message", context.WithValue(background, "currency", ""), "USD"},
+	}
+
+	for _, test := range tests {
+		if currency := displayCurrency(test.ctx); currency != test.currency {
+			t.Errorf("%s: displayCurrency = %q, want %q", test.name, currency, test.currency)
+		}
+	}
+}
+
+func TestTranslateTemplateKeepsData(t *testing.T) {
+	ctx := context.WithValue(context.Background(), "initiator",
+		User{Currency: "EUR", Roman: true})
+	data := tr.T{"Currency": "JPY"}
+
+	message := translateTemplate(ctx, tr.CURRENCYMSG, data)
+	if message != "Your amounts will be displayed in <code>JPY</code>." {
+		t.Errorf("translateTemplate = %q", message)
+	}
+	if len(data) != 1 {
+		t.Errorf("translateTemplate added defaults to the caller's map: %v", data)
+	}
+}
+
+const testNodeId = "02c16cca44562b590dd279c942200bdccfd4f990c3a69fad620c10ef2f8228eaff"
+
+// withNodeAliases fills the alias cache, so the lookups don't go to the
+// network.
+func withNodeAliases(t *testing.T, aliases map[string]string) {
+	oldTTL := s.NodeAliasTTL
+	s.NodeAliasTTL = 0
+	for id, alias := range aliases {
+		nodeAliases.Set(id, nodeAlias{alias, time.Now()})
+	}
+	t.Cleanup(func() {
+		s.NodeAliasTTL = oldTTL
+		for id := range aliases {
+			nodeAliases.Remove(id)
+		}
+	})
+}
+
+func TestGetNodeAlias(t *testing.T) {
+	withNodeAliases(t, map[string]string{
+		testNodeId: "ACINQ",
+		"02c1":     "~",
+	})
+
+	tests := []struct {
+		id    string
+		alias string
+	}{
+		{"", "~"},
+		{"02c1", "~"},
+		{testNodeId, "ACINQ"},
+	}
+
+	for _, test := range tests {
+		if alias := getNodeAlias(test.id); alias != test.alias {
+			t.Errorf("getNodeAlias(%q) = %q, want %q", test.id, alias, test.alias)
+		}
+	}
+}
+
+func TestNodeLink(t *testing.T) {
+	withNodeAliases(t, map[string]string{
+		testNodeId: "ACINQ",
+		"02c1":     "~",
+		"02c16cca": "~",
+	})
+
+	tests := []struct {
+		id   string
+		link string
+	}{
+		{"", "{}"},
+		{"02c1", "<code>02c1</code>"},
+		{"02c16cca", "<code>02c16cca</code>"},
+		{testNodeId, `<a href="http://ln.fiatjaf.com/` + testNodeId + `">02c1…eaff</a>`},
+	}
+
+	for _, test := range tests {
+		if link := nodeLink(test.id); link != test.link {
+			t.Errorf("nodeLink(%q) = %q, want %q", test.id, link, test.link)
+		}
+	}
+}
+
+func TestNodeAliasLink(t *testing.T) {
+	withNodeAliases(t, map[string]string{
+		testNodeId: "<b>a very long alias</b>",
+		"02c1":     "",
+		"02c16cca": "short",
+	})
+
+	tests := []struct {
+		id   string
+		link string
+	}{
+		{"", "{}"},
+		{"02c1", `<a href="http://ln.fiatjaf.com/02c1">02c1</a>`},
+		{"02c16cca", `<a href="http://ln.fiatjaf.com/02c16cca">short</a>`},
+		{testNodeId, `<a href="http://ln.fiatjaf.com/02c16cca44">&lt;b&gt;a very long …</a>`},
+	}
+
+	for _, test := range tests {
+		if link := nodeAliasLink(test.id); link != test.link {
+			t.Errorf("nodeAliasLink(%q) = %q, want %q", test.id, link, test.link)
+		}
+	}
+}
+
+var testCommands = []string{
+	"balance", "bluewallet", "pay", "paynow", "send", "receive", "invoice",
+	"help", "giveaway", "giveflip", "coinflip", "sats4ads", "transactions",
+	"lnurl", "rename", "tip", "toggle",
+}
+
+func TestFindSimilarTypos(t *testing.T) {
+	tests := []struct {
+		source  string
+		similar []string
+	}{
+		{"blance", []string{"balance"}},
+		{"recieve", []string{"receive"}},
+		{"snd", []string{"send"}},
+		{"hlep", []string{"help"}},
+		{"transactoins", []string{"transactions"}},
+		{"giveflp", []string{"giveflip", "giveaway"}},
+		{"cionflip", []string{"coinflip", "giveflip"}},
+		{"py", []string{"pay", "paynow"}},
+		{"zzzz", []string{}},
+	}
+
+	for _, test := range tests {
+		similar := findSimilar(test.source, testCommands, 0)
+		if strings.Join(similar, " ") != strings.Join(test.similar, " ") {
+			t.Errorf("findSimilar(%q) = %q, want %q", test.source, similar, test.similar)
+		}
+	}
+}
+
+func TestFindSimilarTiesAndMax(t *testing.T) {
+	shuffled := make([]string, len(testCommands))
+	for i, command := range testCommands {
+		shuffled[len(testCommands)-1-i] = command
+	}
+
+	tests := []struct {
+		source  string
+		targets []string
+		max     int
+		similar []string
+	}{
+		// all fuzzy matches tie, so they come alphabetically
+		{"ip", testCommands, 0, []string{"coinflip", "giveflip", "tip"}},
+		{"ip", shuffled, 0, []string{"coinflip", "giveflip", "tip"}},
+		{"ip", testCommands, 2, []string{"coinflip", "giveflip"}},
+		{"ip", shuffled, 1, []string{"coinflip"}},
+		{"ip", testCommands, 10, []string{"coinflip", "giveflip", "tip"}},
+		{"xyzxyz", testCommands, 3, nil},
+	}
+
+	for _, test := range tests {
+		similar := findSimilar(test.source, test.targets, test.max)
+		if strings.Join(similar, " ") != strings.Join(test.similar, " ") {
+			t.Errorf("findSimilar(%q, max %d) = %q, want %q",
+				test.source, test.max, similar, test.similar)
+		}
+	}
+}
+
+func TestParseAmountString(t *testing.T) {
+	tests := []struct {
+		amount string
+		msats  int64
+		fails  bool
+	}{
+		{"100", 100000, false},
+		{"2.5", 2500, false},
+		{"100sat", 100000, false},
+		{"100 sats", 100000, false},
+		{"1500msat", 1000, false},
+		{"100k", 100000000, false},
+		{"1.5K", 1500000, false},
+		{"1.5m", 1500000000, false},
+		{"0.0001btc", 10000000, false},
+		{"0.00000001 BTC", 1000, false},
+		{"2*banana", 2 * menuItems["banana"].Num().Int64(), false},
+		{"-5", 0, true},
+		{"0.5", 0, true},
+		{"999msat", 0, true},
+		{"0.000000001btc", 0, true},
+		{"99999999999m", 0, true},
+		{"10kk", 0, true},
+		{"k", 0, true},
+		{"lots", 0, true},
+	}
+
+	for _, test := range tests {
+		msats, err := parseAmountString(context.Background(), test.amount)
+		if (err != nil) != test.fails {
+			t.Errorf("parseAmountString(%q) error = %v, want failure %v", test.amount, err, test.fails)
+			continue
+		}
+		if !test.fails && msats != test.msats {
+			t.Errorf("parseAmountString(%q) = %d, want %d", test.amount, msats, test.msats)
+		}
+	}
+}
+
+func TestEscapeHTML(t *testing.T) {
+	tests := []struct {
+		text    string
+		escaped string
+	}{
+		{"plain", "plain"},
+		{"Tom & Jerry", "Tom &amp; Jerry"},
+		{"<script>", "&lt;script&gt;"},
+		{`say "hi"`, "say &quot;hi&quot;"},
+		{"Tom &amp; Jerry", "Tom &amp; Jerry"},
+		{"&lt;b&gt; & <b>", "&lt;b&gt; &amp; &lt;b&gt;"},
+		{"&#39; &#x27; &nbsp;", "&#39; &#x27; &amp;nbsp;"},
+	}
+
+	for _, test := range tests {
+		escaped := escapeHTML(test.text)
+		if escaped != test.escaped {
+			t.Errorf("escapeHTML(%q) = %q, want %q", test.text, escaped, test.escaped)
+		}
+		if again := escapeHTML(escaped); again != escaped {
+			t.Errorf("escapeHTML isn't idempotent on %q: %q", escaped, again)
+		}
+	}
+}
+
+func TestMakeLinks(t *testing.T) {
+	withNodeAliases(t, map[string]string{
+		testNodeId: "R&D <node>",
+	})
+
+	node := `<a href="http://ln.fiatjaf.com/02c16cca44">R&amp;D &lt;node&gt;</a>`
+	channel := `<a href="http://ln.fiatjaf.com/650000x1000x1">650000x1000x1</a>`
+
+	tests := []struct {
+		text  string
+		links string
+	}{
+		{"no route & no <luck>", "no route &amp; no &lt;luck&gt;"},
+		{"via " + testNodeId, "via " + node},
+		{"<b>" + testNodeId + "</b> & 650000x1000x1",
+			"&lt;b&gt;" + node + "&lt;/b&gt; &amp; " + channel},
+	}
+
+	for _, test := range tests {
+		links := makeLinks(test.text)
+		if links != test.links {
+			t.Errorf("makeLinks(%q) = %q, want %q", test.text, links, test.links)
+		}
+	}
+}
+
+// slowNodeExplorer answers alias queries after a delay, like the real one
+// over the network.
+type slowNodeExplorer struct{ delay time.Duration }
+
+func (e slowNodeExplorer) RoundTrip(r *http.Request) (*http.Response, error) {
+	time.Sleep(e.delay)
+	return &http.Response{
+		StatusCode: 200,
+		Body:       ioutil.NopCloser(strings.NewReader(`[{"alias":"hop"}]`)),
+		Request:    r,
+	}, nil
+}
+
+// benchmarkColdRoute makes links for a 10-hop route with none of the aliases
+// cached, resolving them with the given function first.
+func benchmarkColdRoute(b *testing.B, resolve func(ids []string)) {
+	oldTransport, oldTTL := http.DefaultTransport, s.NodeAliasTTL
+	defer func() { http.DefaultTransport, s.NodeAliasTTL = oldTransport, oldTTL }()
+	http.DefaultTransport = slowNodeExplorer{5 * time.Millisecond}
+	s.NodeAliasTTL = 0
+
+	ids := make([]string, 10)
+	for i := range ids {
+		ids[i] = fmt.Sprintf("02%064x", i)
+	}
+	route := strings.Join(ids, " -> ")
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		b.StopTimer()
+		for _, id := range ids {
+			nodeAliases.Remove(id)
+		}
+		b.StartTimer()
+
+		resolve(ids)
+		makeLinks(route)
+	}
+}
+
+func BenchmarkMakeLinksColdRoute(b *testing.B) {
+	benchmarkColdRoute(b, prefetchNodeAliases)
+}
+
+// what makeLinks did before prefetching: one lookup after the other.
+func BenchmarkMakeLinksColdRouteSequential(b *testing.B) {
+	benchmarkColdRoute(b, func(ids []string) {
+		for _, id := range ids {
+			getNodeAlias(id)
+		}
+	})
+}
+
+func TestTrimLightningScheme(t *testing.T) {
+	tests := []struct {
+		text    string
+		trimmed string
+	}{
+		{"lnbc1invoice", "lnbc1invoice"},
+		{"lightning:lnbc1invoice", "lnbc1invoice"},
+		{"LIGHTNING:LNBC1INVOICE", "LNBC1INVOICE"},
+		{"Lightning:lnurl1dp68gurn", "lnurl1dp68gurn"},
+		{"  lightning:lnbc1invoice\n", "lnbc1invoice"},
+		{"lightning:", ""},
+		{"lightning", "lightning"},
+		{"pay lightning:lnbc1invoice", "pay lightning:lnbc1invoice"},
+	}
+
+	for _, test := range tests {
+		if trimmed := trimLightningScheme(test.text); trimmed != test.trimmed {
+			t.Errorf("trimLightningScheme(%q) = %q, want %q", test.text, trimmed, test.trimmed)
+		}
+	}
+}
+
+func TestFindLUD17(t *testing.T) {
+	tests := []struct {
+		text string
+		url  string
+	}{
+		{"lnurlp://example.com/pay/1", "https://example.com/pay/1"},
+		{"lnurlw://example.com/withdraw?k1=ab", "https://example.com/withdraw?k1=ab"},
+		{"LNURLP://EXAMPLE.COM/pay", "https://EXAMPLE.COM/pay"},
+		{"lightning:lnurlp://example.com/pay", "https://example.com/pay"},
+		{"LIGHTNING:lnurlw://example.com/w", "https://example.com/w"},
+		{"scan this: lnurlp://example.com/p and pay", "https://example.com/p"},
+		{"lnurlp://abcdef.onion/pay", "http://abcdef.onion/pay"},
+		{"keyauth://example.com/auth?tag=login", "https://example.com/auth?tag=login"},
+		{"https://example.com/pay", ""},
+		{"lnurlx://example.com/pay", ""},
+		{"lnurlp:example.com", ""},
+	}
+
+	for _, test := range tests {
+		encoded, ok := findLUD17(test.text)
+		if !ok {
+			if test.url != "" {
+				t.Errorf("findLUD17(%q) found nothing, want %q", test.text, test.url)
+			}
+			continue
+		}
+		if test.url == "" {
+			t.Errorf("findLUD17(%q) = %q, want nothing", test.text, encoded)
+			continue
+		}
+
+		url, err := lnurl.LNURLDecode(encoded)
+		if err != nil {
+			t.Errorf("findLUD17(%q) = %q, which doesn't decode: %s", test.text, encoded, err)
+			continue
+		}
+		if url != test.url {
+			t.Errorf("findLUD17(%q) encodes %q, want %q", test.text, url, test.url)
+		}
+	}
+}
+
+func TestGetBolt11(t *testing.T) {
+	upper := strings.ToUpper(testBolt11)
+	tests := []string{
+		testBolt11,
+		upper,
+		"lightning:" + upper,
+		"pay this: " + testBolt11 + ".",
+		"(" + testBolt11 + ")",
+		`"` + testBolt11 + `"`,
+		"'" + testBolt11 + "',",
+		"<" + testBolt11 + ">",
+		"first line\n" + testBolt11 + "\nthanks!",
+	}
+
+	for _, text := range tests {
+		bolt11, ok := getBolt11(text)
+		if !ok || bolt11 != testBolt11 {
+			t.Errorf("getBolt11(%q) = %q, %v, want the invoice", text, bolt11, ok)
+		}
+	}
+
+	if bolt11, ok := getBolt11("no invoice here, just lnbc."); ok {
+		t.Errorf("getBolt11 without an invoice = %q, want none", bolt11)
+	}
+}
+
+func TestGetBolt11s(t *testing.T) {
+	text := "(" + testBolt11 + "), " + strings.ToUpper(testBolt11) + "."
+	bolt11s, ok := getBolt11s(text)
+	if !ok || len(bolt11s) != 1 || bolt11s[0] != testBolt11 {
+		t.Errorf("getBolt11s(%q) = %v, %v, want the invoice once", text, bolt11s, ok)
+	}
+}
@@ -0,0 +1,110 @@
+package main
+
+import (
+	"context"
+
+	"github.com/bwmarrin/discordgo"
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api"
+)
+
+// IncomingMessage is a message received from any platform, so helpers that
+// only read messages don't have to know where they came from.
+type IncomingMessage interface {
+	// Text is the message text, or the caption of a media message.
+	Text() string
+
+	// ReplyToText is the text of the message this one is replying to, if any.
+	ReplyToText() string
+
+	// Attachments returns the URLs of the images attached to the message.
+	Attachments() ([]string, error)
+
+	// Ref is what should be given to send() to reference this message.
+	Ref() interface{}
+}
+
+// incomingMessage returns the message that triggered the current action, or
+// nil if there isn't one.
+func incomingMessage(ctx context.Context) IncomingMessage {
+	switch m := ctx.Value("message").(type) {
+	case *tgbotapi.Message:
+		if m != nil {
+			return telegramMessage{m}
+		}
+	case *discordgo.Message:
+		if m != nil {
+			replyTo, _ := ctx.Value("discordReplyTo").(*discordgo.Message)
+			return discordMessage{m, replyTo}
+		}
+	}
+	return nil
+}
+
+type telegramMessage struct{ m *tgbotapi.Message }
+
+func (tm telegramMessage) Text() string {
+	if tm.m.Text == "" {
+		return tm.m.Caption
+	}
+	return tm.m.Text
+}
+
+func (tm telegramMessage) ReplyToText() string {
+	if tm.m.ReplyToMessage == nil {
+		return ""
+	}
+	return tm.m.ReplyToMessage.Text
+}
+
+func (tm telegramMessage) Attachments() ([]string, error) {
+	if tm.m.Photo == nil || len(*tm.m.Photo) == 0 {
+		return nil, nil
+	}
+
+	// the last one is the biggest size
+	photos := *tm.m.Photo
+	photo := photos[len(photos)-1]
+
+	photourl, err := bot.GetFileDirectURL(photo.FileID)
+	if err != nil {
+		log.Warn().Err(err).Str("fileid", photo.FileID).
+			Msg("failed to get photo URL.")
+		return nil, err
+	}
+	return []string{photourl}, nil
+}
+
+func (tm telegramMessage) Ref() interface{} { return tm.m.MessageID }
+
+type discordMessage struct {
+	m       *discordgo.Message
+	replyTo *discordgo.Message
+}
+
+func (dm discordMessage) Text() string { return dm.m.Content }
+
+func (dm discordMessage) ReplyToText() string {
+	if dm.replyTo == nil {
+		return ""
+	}
+
+	// our own messages are embeds with no content
+	if dm.replyTo.Content == "" && len(dm.replyTo.Embeds) > 0 {
+		return dm.replyTo.Embeds[0].Description
+	}
+	return dm.replyTo.Content
+}
+
+func (dm discordMessage) Attachments() ([]string, error) {
+	var imageURLs []string
+	for _, attachment := range dm.m.Attachments {
+		if attachment.Width == 0 || attachment.Height == 0 {
+			// not an image
+			continue
+		}
+		imageURLs = append(imageURLs, attachment.URL)
+	}
+	return imageURLs, nil
+}
+
+func (dm discordMessage) Ref() interface{} { return discordIDFromMessage(dm.m) }
@@ -154,7 +154,7 @@ ON CONFLICT (payment_hash) DO UPDATE SET amount = t.amount + $4
 		}
 
 		// check proxy balance (should be always zero)
-		if errW := checkProxyBalance(txn); err != nil {
+		if errW := checkProxyBalance(txn); errW != nil {
 			err = errW
 			log.Error().Err(err).Msg("proxy balance check on reveal")
 			return
@@ -332,7 +332,7 @@ func settleCoinflip(
 	// receiver must also have the necessary sats in his balance at the time
 	receiverBalance := getBalance(txn, toId)
 	if receiverBalance < msats+COINFLIP_TAX {
-		err = errors.New("Receiver has insufficient balance.")
+		err = ErrInsufficientBalance
 		return
 	}
 
@@ -373,7 +373,7 @@ ON CONFLICT (payment_hash) DO UPDATE SET amount = t.amount + $4
 		}
 
 		// check proxy balance (should be always zero)
-		if errW := checkProxyBalance(txn); err != nil {
+		if errW := checkProxyBalance(txn); errW != nil {
 			err = errW
 			log.Error().Err(err).Msg("proxy balance check on coinflip")
 			return
@@ -401,6 +401,54 @@ ON CONFLICT (payment_hash) DO UPDATE SET amount = t.amount + $4
 	return
 }
 
+// drop
+func dropKeyboard(
+	ctx context.Context,
+	dropid string,
+	dropperId int,
+	nparticipants int,
+	share int,
+) *tgbotapi.InlineKeyboardMarkup {
+	return &tgbotapi.InlineKeyboardMarkup{
+		[][]tgbotapi.InlineKeyboardButton{
+			{
+				tgbotapi.NewInlineKeyboardButtonData(
+					translate(ctx, t.CANCEL),
+					fmt.Sprintf("cancel=%d", dropperId),
+				),
+				tgbotapi.NewInlineKeyboardButtonData(
+					translate(ctx, t.DROPCLAIM),
+					fmt.Sprintf("drop=%d-%d-%d-%s", dropperId, nparticipants, share, dropid),
+				),
+			},
+		},
+	}
+}
+
+// dropAdData is what goes in the drop message, rendered again after each claim.
+func dropAdData(
+	ctx context.Context,
+	dropper User,
+	claimers []User,
+	nparticipants int,
+	share int,
+) t.T {
+	names := make([]string, len(claimers))
+	for i, claimer := range claimers {
+		names[i] = claimer.AtName(ctx)
+	}
+
+	return t.T{
+		"User":         dropper.AtName(ctx),
+		"Sats":         share * nparticipants,
+		"Share":        share,
+		"Participants": nparticipants,
+		"Claimed":      len(claimers),
+		"Progress":     progressBar(len(claimers), nparticipants),
+		"Claimers":     strings.Join(names, " "),
+	}
+}
+
 // fundraise
 func fundraiseKeyboard(
 	ctx context.Context,
@@ -419,6 +467,7 @@ func fundraiseKeyboard(
 	}
 
 	rds.Expire("fundraise:"+fundraiseid, s.GiveAwayTimeout)
+	rds.Expire("fundraisetitle:"+fundraiseid, s.GiveAwayTimeout)
 
 	return &tgbotapi.InlineKeyboardMarkup{
 		[][]tgbotapi.InlineKeyboardButton{
@@ -432,6 +481,42 @@ func fundraiseKeyboard(
 	}
 }
 
+// fundraiseAdData is what goes in the fundraise message, it is rendered again
+// with the new progress bar every time someone contributes.
+func fundraiseAdData(
+	ctx context.Context,
+	fundraiseid string,
+	receiver User,
+	contributors []User,
+	nparticipants int,
+	sats int,
+) t.T {
+	names := make([]string, len(contributors))
+	for i, contributor := range contributors {
+		names[i] = contributor.AtName(ctx)
+	}
+
+	return t.T{
+		"Title":        escapeHTML(rds.Get("fundraisetitle:" + fundraiseid).Val()),
+		"ToUser":       receiver.AtName(ctx),
+		"Participants": nparticipants,
+		"Contributed":  len(contributors),
+		"Progress":     progressBar(len(contributors), nparticipants),
+		"Sats":         sats,
+		"Fund":         sats * nparticipants,
+		"Registered":   strings.Join(names, " "),
+	}
+}
+
+func progressBar(done, total int) string {
+	const width = 10
+	filled := width
+	if total > 0 && done < total {
+		filled = done * width / total
+	}
+	return strings.Repeat("▓", filled) + strings.Repeat("░", width-filled)
+}
+
 func settleFundraise(
 	ctx context.Context,
 	sats int,
@@ -485,7 +570,7 @@ ON CONFLICT (payment_hash) DO UPDATE SET amount = t.amount + $4
 		}
 
 		// check proxy balance (should be always zero)
-		if errW := checkProxyBalance(txn); err != nil {
+		if errW := checkProxyBalance(txn); errW != nil {
 			err = errW
 			log.Error().Err(err).Msg("proxy balance check on fundraise")
 			return
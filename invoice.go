@@ -94,6 +94,52 @@ func resolveWaitingInvoice(hash string, inv InvoiceData) {
 	}
 }
 
+// invoiceLimits returns the amounts, in sat, invoices made by this user must be
+// within, 0 meaning no limit. operators can change them for each user with
+// /invoicelimits, otherwise the global settings are used.
+func (u User) invoiceLimits() (min, max int64) {
+	var limits struct {
+		Min int64 `db:"min"`
+		Max int64 `db:"max"`
+	}
+	err := pg.Get(&limits, `
+SELECT coalesce(invoice_min_sat, $2) AS min, coalesce(invoice_max_sat, $3) AS max
+FROM account WHERE id = $1
+    `, u.Id, s.InvoiceMinSats, s.InvoiceMaxSats)
+	if err != nil {
+		log.Warn().Err(err).Stringer("user", &u).Msg("failed to load invoice limits")
+		return s.InvoiceMinSats, s.InvoiceMaxSats
+	}
+	return limits.Min, limits.Max
+}
+
+// setInvoiceLimits changes the limits of a user, nil means the global setting.
+func (u User) setInvoiceLimits(min, max *int64) error {
+	_, err := pg.Exec(`
+UPDATE account SET invoice_min_sat = $2, invoice_max_sat = $3 WHERE id = $1
+    `, u.Id, min, max)
+	return err
+}
+
+func checkInvoiceLimits(u User, msatoshi int64) error {
+	min, max := u.invoiceLimits()
+
+	if msatoshi == 0 {
+		// the payer chooses the amount of an amountless invoice and by the time
+		// we see it the payment is already settled, so with a maximum set they
+		// can't be made at all
+		if max > 0 {
+			return ErrInvoiceAmount.withData(t.T{"Min": min, "Max": max, "Amountless": true})
+		}
+		return nil
+	}
+
+	if (min > 0 && msatoshi < min*1000) || (max > 0 && msatoshi > max*1000) {
+		return ErrInvoiceAmount.withData(t.T{"Min": min, "Max": max})
+	}
+	return nil
+}
+
 // creating too many small invoices is forbidden
 // because we're not a faucet milking machine
 
@@ -139,7 +185,7 @@ func handleInvoice(ctx context.Context, opts docopt.Opts, desc string) {
 		send(ctx, qrURL(lnurl), lnurl)
 		go u.track("print lnurl", nil)
 	} else {
-		msats, err := parseSatoshis(opts)
+		msats, err := parseSatoshis(ctx, opts)
 		if err != nil {
 			if opts["any"].(bool) {
 				msats = 0
@@ -151,23 +197,28 @@ func handleInvoice(ctx context.Context, opts docopt.Opts, desc string) {
 
 		go u.track("make invoice", map[string]interface{}{"sats": msats / 1000})
 
-		if desc == "" {
-			desc = "to @lntxbot"
+		// without a description makeInvoice uses the user's default one
+		if desc != "" {
+			desc = u.Username + ":  " + desc
 		}
 
 		bolt11, _, err := u.makeInvoice(ctx, &MakeInvoiceArgs{
 			Msatoshi:    msats,
-			Description: u.Username + ":  " + desc,
+			Description: desc,
 			Extra:       InvoiceExtra{Message: ctx.Value("message").(*tgbotapi.Message)},
 		})
 		if err != nil {
 			log.Warn().Err(err).Msg("failed to generate invoice")
-			send(ctx, u, t.FAILEDINVOICE, t.T{"Err": err.Error()})
+			send(ctx, u, t.FAILEDINVOICE, t.T{"Err": messageFromError(ctx, err)})
 			return
 		}
 
-		// send invoice with qr code
-		send(ctx, qrURL(bolt11), "<pre>"+bolt11+"</pre>")
+		// send invoice with qr code, unless the user doesn't want it
+		if u.SkipQR {
+			send(ctx, "<pre>"+bolt11+"</pre>")
+		} else {
+			send(ctx, qrURL(bolt11), "<pre>"+bolt11+"</pre>")
+		}
 	}
 }
 
@@ -193,13 +244,17 @@ func paymentReceived(
 		return
 	}
 
+	currency, rate := currentFiatRate(user.Currency)
+
 	_, err = pg.Exec(`
 INSERT INTO lightning.transaction
-  (to_id, amount, description, payment_hash, preimage, tag)
-VALUES ($1, $2, $3, $4, $5, $6)
+  (to_id, amount, description, payment_hash, preimage, tag,
+   fiat_currency, msat_per_fiat)
+VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
 ON CONFLICT (payment_hash) DO UPDATE SET to_id = $1
     `, user.Id, amount, data.Description, hash,
-		data.Preimage, sql.NullString{String: data.Tag, Valid: data.Tag != ""})
+		data.Preimage, sql.NullString{String: data.Tag, Valid: data.Tag != ""},
+		currency, rate)
 	if err != nil {
 		log.Error().Err(err).
 			Stringer("user", &user).Str("hash", hash).
@@ -212,6 +267,7 @@ ON CONFLICT (payment_hash) DO UPDATE SET to_id = $1
 	}
 
 	go resolveWaitingInvoice(hash, data)
+	metricPaymentReceived(amount)
 
 	user.track("got payment", map[string]interface{}{
 		"sats": amount / 1000,
@@ -238,6 +294,19 @@ ON CONFLICT (payment_hash) DO UPDATE SET to_id = $1
 		tmplParams["SenderName"] = senderNameFromPayerData(*payer)
 	}
 
+	// notify the webhook given for this invoice and the one set by the user
+	payload := map[string]interface{}{
+		"payment_hash": hash,
+		"msatoshi":     amount,
+		"description":  data.Description,
+	}
+	if webhook := data.Extra.Webhook; webhook != "" {
+		go postWebhook(user, webhook, payload)
+	}
+	if webhook := user.getWebhook(); webhook != "" {
+		go postWebhook(user, webhook, payload)
+	}
+
 	send(ctx, user, t.PAYMENTRECEIVED, tmplParams)
 	if dmi, ok := data.MessageId.(DiscordMessageID); ok {
 		discord.MessageReactionAdd(dmi.Channel(), dmi.Message(), "⚠️")
@@ -248,7 +317,16 @@ ON CONFLICT (payment_hash) DO UPDATE SET to_id = $1
 
 func saveInvoiceData(hash string, data InvoiceData) error {
 	b, _ := json.Marshal(data)
-	return rds.Set("invdata:"+hash, string(b), *data.Expiry).Err()
+
+	// cliche doesn't take an expiry, so the bolt11 may stay payable for longer
+	// than what was asked. the data must outlive it or a late payment would be
+	// received by the node and credited to nobody.
+	ttl := *data.Expiry
+	if ttl < s.InvoiceTimeout {
+		ttl = s.InvoiceTimeout
+	}
+
+	return rds.Set("invdata:"+hash, string(b), ttl).Err()
 }
 
 func loadInvoiceData(hash string) (data InvoiceData, err error) {
@@ -304,3 +382,54 @@ func checkAllIncomingPayments(ctx context.Context) {
 	// 	}
 	// }
 }
+
+// handleInvoiceLimits is the operator command
+// "/invoicelimits <account> [<min> <max>]", where "-" restores the global
+// setting for that limit.
+func handleInvoiceLimits(ctx context.Context, args []string) {
+	admin := ctx.Value("initiator").(User)
+
+	if len(args) != 1 && len(args) != 3 {
+		send(ctx, admin, t.ERROR, t.T{
+			"Err": "usage: /invoicelimits <account> [<min> <max>]"})
+		return
+	}
+
+	id, err := strconv.Atoi(args[0])
+	if err != nil {
+		send(ctx, admin, t.ERROR, t.T{"Err": "invalid account id."})
+		return
+	}
+	target, err := loadUser(id)
+	if err != nil {
+		send(ctx, admin, t.ERROR, t.T{"Err": "account not found."})
+		return
+	}
+
+	if len(args) == 3 {
+		limits := make([]*int64, 2)
+		for i, arg := range args[1:] {
+			if arg == "-" {
+				continue
+			}
+			sat, err := strconv.ParseInt(arg, 10, 64)
+			if err != nil || sat < 0 {
+				send(ctx, admin, t.ERROR, t.T{"Err": "invalid limit " + arg + "."})
+				return
+			}
+			limits[i] = &sat
+		}
+
+		if err := target.setInvoiceLimits(limits[0], limits[1]); err != nil {
+			log.Warn().Err(err).Stringer("user", &target).
+				Msg("failed to set invoice limits")
+			send(ctx, admin, t.ERROR, t.T{"Err": ErrDatabase.Error()})
+			return
+		}
+	}
+
+	min, max := target.invoiceLimits()
+	send(ctx, admin, fmt.Sprintf(
+		"Invoices of %s: minimum %d sat, maximum %d sat (0 means no limit).",
+		target.String(), min, max))
+}
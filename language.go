@@ -0,0 +1,95 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"sort"
+
+	"github.com/docopt/docopt-go"
+	"github.com/fiatjaf/lntxbot/t"
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api"
+)
+
+// names shown on the language buttons, languages missing here show the code
+var languageNames = map[string]string{
+	"en": "English",
+	"de": "Deutsch",
+	"es": "Español",
+	"ru": "Русский",
+}
+
+func availableLanguages() []string {
+	langs := make([]string, 0, len(bundle.Translations))
+	for lang := range bundle.Translations {
+		langs = append(langs, lang)
+	}
+	sort.Strings(langs)
+	return langs
+}
+
+func handleLanguage(ctx context.Context, opts docopt.Opts) {
+	u := ctx.Value("initiator").(User)
+
+	if lang, err := opts.String("<lang>"); err == nil {
+		if err := u.setLocale(lang); err != nil {
+			send(ctx, u, t.ERROR, t.T{"Err": err.Error()})
+			return
+		}
+		go u.track("language", map[string]interface{}{"lang": lang})
+		ctx = context.WithValue(ctx, "locale", lang)
+	}
+
+	send(ctx, u, t.LANGUAGEMSG, t.T{"Language": u.Locale}, languageKeyboard(u.Locale))
+}
+
+func handleLanguageCallback(ctx context.Context) {
+	u := ctx.Value("initiator").(User)
+	cb := ctx.Value("callbackQuery").(*tgbotapi.CallbackQuery)
+
+	lang := cb.Data[5:]
+	if err := u.setLocale(lang); err != nil {
+		send(ctx, u, t.ERROR, t.T{"Err": err.Error()})
+		return
+	}
+	go u.track("language", map[string]interface{}{"lang": lang})
+
+	ctx = context.WithValue(ctx, "locale", lang)
+	send(ctx, EDIT, t.LANGUAGEMSG, t.T{"Language": lang}, languageKeyboard(lang))
+}
+
+func languageKeyboard(current string) *tgbotapi.InlineKeyboardMarkup {
+	var row []tgbotapi.InlineKeyboardButton
+	for _, lang := range availableLanguages() {
+		name, ok := languageNames[lang]
+		if !ok {
+			name = lang
+		}
+		if lang == current {
+			name = "✅ " + name
+		}
+		row = append(row, tgbotapi.NewInlineKeyboardButtonData(name, "lang="+lang))
+	}
+
+	return &tgbotapi.InlineKeyboardMarkup{
+		InlineKeyboard: [][]tgbotapi.InlineKeyboardButton{row},
+	}
+}
+
+// setLocale changes the language of the user, which is then never changed
+// automatically from the telegram client language.
+func (u *User) setLocale(lang string) error {
+	if _, languageAvailable := bundle.Translations[lang]; !languageAvailable {
+		return errors.New("language not available.")
+	}
+
+	_, err := pg.Exec(
+		"UPDATE account SET locale = $2, manual_locale = true WHERE id = $1",
+		u.Id, lang)
+	if err != nil {
+		log.Warn().Err(err).Stringer("user", u).Msg("failed to set locale")
+		return ErrDatabase
+	}
+
+	u.Locale = lang
+	return nil
+}
@@ -0,0 +1,104 @@
+package main
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"crypto/sha512"
+	"encoding/binary"
+	"errors"
+	"math/big"
+	"strconv"
+
+	"github.com/btcsuite/btcd/btcec"
+)
+
+// lud05LinkingKey derives the lnurl-auth linking key as described on LUD-05:
+// the hashingKey is m/138'/0 and the linking key is m/138'/<a>/<b>/<c>/<d>,
+// where a, b, c and d come from HMAC-SHA256(hashingKey, domain).
+//
+// Since users don't have their own wallet seed here we derive one from
+// LNURL_AUTH_SEED and the user id, so it doesn't change if the bot token does.
+func lud05LinkingKey(userId int, domain string) (*btcec.PrivateKey, error) {
+	mac := hmac.New(sha256.New, []byte(s.LNURLAuthSeed))
+	mac.Write([]byte("lnurl-auth:" + strconv.Itoa(userId)))
+	key, chain := bip32Master(mac.Sum(nil))
+
+	// m/138'
+	key, chain, err := bip32Child(key, chain, 0x80000000+138)
+	if err != nil {
+		return nil, err
+	}
+
+	// hashingKey: m/138'/0
+	hashingKey, _, err := bip32Child(key, chain, 0)
+	if err != nil {
+		return nil, err
+	}
+
+	// linkingKey: m/138'/<a>/<b>/<c>/<d>
+	for _, index := range lud05PathSuffix(hashingKey, domain) {
+		key, chain, err = bip32Child(key, chain, index)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	sk, _ := btcec.PrivKeyFromBytes(btcec.S256(), key)
+	return sk, nil
+}
+
+// lud05PathSuffix is <a>/<b>/<c>/<d>, the first 16 bytes of
+// HMAC-SHA256(hashingKey, domain) as four big-endian uint32.
+func lud05PathSuffix(hashingKey []byte, domain string) (suffix [4]uint32) {
+	mac := hmac.New(sha256.New, hashingKey)
+	mac.Write([]byte(domain))
+	derivation := mac.Sum(nil)
+
+	for i := range suffix {
+		suffix[i] = binary.BigEndian.Uint32(derivation[i*4 : i*4+4])
+	}
+	return suffix
+}
+
+func bip32Master(seed []byte) (key []byte, chain []byte) {
+	mac := hmac.New(sha512.New, []byte("Bitcoin seed"))
+	mac.Write(seed)
+	I := mac.Sum(nil)
+	return I[:32], I[32:]
+}
+
+// bip32Child is BIP-32 private parent key -> private child key derivation.
+func bip32Child(key []byte, chain []byte, index uint32) ([]byte, []byte, error) {
+	var data []byte
+	if index >= 0x80000000 {
+		data = append([]byte{0}, key...)
+	} else {
+		_, pk := btcec.PrivKeyFromBytes(btcec.S256(), key)
+		data = pk.SerializeCompressed()
+	}
+	data = append(data, 0, 0, 0, 0)
+	binary.BigEndian.PutUint32(data[len(data)-4:], index)
+
+	mac := hmac.New(sha512.New, chain)
+	mac.Write(data)
+	I := mac.Sum(nil)
+
+	n := btcec.S256().N
+	il := new(big.Int).SetBytes(I[:32])
+	if il.Cmp(n) >= 0 {
+		return nil, nil, errors.New("invalid bip32 child, try the next index")
+	}
+
+	child := il.Add(il, new(big.Int).SetBytes(key))
+	child.Mod(child, n)
+	if child.Sign() == 0 {
+		return nil, nil, errors.New("invalid bip32 child, try the next index")
+	}
+
+	// pad to 32 bytes
+	childKey := make([]byte, 32)
+	b := child.Bytes()
+	copy(childKey[32-len(b):], b)
+
+	return childKey, I[32:], nil
+}
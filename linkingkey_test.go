@@ -0,0 +1,104 @@
+package main
+
+import (
+	"encoding/hex"
+	"testing"
+)
+
+// BIP-32 test vector 1, chain m/0'/1/2'/2/1000000000.
+func TestBIP32Derivation(t *testing.T) {
+	seed, _ := hex.DecodeString("000102030405060708090a0b0c0d0e0f")
+	key, chain := bip32Master(seed)
+
+	if got := hex.EncodeToString(key); got != "e8f32e723decf4051aefac8e2c93c9c5b214313817cdb01a1494b917c8436b35" {
+		t.Fatalf("master key = %s", got)
+	}
+	if got := hex.EncodeToString(chain); got != "873dff81c02f525623fd1fe5167eac3a55a049de3d314bb42ee227ffed37d508" {
+		t.Fatalf("master chain code = %s", got)
+	}
+
+	tests := []struct {
+		path  string
+		index uint32
+		key   string
+		chain string
+	}{
+		{"m/0'", 0x80000000,
+			"edb2e14f9ee77d26dd93b4ecede8d16ed408ce149b6cd80b0715a2d911a0afea",
+			"47fdacbd0f1097043b78c63c20c34ef4ed9a111d980047ad16282c7ae6236141"},
+		{"m/0'/1", 1,
+			"3c6cb8d0f6a264c91ea8b5030fadaa8e538b020f0a387421a12de9319dc93368",
+			"2a7857631386ba23dacac34180dd1983734e444fdbf774041578e9b6adb37c19"},
+		{"m/0'/1/2'", 0x80000002,
+			"cbce0d719ecf7431d88e6a89fa1483e02e35092af60c042b1df2ff59fa424dca",
+			"04466b9cc8e161e966409ca52986c584f07e9dc81f735db683c3ff6ec7b1503f"},
+		{"m/0'/1/2'/2", 2,
+			"0f479245fb19a38a1954c5c7c0ebab2f9bdfd96a17563ef28a6a4b1a2a764ef4",
+			"cfb71883f01676f587d023cc53a35bc7f88f724b1f8c2892ac1275ac822a3edd"},
+		{"m/0'/1/2'/2/1000000000", 1000000000,
+			"471b76e389e528d6de6d816857e012c5455051cad6660850e58372a6c3e6e7c8",
+			"c783e67b921d2beb8f6b389cc646d7263b4145701dadd2161548a8b078e65e9e"},
+	}
+
+	for _, test := range tests {
+		var err error
+		key, chain, err = bip32Child(key, chain, test.index)
+		if err != nil {
+			t.Fatalf("%s: %s", test.path, err)
+		}
+		if got := hex.EncodeToString(key); got != test.key {
+			t.Errorf("%s key = %s, want %s", test.path, got, test.key)
+		}
+		if got := hex.EncodeToString(chain); got != test.chain {
+			t.Errorf("%s chain code = %s, want %s", test.path, got, test.chain)
+		}
+	}
+}
+
+// the example on LUD-05.
+func TestLUD05PathSuffix(t *testing.T) {
+	hashingKey, _ := hex.DecodeString("7d417a6a5e9a6a4a879aeaba11a11838764c8fa2b959c242d43dea682b3e409b")
+
+	want := [4]uint32{1588488367, 2659270754, 38110259, 4136336762}
+	if got := lud05PathSuffix(hashingKey, "site.com"); got != want {
+		t.Errorf("path suffix for site.com = %v, want %v", got, want)
+	}
+}
+
+func TestLinkingKey(t *testing.T) {
+	oldSeed, oldToken := s.LNURLAuthSeed, s.TelegramBotToken
+	defer func() { s.LNURLAuthSeed, s.TelegramBotToken = oldSeed, oldToken }()
+	s.TelegramBotToken = "123:token"
+
+	pubkey := func(u User, domain string) string {
+		_, pk, err := u.LinkingKey(domain)
+		if err != nil {
+			t.Fatalf("LinkingKey(%q): %s", domain, err)
+		}
+		return hex.EncodeToString(pk.SerializeCompressed())
+	}
+
+	s.LNURLAuthSeed = ""
+	legacy := pubkey(User{Id: 7}, "site.com")
+
+	s.LNURLAuthSeed = "seed"
+	lud05 := pubkey(User{Id: 7}, "site.com")
+
+	if lud05 == legacy {
+		t.Error("LNURL_AUTH_SEED didn't change the linking key")
+	}
+	if pubkey(User{Id: 7}, "site.com") != lud05 {
+		t.Error("the linking key isn't stable")
+	}
+	if pubkey(User{Id: 7}, "other.com") == lud05 {
+		t.Error("two domains got the same linking key")
+	}
+	if pubkey(User{Id: 8}, "site.com") == lud05 {
+		t.Error("two users got the same linking key")
+	}
+
+	s.TelegramBotToken = "456:other"
+	if pubkey(User{Id: 7}, "site.com") != lud05 {
+		t.Error("the lud-05 linking key depends on the bot token")
+	}
+}
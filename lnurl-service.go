@@ -7,13 +7,13 @@ import (
 	"encoding/json"
 	"fmt"
 	"net/http"
+	"net/url"
 	"strconv"
 	"strings"
 	"time"
 
 	"github.com/docopt/docopt-go"
 	"github.com/fiatjaf/go-lnurl"
-	decodepay "github.com/fiatjaf/ln-decodepay"
 	"github.com/fiatjaf/lntxbot/t"
 	"github.com/gorilla/mux"
 )
@@ -21,7 +21,7 @@ import (
 func handleCreateLNURLWithdraw(ctx context.Context, opts docopt.Opts) (enc string) {
 	u := ctx.Value("initiator").(User)
 
-	maxMSats, err := parseSatoshis(opts)
+	maxMSats, err := parseSatoshis(ctx, opts)
 	if err != nil {
 		send(ctx, u, t.ERROR, t.T{"Err": err.Error()})
 		return
@@ -30,14 +30,10 @@ func handleCreateLNURLWithdraw(ctx context.Context, opts docopt.Opts) (enc strin
 
 	go u.track("lnurl generate", map[string]interface{}{"sats": maxSats})
 
-	challenge := hashString("%s:%d:%d", s.TelegramBotToken, u.Id, maxSats)
-	nexturl := fmt.Sprintf("%s/lnurl/withdraw?challenge=%s", s.ServiceURL, challenge)
-	rds.Set("lnurlwithdraw:"+challenge,
-		fmt.Sprintf(`%d-%d`, u.Id, maxSats), 30*time.Minute)
-
-	enc, err = lnurl.LNURLEncode(nexturl)
+	enc, err = u.lnurlWithdrawVoucher(ctx, maxSats)
 	if err != nil {
-		log.Error().Err(err).Msg("error encoding lnurl on withdraw")
+		log.Warn().Err(err).Msg("error making lnurl-withdraw voucher")
+		send(ctx, u, t.ERROR, t.T{"Err": messageFromError(ctx, err)})
 		return
 	}
 
@@ -45,6 +41,30 @@ func handleCreateLNURLWithdraw(ctx context.Context, opts docopt.Opts) (enc strin
 	return
 }
 
+// lnurlWithdrawVoucher returns an lnurl-withdraw anyone can use to take up to
+// maxSats from this user in the next 30 minutes. each one has its own random
+// challenge, deleted when used, so a voucher can't be redeemed twice.
+// vouchers over the 2fa threshold need the code now, nobody can give it when
+// the voucher is redeemed.
+func (u User) lnurlWithdrawVoucher(ctx context.Context, maxSats int64) (string, error) {
+	if u.Frozen {
+		return "", ErrAccountFrozen
+	}
+	if err := u.checkTwoFactorTransfer(ctx, maxSats*1000); err != nil {
+		return "", err
+	}
+
+	challenge, err := randomHex()
+	if err != nil {
+		return "", err
+	}
+	nexturl := fmt.Sprintf("%s/lnurl/withdraw?challenge=%s", s.ServiceURL, challenge)
+	rds.Set("lnurlwithdraw:"+challenge,
+		fmt.Sprintf(`%d-%d`, u.Id, maxSats), 30*time.Minute)
+
+	return lnurl.LNURLEncode(nexturl)
+}
+
 func serveLNURL() {
 	ctx := context.WithValue(context.Background(), "origin", "external")
 
@@ -127,22 +147,21 @@ func serveLNURL() {
 			return
 		}
 
-		// double-check the challenge (it's a hash of the parameters + our secret)
-		if challenge != hashString("%s:%d:%d", s.TelegramBotToken, payer.Id, chMax) {
-			json.NewEncoder(w).Encode(
-				lnurl.ErrorResponse("Invalid amount for this lnurl."))
-			return
-		}
-
-		if err := rds.Del("lnurlwithdraw:" + challenge).Err(); err != nil {
+		deleted, err := rds.Del("lnurlwithdraw:" + challenge).Result()
+		if err != nil {
 			// if error stop here to prevent extra withdrawals
 			log.Error().Err(err).Str("challenge", challenge).
 				Msg("error deleting used challenge on lnurl withdraw")
 			json.NewEncoder(w).Encode(lnurl.ErrorResponse("Redis error. Please report."))
 			return
 		}
+		if deleted == 0 {
+			// another request used it between our get and our delete
+			json.NewEncoder(w).Encode(lnurl.ErrorResponse("Unknown lnurl."))
+			return
+		}
 
-		inv, err := decodepay.Decodepay(bolt11)
+		inv, err := decodeInvoice(bolt11)
 		if err != nil {
 			json.NewEncoder(w).Encode(lnurl.ErrorResponse("Invalid payment request."))
 			return
@@ -160,6 +179,9 @@ func serveLNURL() {
 			"sats": float64(inv.MSatoshi) / 1000,
 		})
 
+		// the code, if it was needed, was given when the voucher was made
+		ctx = context.WithValue(ctx, "2fa", true)
+
 		// do the pay flow with these odd opts and fake message.
 		opts := docopt.Opts{
 			"pay":       true,
@@ -211,51 +233,98 @@ func serveLNURL() {
 			log.Debug().Str("url", r.URL.String()).Str("amount", amount).
 				Msg("lnurl-pay second request")
 
-				// amount
-			msatoshi, err := strconv.ParseInt(amount, 10, 64)
-			if err != nil {
-				json.NewEncoder(w).Encode(lnurl.ErrorResponse("Invalid msatoshi amount."))
-				return
-			}
-
-			// payer data
-			var hhash [32]byte
-			payerdata := qs.Get("payerdata")
-			if payerdata == "" {
-				hhash = sha256.Sum256([]byte(params.EncodedMetadata))
-			} else {
-				hhash = sha256.Sum256([]byte(params.EncodedMetadata + payerdata))
-			}
-			var payerData lnurl.PayerDataValues
-			json.Unmarshal([]byte(payerdata), &payerData)
-
-			// webhook
-			webhook := qs.Get("webhook")
-
-			bolt11, _, err := receiver.makeInvoice(ctx, &MakeInvoiceArgs{
-				IgnoreInvoiceSizeLimit: true,
-				Msatoshi:               msatoshi,
-				DescriptionHash:        hex.EncodeToString(hhash[:]),
-				Extra: InvoiceExtra{
-					Comment:   qs.Get("comment"),
-					PayerData: &payerData,
-					Webhook:   webhook,
-				},
-			})
-			if err != nil {
-				log.Warn().Err(err).Msg("failed to generate lnurl-pay invoice")
-				json.NewEncoder(w).Encode(
-					lnurl.ErrorResponse("Failed to generate invoice."))
-				return
-			}
-
-			json.NewEncoder(w).Encode(lnurl.LNURLPayValues{
-				LNURLResponse: lnurl.OkResponse(),
-				PR:            bolt11,
-				Routes:        []struct{}{},
-				Disposable:    lnurl.FALSE,
-			})
+			lnurlPayInvoice(ctx, w, qs, receiver, params, "")
+		}
+	})
+}
+
+// lnurlPayInvoice answers the lnurl-pay callback with an invoice for the
+// amount asked, committing to the metadata and payer data.
+func lnurlPayInvoice(
+	ctx context.Context,
+	w http.ResponseWriter,
+	qs url.Values,
+	receiver User,
+	params lnurl.LNURLPayParams,
+	tag string,
+) {
+	// amount
+	msatoshi, err := strconv.ParseInt(qs.Get("amount"), 10, 64)
+	if err != nil {
+		json.NewEncoder(w).Encode(lnurl.ErrorResponse("Invalid msatoshi amount."))
+		return
+	}
+	if msatoshi < params.MinSendable || msatoshi > params.MaxSendable {
+		json.NewEncoder(w).Encode(lnurl.ErrorResponse(fmt.Sprintf(
+			"Amount must be between %d and %d msatoshi.",
+			params.MinSendable, params.MaxSendable)))
+		return
+	}
+
+	// payer data
+	var hhash [32]byte
+	payerdata := qs.Get("payerdata")
+	if payerdata == "" {
+		hhash = sha256.Sum256([]byte(params.EncodedMetadata))
+	} else {
+		hhash = sha256.Sum256([]byte(params.EncodedMetadata + payerdata))
+	}
+	var payerData lnurl.PayerDataValues
+	json.Unmarshal([]byte(payerdata), &payerData)
+
+	// webhook
+	webhook := qs.Get("webhook")
+
+	bolt11, _, err := receiver.makeInvoice(ctx, &MakeInvoiceArgs{
+		IgnoreInvoiceSizeLimit: true,
+		Msatoshi:               msatoshi,
+		DescriptionHash:        hex.EncodeToString(hhash[:]),
+		Tag:                    tag,
+		Extra: InvoiceExtra{
+			Comment:   qs.Get("comment"),
+			PayerData: &payerData,
+			Webhook:   webhook,
+		},
+	})
+	if err != nil {
+		log.Warn().Err(err).Msg("failed to generate lnurl-pay invoice")
+		json.NewEncoder(w).Encode(
+			lnurl.ErrorResponse("Failed to generate invoice."))
+		return
+	}
+
+	json.NewEncoder(w).Encode(lnurl.LNURLPayValues{
+		LNURLResponse: lnurl.OkResponse(),
+		PR:            bolt11,
+		Routes:        []struct{}{},
+		Disposable:    lnurl.FALSE,
+	})
+}
+
+func handleLightningAddress(ctx context.Context, opts docopt.Opts) {
+	u := ctx.Value("initiator").(User)
+
+	if name, err := opts.String("<name>"); err == nil {
+		go u.track("lightning address set", nil)
+
+		if err := u.setLightningAlias(name); err != nil {
+			send(ctx, u, t.ERROR, t.T{"Err": err.Error()})
+			return
 		}
+	}
+
+	var telegramAddress string
+	if u.Username != "" && u.TelegramId != 0 {
+		telegramAddress = u.Username + "@" + getHost()
+	}
+	var aliasAddress string
+	if u.LightningAlias != "" {
+		aliasAddress = u.LightningAlias + "@" + getHost()
+	}
+
+	send(ctx, u, t.LIGHTNINGADDRESSMSG, t.T{
+		"Telegram": telegramAddress,
+		"Alias":    aliasAddress,
 	})
 }
 
@@ -264,14 +333,20 @@ func lnurlPayUserParams(
 	username string,
 ) (receiver User, params lnurl.LNURLPayParams, err error) {
 	isTelegramUsername := false
+	isLightningAlias := false
+	username = strings.ToLower(username)
 
 	if id, errx := strconv.Atoi(username); errx == nil {
 		// case in which username is a number
 		receiver, err = loadUser(id)
-	} else {
-		// case in which username is a real username
-		receiver, err = loadTelegramUsername(username)
+	} else if receiver, err = loadTelegramUsername(username); err == nil {
+		// case in which username is a real username. these come first as
+		// someone may take on telegram a name that was already an alias here
 		isTelegramUsername = true
+	} else {
+		// case in which username is an alias chosen by the user
+		receiver, err = loadLightningAlias(username)
+		isLightningAlias = true
 	}
 	if err != nil {
 		return
@@ -284,11 +359,14 @@ func lnurlPayUserParams(
 
 	if isTelegramUsername {
 		// get user avatar from public t.me/ page
-		if b, err := getTelegramUserPicture(username); err == nil {
+		if b, err := getTelegramUserPicture(ctx, username); err == nil {
 			metadata.Image.Bytes = b
 			metadata.Image.Ext = "jpeg"
 		}
 
+	}
+
+	if isTelegramUsername || isLightningAlias {
 		// add internet identifier
 		metadata.LightningAddress = fmt.Sprintf("%s@%s",
 			username, getHost())
@@ -11,9 +11,11 @@ import (
 	"net/http"
 	"net/url"
 	"strconv"
+	"strings"
 	"time"
 
 	"github.com/btcsuite/btcd/btcec"
+	"github.com/docopt/docopt-go"
 	"github.com/fiatjaf/go-lnurl"
 	"github.com/fiatjaf/lntxbot/t"
 	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api"
@@ -60,9 +62,20 @@ type handleLNURLOpts struct {
 	anonymous              bool
 }
 
+// lnurlSession calls lnurl callbacks with lnurl.Client, like the go-lnurl
+// functions do, so they go through the circuit breaker.
+func lnurlSession() *napping.Session {
+	return &napping.Session{Client: lnurl.Client}
+}
+
 func handleLNURL(ctx context.Context, lnurltext string, opts handleLNURLOpts) {
 	u := ctx.Value("initiator").(User)
 
+	lnurltext = trimLightningScheme(lnurltext)
+	if encoded, ok := findLUD17(lnurltext); ok {
+		lnurltext = encoded
+	}
+
 	_, iparams, err := lnurl.HandleLNURL(lnurltext)
 	if err != nil {
 		if lnurlerr, ok := err.(lnurl.LNURLErrorResponse); ok {
@@ -108,19 +121,36 @@ func handleLNURLAuth(
 	opts handleLNURLOpts,
 	params lnurl.LNURLAuthParams,
 ) {
+	// don't let anyone trick users into hammering a host with logins
+	ratekey := fmt.Sprintf("lnurlauth:%d:%s", u.Id, params.Host)
+	if attempts, err := rds.Incr(ratekey).Result(); err == nil {
+		if attempts == 1 {
+			rds.Expire(ratekey, s.LNURLAuthWindow)
+		}
+		if attempts > int64(s.LNURLAuthMaxAttempts) {
+			logger(ctx).Info().Stringer("user", &u).Str("host", params.Host).
+				Int64("attempts", attempts).Msg("lnurl-auth rate limited")
+			send(ctx, u, t.LNURLAUTHTHROTTLED, t.T{
+				"Host":    params.Host,
+				"Minutes": int(s.LNURLAuthWindow.Minutes()),
+			})
+			return
+		}
+	}
+
 	key, sig, err := u.SignKeyAuth(params.Host, params.K1)
 	if err != nil {
-		send(ctx, u, t.ERROR, t.T{"Err": err.Error()})
+		send(ctx, u, t.ERROR, t.T{"Err": messageFromError(ctx, err)})
 		return
 	}
 
 	var sentsigres lnurl.LNURLResponse
-	_, err = napping.Get(params.Callback, &url.Values{
+	_, err = lnurlSession().Get(params.Callback, &url.Values{
 		"key": {key},
 		"sig": {sig},
 	}, &sentsigres, &sentsigres)
 	if err != nil {
-		send(ctx, u, t.ERROR, t.T{"Err": err.Error()})
+		send(ctx, u, t.ERROR, t.T{"Err": messageFromError(ctx, err)})
 		return
 	}
 	if sentsigres.Status == "ERROR" {
@@ -141,6 +171,47 @@ func handleLNURLAuth(
 	}
 }
 
+// handleLNURLAuthKey shows the key a user would login with on a domain. It
+// doesn't call anything, the lnurl is only decoded to get the domain from it.
+func handleLNURLAuthKey(ctx context.Context, opts docopt.Opts) {
+	u := ctx.Value("initiator").(User)
+
+	text := trimLightningScheme(opts["<domain>"].(string))
+	if strings.HasPrefix(strings.ToLower(text), "lnurl1") {
+		decoded, err := lnurl.LNURLDecode(text)
+		if err != nil {
+			send(ctx, u, t.ERROR, t.T{"Err": "invalid lnurl: " + err.Error()})
+			return
+		}
+		text = decoded
+	}
+
+	host := strings.ToLower(text)
+	if strings.Contains(text, "://") {
+		parsed, err := url.Parse(text)
+		if err != nil || parsed.Host == "" {
+			send(ctx, u, t.ERROR, t.T{"Err": "invalid url."})
+			return
+		}
+		host = parsed.Host
+	}
+
+	_, pk, err := u.LinkingKey(host)
+	if err != nil {
+		log.Error().Err(err).Stringer("user", &u).Str("domain", host).
+			Msg("failed to derive lnurl-auth key")
+		send(ctx, u, t.ERROR, t.T{"Err": messageFromError(ctx, err)})
+		return
+	}
+
+	go u.track("lnurl-auth key", map[string]interface{}{"domain": host})
+
+	send(ctx, u, t.LNURLAUTHKEY, t.T{
+		"Host":      host,
+		"PublicKey": hex.EncodeToString(pk.SerializeCompressed()),
+	})
+}
+
 func handleLNURLWithdraw(
 	ctx context.Context,
 	u User,
@@ -174,43 +245,156 @@ func handleLNURLWithdraw(
 	desc := params.DefaultDescription
 	if opts.balanceCheckService != nil {
 		desc += " (automatic)"
-		log.Info().Stringer("user", &u).Str("service", params.CallbackURL.Hostname()).
+		logger(ctx).Info().Stringer("user", &u).Str("service", params.CallbackURL.Hostname()).
 			Msg("performing automatic balanceCheck")
 	}
 
+	// when the service lets us choose an amount ask the user, unless this is
+	// an automatic balance check, which always withdraws everything
+	if params.MinWithdrawable != params.MaxWithdrawable &&
+		opts.balanceCheckService == nil {
+		if params.MinWithdrawable > params.MaxWithdrawable {
+			send(ctx, u, t.ERROR, t.T{"Err": "invalid withdraw range."})
+			return
+		}
+
+		sent := send(ctx, u, ctx.Value("message"),
+			&tgbotapi.ForceReply{ForceReply: true},
+			t.LNURLWITHDRAWPROMPT, t.T{
+				"Domain": params.CallbackURL.Hostname(),
+				"Min":    float64(params.MinWithdrawable) / 1000,
+				"Max":    float64(params.MaxWithdrawable) / 1000,
+				"Text":   params.DefaultDescription,
+			})
+		if sent == nil {
+			return
+		}
+
+		sentId, _ := sent.(int)
+		data, _ := json.Marshal(RedisWithdrawParams{
+			Type:   "lnurlwithdraw-amount",
+			Params: params,
+		})
+		rds.Set(fmt.Sprintf("reply:%d:%d", u.Id, sentId), data, s.ReplyPromptTimeout)
+		return
+	}
+
 	// lnurl-withdraw: make an invoice with the highest possible value and send
-	bolt11, _, err := u.makeInvoice(ctx, &MakeInvoiceArgs{
+	shouldCancelBalanceCheck = !lnurlwithdrawFinish(
+		ctx, u, params, params.MaxWithdrawable, desc)
+}
+
+type RedisWithdrawParams struct {
+	Type   string                      `json:"type"`
+	Params lnurl.LNURLWithdrawResponse `json:"params"`
+}
+
+func handleLNURLWithdrawAmount(ctx context.Context, msats int64, raw string) {
+	u := ctx.Value("initiator").(User)
+
+	// get data from redis object
+	var data RedisWithdrawParams
+	json.Unmarshal([]byte(raw), &data)
+	if data.Params.CallbackURL == nil {
+		callbackURL, err := url.Parse(data.Params.Callback)
+		if err != nil {
+			send(ctx, u, t.ERROR, t.T{"Err": messageFromError(ctx, err)})
+			return
+		}
+		data.Params.CallbackURL = callbackURL
+	}
+
+	if msats < data.Params.MinWithdrawable || msats > data.Params.MaxWithdrawable {
+		send(ctx, u, t.ERROR, t.T{
+			"Err": fmt.Sprintf("amount must be between %.15g and %.15g sat.",
+				float64(data.Params.MinWithdrawable)/1000,
+				float64(data.Params.MaxWithdrawable)/1000),
+		})
+		return
+	}
+
+	lnurlwithdrawFinish(ctx, u, data.Params, msats, data.Params.DefaultDescription)
+}
+
+// lnurlwithdrawFinish makes an invoice for the given amount and sends it to the
+// lnurl-withdraw callback. returns false if the callback couldn't be reached.
+func lnurlwithdrawFinish(
+	ctx context.Context,
+	u User,
+	params lnurl.LNURLWithdrawResponse,
+	msats int64,
+	desc string,
+) (ok bool) {
+	bolt11, hash, err := u.makeInvoice(ctx, &MakeInvoiceArgs{
 		IgnoreInvoiceSizeLimit: false,
-		Msatoshi:               params.MaxWithdrawable,
+		Msatoshi:               msats,
 		Description:            desc,
 	})
 	if err != nil {
-		send(ctx, u, t.ERROR, t.T{"Err": err.Error()})
-		err = err
-		return
+		send(ctx, u, t.ERROR, t.T{"Err": messageFromError(ctx, err)})
+		return true
 	}
-	log.Debug().Str("bolt11", bolt11).Str("k1", params.K1).
+	logger(ctx).Debug().Str("bolt11", bolt11).Str("k1", params.K1).
 		Msg("sending invoice to lnurl callback")
 	var sentinvres lnurl.LNURLResponse
-	_, err = napping.Get(params.Callback, &url.Values{
+	_, err = lnurlSession().Get(params.Callback, &url.Values{
 		"k1": {params.K1},
 		"pr": {bolt11},
 		"balanceNotify": {fmt.Sprintf("%s/lnurl/withdraw/notify?service=%s&user=%d",
 			s.ServiceURL, params.CallbackURL.Hostname(), u.Id)},
 	}, &sentinvres, &sentinvres)
 	if err != nil {
-		send(ctx, u, t.ERROR, t.T{"Err": err.Error()})
-		shouldCancelBalanceCheck = true
-		return
+		send(ctx, u, t.ERROR, t.T{"Err": messageFromError(ctx, err)})
+		return false
 	}
 	if sentinvres.Status == "ERROR" {
 		send(ctx, u, t.LNURLERROR, t.T{
 			"Host":   params.CallbackURL.Hostname(),
 			"Reason": sentinvres.Reason,
 		})
+		return true
+	}
+	go u.track("lnurl-withdraw", map[string]interface{}{"sats": msats / 1000})
+	go watchLNURLWithdraw(ctx, u, bolt11, hash, params.CallbackURL.Hostname(), msats)
+	return true
+}
+
+// watchLNURLWithdraw tells the user when a service accepted our invoice but
+// never paid it before it expired. A paid invoice is already notified by
+// paymentReceived.
+func watchLNURLWithdraw(
+	ctx context.Context,
+	u User,
+	bolt11, hash, host string,
+	msats int64,
+) {
+	// the expiry is the one the node put in the invoice, we can't choose it
+	inv, err := decodeInvoice(bolt11)
+	if err != nil {
+		logger(ctx).Warn().Err(err).Str("bolt11", bolt11).
+			Msg("can't decode our own lnurl-withdraw invoice")
 		return
 	}
-	go u.track("lnurl-withdraw", map[string]interface{}{"sats": params.MaxWithdrawable})
+	expiresAt := time.Unix(int64(inv.CreatedAt+inv.Expiry), 0)
+
+	select {
+	case <-waitInvoice(hash):
+	case <-time.After(time.Until(expiresAt)):
+		if _, err := u.getTransaction(hash); err == nil {
+			// paid at the last moment
+			return
+		}
+
+		logger(ctx).Info().Stringer("user", &u).Str("host", host).Str("hash", hash).
+			Msg("lnurl-withdraw invoice expired unpaid")
+		go u.track("lnurl-withdraw expired", map[string]interface{}{
+			"sats": msats / 1000,
+		})
+		send(ctx, u, t.LNURLWITHDRAWEXPIRED, t.T{
+			"Sats": float64(msats) / 1000,
+			"Host": host,
+		})
+	}
 }
 
 type RedisPayParams struct {
@@ -218,6 +402,28 @@ type RedisPayParams struct {
 	Params    lnurl.LNURLPayParams `json:"params"`
 	MSatoshi  int64                `json:"msatoshi"`
 	Anonymous bool                 `json:"anonymous"`
+	Check     string               `json:"check"` // see lnurlpayCallbackCheck
+}
+
+// lnurlpayCallbackCheck signs the callback of the first lnurl-pay response so
+// we know the params stored while waiting for the user weren't pointed
+// somewhere else before the invoice is fetched.
+func lnurlpayCallbackCheck(params lnurl.LNURLPayParams) string {
+	return hashString("%s:lnurlpay:%s", s.TelegramBotToken, params.Callback)
+}
+
+func (data RedisPayParams) verify(ctx context.Context, u User) bool {
+	if data.Check != "" && data.Check == lnurlpayCallbackCheck(data.Params) {
+		return true
+	}
+
+	logger(ctx).Warn().Stringer("user", &u).Str("callback", data.Params.Callback).
+		Msg("lnurl-pay stored callback doesn't match the first response")
+	send(ctx, u, t.LNURLERROR, t.T{
+		"Host":   data.Params.CallbackURL().Hostname(),
+		"Reason": "callback changed since the first response.",
+	})
+	return false
 }
 
 func handleLNURLPay(
@@ -235,14 +441,7 @@ func handleLNURLPay(
 		// we will try to pay this amount and we don't care about anything else
 
 		// except we check for amount between limits
-		if *opts.payAmountWithoutPrompt < params.MinSendable || *opts.payAmountWithoutPrompt > params.MaxSendable {
-			send(ctx, u, t.LNURLPAYAMOUNTSNOTICE, t.T{
-				"Domain": receiverName,
-				"Min":    float64(params.MinSendable) / 1000,
-				"Max":    float64(params.MaxSendable) / 1000,
-				"Exact":  params.MinSendable == params.MaxSendable,
-				"NoMax":  params.MaxSendable > 1000000000,
-			})
+		if !lnurlpayCheckAmount(ctx, u, params, *opts.payAmountWithoutPrompt) {
 			return
 		}
 
@@ -309,8 +508,17 @@ func handleLNURLPay(
 
 	var imageURL interface{}
 	if params.Metadata.Image.Ext != "" {
-		imageURL = tempAssetURL("."+params.Metadata.Image.Ext,
-			params.Metadata.Image.Bytes)
+		ext := strings.ToLower(params.Metadata.Image.Ext)
+		size := len(params.Metadata.Image.Bytes)
+		if ext != "png" && ext != "jpg" && ext != "jpeg" {
+			logger(ctx).Info().Str("ext", ext).Str("domain", receiverName).
+				Msg("lnurl-pay metadata image has unsupported type, ignoring")
+		} else if size > s.LNURLImageMaxSize {
+			logger(ctx).Info().Int("size", size).Str("domain", receiverName).
+				Msg("lnurl-pay metadata image is too big, ignoring")
+		} else {
+			imageURL = tempAssetURL("."+ext, params.Metadata.Image.Bytes)
+		}
 	}
 
 	sent := send(ctx, u, t.LNURLPAYPROMPT, t.T{
@@ -332,8 +540,9 @@ func handleLNURLPay(
 		Type:      "lnurlpay-amount",
 		Params:    params,
 		Anonymous: opts.anonymous,
+		Check:     lnurlpayCallbackCheck(params),
 	})
-	rds.Set(fmt.Sprintf("reply:%d:%d", u.Id, sentId), data, time.Hour*1)
+	rds.Set(fmt.Sprintf("reply:%d:%d", u.Id, sentId), data, s.ReplyPromptTimeout)
 
 	if fixedAmount > 0 && params.CommentAllowed > 0 {
 		// need a comment
@@ -341,12 +550,23 @@ func handleLNURLPay(
 	}
 }
 
-func handleLNURLPayAmount(ctx context.Context, msats int64, raw string) {
+// handleLNURLPayAmount is called with an amount for an lnurl-pay prompt. the
+// prompt is stored under replyKey and is only deleted once the amount is
+// accepted, so an amount out of bounds can be fixed in another reply.
+func handleLNURLPayAmount(ctx context.Context, msats int64, raw string, replyKey string) {
 	u := ctx.Value("initiator").(User)
 
 	// get data from redis object
 	var data RedisPayParams
 	json.Unmarshal([]byte(raw), &data)
+	if !data.verify(ctx, u) {
+		return
+	}
+
+	if !lnurlpayCheckAmount(ctx, u, data.Params, msats) {
+		return
+	}
+	rds.Del(replyKey)
 
 	if data.Params.CommentAllowed > 0 {
 		// ask for comment
@@ -357,12 +577,113 @@ func handleLNURLPayAmount(ctx context.Context, msats int64, raw string) {
 	}
 }
 
+// handleLNURLPayFiatAmount is called when the user replies to the amount prompt
+// with a fiat amount. We show the converted value and ask for a confirmation.
+// Like on handleLNURLPayAmount the prompt is kept until the amount is accepted.
+func handleLNURLPayFiatAmount(
+	ctx context.Context,
+	msats int64,
+	fiat float64,
+	currency string,
+	raw string,
+	replyKey string,
+) {
+	u := ctx.Value("initiator").(User)
+
+	var data RedisPayParams
+	json.Unmarshal([]byte(raw), &data)
+	if !data.verify(ctx, u) {
+		return
+	}
+
+	if !lnurlpayCheckAmount(ctx, u, data.Params, msats) {
+		return
+	}
+	rds.Del(replyKey)
+
+	go u.track("lnurl-pay fiat amount", map[string]interface{}{
+		"currency": currency,
+	})
+
+	keyboard := tgbotapi.NewInlineKeyboardMarkup(
+		tgbotapi.NewInlineKeyboardRow(
+			tgbotapi.NewInlineKeyboardButtonData(
+				translate(ctx, t.CANCEL),
+				fmt.Sprintf("cancel=%d", u.Id)),
+			tgbotapi.NewInlineKeyboardButtonData(
+				translateTemplate(ctx, t.PAYAMOUNT,
+					t.T{"Sats": float64(msats) / 1000}),
+				fmt.Sprintf("lnurlpay=%d", msats)),
+		),
+	)
+
+	sent := send(ctx, u, t.LNURLPAYFIATCONFIRM, t.T{
+		"Domain":   data.Params.CallbackURL().Hostname(),
+		"Fiat":     fiat,
+		"Currency": currency,
+		"Sats":     float64(msats) / 1000,
+	}, ctx.Value("message"), &keyboard)
+	if sent == nil {
+		return
+	}
+
+	// the "lnurlpay=" button will look for the same data on the new message
+	sentId, _ := sent.(int)
+	rds.Set(fmt.Sprintf("reply:%d:%d", u.Id, sentId), raw, s.ReplyPromptTimeout)
+}
+
+// lnurlpayRawMetadata is the metadata exactly as the service sent it, which
+// is what the invoice description_hash commits to. encoding it again from the
+// parsed entries could change the order or the spacing.
+func lnurlpayRawMetadata(params lnurl.LNURLPayParams) string {
+	if params.EncodedMetadata != "" {
+		return params.EncodedMetadata
+	}
+	return params.MetadataEncoded()
+}
+
+// lnurlpayCheckAmount tells the user when an amount is out of the bounds
+// given by the lnurl-pay service.
+func lnurlpayCheckAmount(
+	ctx context.Context,
+	u User,
+	params lnurl.LNURLPayParams,
+	msats int64,
+) bool {
+	if msats >= params.MinSendable && msats <= params.MaxSendable {
+		return true
+	}
+
+	receiverName := params.CallbackURL().Hostname()
+	if params.Metadata.LightningAddress != "" {
+		receiverName = params.Metadata.LightningAddress
+	}
+
+	send(ctx, u, t.LNURLPAYAMOUNTSNOTICE, t.T{
+		"Domain": receiverName,
+		"Min":    float64(params.MinSendable) / 1000,
+		"Max":    float64(params.MaxSendable) / 1000,
+		"Exact":  params.MinSendable == params.MaxSendable,
+		"NoMax":  params.MaxSendable > 1000000000,
+	})
+	return false
+}
+
 func handleLNURLPayComment(ctx context.Context, comment string, raw string) {
 	u := ctx.Value("initiator").(User)
 
 	// get data from redis object
 	var data RedisPayParams
 	json.Unmarshal([]byte(raw), &data)
+	if !data.verify(ctx, u) {
+		return
+	}
+
+	// a single dash means the user doesn't want to send a comment
+	comment = strings.TrimSpace(comment)
+	if comment == "-" {
+		comment = ""
+	}
 
 	// proceed to fetch invoice and pay
 	lnurlpayFinish(ctx, u, data.Params, data.MSatoshi, comment, data.Anonymous)
@@ -376,7 +697,10 @@ func lnurlpayAskForComment(
 	anonymous bool,
 ) {
 	sent := send(ctx, u, ctx.Value("message"), &tgbotapi.ForceReply{ForceReply: true},
-		t.LNURLPAYPROMPTCOMMENT, t.T{"Domain": params.CallbackURL().Hostname()})
+		t.LNURLPAYPROMPTCOMMENT, t.T{
+			"Domain":    params.CallbackURL().Hostname(),
+			"MaxLength": params.CommentAllowed,
+		})
 	if sent == nil {
 		return
 	}
@@ -387,8 +711,9 @@ func lnurlpayAskForComment(
 		Params:    params,
 		MSatoshi:  msats,
 		Anonymous: anonymous,
+		Check:     lnurlpayCallbackCheck(params),
 	})
-	rds.Set(fmt.Sprintf("reply:%d:%d", u.Id, sentId), data, time.Hour*1)
+	rds.Set(fmt.Sprintf("reply:%d:%d", u.Id, sentId), data, s.ReplyPromptTimeout)
 }
 
 func lnurlpayFinish(
@@ -399,6 +724,18 @@ func lnurlpayFinish(
 	comment string,
 	anonymous bool,
 ) {
+	// the amount may have been chosen in an earlier step
+	if !lnurlpayCheckAmount(ctx, u, params, msats) {
+		return
+	}
+
+	// comments must fit in the length allowed by the service
+	if params.CommentAllowed == 0 {
+		comment = ""
+	} else if rcomment := []rune(comment); int64(len(rcomment)) > params.CommentAllowed {
+		comment = string(rcomment[:params.CommentAllowed])
+	}
+
 	var payerdata *lnurl.PayerDataValues
 	var proofOfPayerKey *btcec.PrivateKey
 
@@ -416,7 +753,7 @@ func lnurlpayFinish(
 		// other non-anonymous data
 		if !anonymous {
 			if params.PayerData.LightningAddress != nil {
-				payerdata.LightningAddress = u.Username + "@" + getHost()
+				payerdata.LightningAddress = u.LightningAddress()
 			}
 			if params.PayerData.FreeName != nil {
 				payerdata.FreeName = u.Username
@@ -425,7 +762,7 @@ func lnurlpayFinish(
 				key, sig, err := u.SignKeyAuth(
 					params.CallbackURL().Hostname(), params.PayerData.KeyAuth.K1)
 				if err != nil {
-					send(ctx, u, t.ERROR, t.T{"Err": err.Error()})
+					send(ctx, u, t.ERROR, t.T{"Err": messageFromError(ctx, err)})
 					return
 				}
 
@@ -448,14 +785,28 @@ func lnurlpayFinish(
 			})
 		}
 
-		send(ctx, u, t.ERROR, t.T{"Err": err.Error()})
+		send(ctx, u, t.ERROR, t.T{"Err": messageFromError(ctx, err)})
+		return
+	}
+
+	// the description_hash was checked by params.Call, but not the amount
+	if inv, err := decodeInvoice(res.PR); err != nil || inv.MSatoshi != msats {
+		logger(ctx).Warn().Err(err).Stringer("user", &u).Str("bolt11", res.PR).
+			Int64("msats", msats).Msg("lnurl-pay returned an invoice for a different amount")
+		send(ctx, u, t.LNURLERROR, t.T{
+			"Host":   params.CallbackURL().Hostname(),
+			"Reason": "returned an invoice for a different amount.",
+		})
 		return
 	}
 
 	processingMessageId := send(ctx, u, res.PR+"\n\n"+translate(ctx, t.PROCESSING))
 
+	// start waiting before paying as internal payments resolve immediately
+	wait := waitPaymentSuccess(res.ParsedInvoice.PaymentHash)
+
 	// pay it
-	hash, err := u.payInvoice(ctx, res.PR, 0)
+	hash, err := u.payInvoice(ctx, res.PR, 0, nil)
 	if err == nil {
 		deleteMessage(&tgbotapi.Message{
 			Chat:      &tgbotapi.Chat{ID: u.TelegramChatId},
@@ -463,8 +814,17 @@ func lnurlpayFinish(
 		})
 
 		// wait until lnurl-pay is paid successfully.
+		paymentWatchers.Add(1)
 		go func() {
-			preimage := <-waitPaymentSuccess(hash)
+			defer paymentWatchers.Done()
+			wctx, cancel := context.WithTimeout(context.Background(), time.Hour*24)
+			defer cancel()
+			preimage, err := awaitPaymentResult(wctx, hash, wait)
+			if err != nil {
+				logger(ctx).Debug().Err(err).Str("hash", hash).
+					Msg("lnurl-pay payment didn't succeed")
+				return
+			}
 			bpreimage, _ := hex.DecodeString(preimage)
 
 			// send all metadata about this payment as a file to be kept on telegram
@@ -474,7 +834,7 @@ func lnurlpayFinish(
 			if f, err := zip.Create("metadata.json"); err != nil {
 				goto zipfinished
 			} else {
-				if _, err = f.Write([]byte(params.MetadataEncoded())); err != nil {
+				if _, err = f.Write([]byte(lnurlpayRawMetadata(params))); err != nil {
 					goto zipfinished
 				}
 			}
@@ -506,7 +866,7 @@ func lnurlpayFinish(
 
 		zipfinished:
 			if err != nil {
-				log.Warn().Err(err).Msg("failed to zip metadata")
+				logger(ctx).Warn().Err(err).Msg("failed to zip metadata")
 				send(ctx, u, t.ERROR, t.T{
 					"Err": "Failed to send lnurl-pay metadata. Please report."})
 				return
@@ -518,36 +878,52 @@ func lnurlpayFinish(
 				"HashFirstChars": res.ParsedInvoice.PaymentHash[:5],
 			}, tempAssetURL(".zip", zipbuf.Bytes()))
 
-			// notify user with success action end applicable
+			// notify user, with the success action when applicable
+			var text, value, url string
+			var decerr error
 			if res.SuccessAction != nil {
-				var text string
-				var decerr error
-				var value string
-
 				switch res.SuccessAction.Tag {
 				case "message":
 					text = res.SuccessAction.Message
 				case "url":
 					text = res.SuccessAction.Description
+					url = res.SuccessAction.URL
 				case "aes":
 					text = res.SuccessAction.Description
-					value, decerr = res.SuccessAction.Decipher(bpreimage)
+					if len(bpreimage) != 32 {
+						decerr = fmt.Errorf("got a preimage of %d bytes instead of 32",
+							len(bpreimage))
+					} else {
+						value, decerr = res.SuccessAction.Decipher(bpreimage)
+						if decerr == nil && value == "" {
+							decerr = fmt.Errorf("deciphered value is empty")
+						}
+					}
+					if decerr != nil {
+						logger(ctx).Warn().Err(decerr).Str("hash", hash).
+							Str("domain", params.CallbackURL().Hostname()).
+							Msg("failed to decipher lnurl-pay aes success action")
+					}
 				}
+			}
 
-				// give it a time so it's the last message to be sent
-				time.Sleep(2 * time.Second)
+			// give it a time so it's the last message to be sent
+			time.Sleep(2 * time.Second)
 
-				send(ctx, u, t.LNURLPAYSUCCESS, t.T{
-					"Domain":        params.CallbackURL().Hostname(),
-					"Text":          text,
-					"Value":         value,
-					"URL":           res.SuccessAction.URL,
-					"DecipherError": decerr,
-				}, ctx.Value("message"))
-			}
+			send(ctx, u, t.LNURLPAYSUCCESS, t.T{
+				"Domain":         params.CallbackURL().Hostname(),
+				"Hash":           res.ParsedInvoice.PaymentHash,
+				"HashFirstChars": res.ParsedInvoice.PaymentHash[:5],
+				"HasAction":      res.SuccessAction != nil,
+				"Text":           text,
+				"Value":          value,
+				"URL":            url,
+				"DecipherError":  decerr,
+			}, ctx.Value("message"))
 		}()
 	} else {
-		send(ctx, u, t.ERROR, t.T{"Err": err.Error()}, processingMessageId)
+		removeWaitingPaymentSuccess(res.ParsedInvoice.PaymentHash, wait)
+		send(ctx, u, t.ERROR, t.T{"Err": messageFromError(ctx, err)}, processingMessageId)
 	}
 }
 
@@ -1,6 +1,12 @@
 package main
 
-import "os"
+import (
+	"context"
+	"os"
+
+	"github.com/lucsky/cuid"
+	"github.com/rs/zerolog"
+)
 
 // PluginLogger prefixes the log output with 'plugin-lntxbot'
 // and writes to stderr
@@ -10,3 +16,18 @@ func (pl PluginLogger) Write(p []byte) (n int, err error) {
 	_, err = os.Stderr.Write([]byte("\x1B[01;46mlntxbot\x1B[0m " + string(p)))
 	return len(p), err
 }
+
+// withRequestId attaches a logger tagged with a fresh correlation id to the
+// context so everything logged while handling one update can be traced together.
+func withRequestId(ctx context.Context) context.Context {
+	reqlog := log.With().Str("req", cuid.Slug()).Logger()
+	return context.WithValue(ctx, "logger", &reqlog)
+}
+
+// logger returns the request-scoped logger, or the global one when there is none.
+func logger(ctx context.Context) *zerolog.Logger {
+	if reqlog, ok := ctx.Value("logger").(*zerolog.Logger); ok {
+		return reqlog
+	}
+	return &log
+}
@@ -37,6 +37,7 @@ type Settings struct {
 	Host             string   `envconfig:"HOST" default:"0.0.0.0"`
 	Port             string   `envconfig:"PORT" required:"true"`
 	TorProxyURL      *url.URL `envconfig:"TOR_PROXY_URL"`
+	Network          string   `envconfig:"NETWORK" default:"mainnet"` // mainnet, testnet, signet or regtest
 	TelegramBotToken string   `envconfig:"TELEGRAM_BOT_TOKEN" required:"true"`
 	PostgresURL      string   `envconfig:"DATABASE_URL" required:"true"`
 	RedisURL         string   `envconfig:"REDIS_URL" required:"true"`
@@ -44,6 +45,9 @@ type Settings struct {
 	ClicheJARPath    string   `envconfig:"CLICHE_JAR_PATH" required:"true"`
 	ClicheDataDir    string   `envconfig:"CLICHE_DATADIR" required:"true"`
 
+	// also accept commands like $balance, besides slash commands
+	DiscordTextCommands bool `envconfig:"DISCORD_TEXT_COMMANDS" default:"true"`
+
 	// account in the database named '@'
 	ProxyAccount int `envconfig:"PROXY_ACCOUNT" required:"true"`
 	AdminAccount int `envconfig:"ADMIN_ACCOUNT"`
@@ -53,9 +57,19 @@ type Settings struct {
 	BitrefillBasicAuth string `envconfig:"BITREFILL_BASIC_AUTH"`
 
 	InvoiceTimeout       time.Duration `envconfig:"INVOICE_TIMEOUT" default:"480h"`
+	InvoiceMinSats       int64         `envconfig:"INVOICE_MIN_SATS" default:"0"` // 0 means no limit, can be changed per user
+	InvoiceMaxSats       int64         `envconfig:"INVOICE_MAX_SATS" default:"0"`
+	InvoiceDescription   string        `envconfig:"INVOICE_DESCRIPTION" default:"Payment to {user}"` // used when users haven't set their own
 	PayConfirmTimeout    time.Duration `envconfig:"PAY_CONFIRM_TIMEOUT" default:"10m"`
+	ReplyPromptTimeout   time.Duration `envconfig:"REPLY_PROMPT_TIMEOUT" default:"15m"` // prompts answered by replying
+	PaymentMaxAttempts   int           `envconfig:"PAYMENT_MAX_ATTEMPTS" default:"3"`   // tries on temporary failures
+	PaymentRateLimit     int           `envconfig:"PAYMENT_RATE_LIMIT" default:"20"`    // payments per user per minute, 0 means no limit
+	PendingCheckInterval time.Duration `envconfig:"PENDING_CHECK_INTERVAL" default:"10m"`
 	GiveAwayTimeout      time.Duration `envconfig:"GIVE_AWAY_TIMEOUT" default:"5h"`
 	HiddenMessageTimeout time.Duration `envconfig:"HIDDEN_MESSAGE_TIMEOUT" default:"72h"`
+	TxListPageSize       int           `envconfig:"TX_LIST_PAGE_SIZE" default:"25"`
+	NodeAliasTTL         time.Duration `envconfig:"NODE_ALIAS_TTL" default:"6h"` // 0 means never expire
+	NodeAliasWarmup      time.Duration `envconfig:"NODE_ALIAS_WARMUP"`           // interval, 0 means disabled
 
 	CoinflipDailyQuota int `envconfig:"COINFLIP_DAILY_QUOTA" default:"5"` // times each user can join a coinflip
 	CoinflipAvgDays    int `envconfig:"COINFLIP_AVG_DAYS" default:"7"`    // days we'll consider for the average
@@ -64,6 +78,25 @@ type Settings struct {
 	GiveawayDailyQuota int `envconfig:"GIVEAWAY_DAILY_QUOTA" default:"5"`
 	GiveawayAvgDays    int `envconfig:"GIVEAWAY_AVG_DAYS" default:"7"`
 
+	LNURLImageMaxSize int `envconfig:"LNURL_IMAGE_MAX_SIZE" default:"1000000"` // in bytes
+
+	LNURLAuthSeed        string        `envconfig:"LNURL_AUTH_SEED"`                     // changes all lnurl-auth keys, see LinkingKey
+	LNURLAuthMaxAttempts int           `envconfig:"LNURL_AUTH_MAX_ATTEMPTS" default:"5"` // per user per host
+	LNURLAuthWindow      time.Duration `envconfig:"LNURL_AUTH_WINDOW" default:"10m"`
+
+	TwoFactorMaxAttempts int           `envconfig:"TWOFACTOR_MAX_ATTEMPTS" default:"5"` // wrong codes before locking
+	TwoFactorLockout     time.Duration `envconfig:"TWOFACTOR_LOCKOUT" default:"15m"`
+
+	MetricsAddr string `envconfig:"METRICS_ADDR"` // like ":9100", serves /metrics there, disabled if empty
+	LogJSON     bool   `envconfig:"LOG_JSON"`     // plain JSON lines instead of colored console output
+
+	ShutdownTimeout time.Duration `envconfig:"SHUTDOWN_TIMEOUT" default:"30s"` // max wait for in-flight payments on exit
+
+	NodeReconnectAttempts int `envconfig:"NODE_RECONNECT_ATTEMPTS" default:"5"` // backoff doubles from 1s
+
+	CircuitBreakerFailures int           `envconfig:"CIRCUIT_BREAKER_FAILURES" default:"5"` // consecutive, per host
+	CircuitBreakerCooldown time.Duration `envconfig:"CIRCUIT_BREAKER_COOLDOWN" default:"1m"`
+
 	Banned map[int]bool `envconfig:"BANNED"`
 
 	NodeId string
@@ -72,7 +105,6 @@ type Settings struct {
 
 var s Settings
 var pg *sqlx.DB
-var ln *cliche.Control
 var rds *redis.Client
 var bot *tgbotapi.BotAPI
 var discord *discordgo.Session
@@ -80,6 +112,7 @@ var amp *amplitude.Client
 var log = zerolog.New(os.Stderr).Output(zerolog.ConsoleWriter{Out: PluginLogger{}})
 var router = mux.NewRouter()
 var waitingPaymentSuccesses = cmap.New() //  make(map[string][]chan string)
+var paymentsInFlight = cmap.New()        // payment hashes being paid right now
 var bundle t.Bundle
 
 //go:embed templates
@@ -95,17 +128,32 @@ func main() {
 		log.Fatal().Err(err).Msg("couldn't process envconfig.")
 	}
 
-	// increase default lnurl client timeout because people are using tor unfortunately
-	lnurl.Client = &http.Client{Timeout: 25 * time.Second}
+	// stop calling external hosts that keep failing. only the clients for lnurl
+	// servers, price sources, images and the node explorer, never the default
+	// transport as the telegram and discord clients use it too
+	external := newCircuitBreaker(http.DefaultTransport)
+	priceClient.Transport = external
+	imageClient.Transport = external
+	explorerClient.Transport = external
+
+	// increase default lnurl client timeout because people are using tor unfortunately.
+	// its own transport sends .onion hosts to lnurl.TorClient
+	lnurl.Client = &http.Client{
+		Timeout:   25 * time.Second,
+		Transport: newCircuitBreaker(lnurl.Client.Transport),
+	}
 	lnurl.TorClient = &http.Client{
 		Timeout: 50 * time.Second,
-		Transport: &http.Transport{
+		Transport: newCircuitBreaker(&http.Transport{
 			Proxy: http.ProxyURL(s.TorProxyURL),
-		},
+		}),
 	}
 
 	// setup logger
 	zerolog.SetGlobalLevel(zerolog.DebugLevel)
+	if s.LogJSON {
+		log = zerolog.New(os.Stderr)
+	}
 	log = log.With().Timestamp().Logger()
 
 	// http client
@@ -123,12 +171,8 @@ func main() {
 	rand.Seed(time.Now().UnixNano())
 
 	// setup cliche
-	ln = &cliche.Control{
-		JARPath: s.ClicheJARPath,
-		DataDir: s.ClicheDataDir,
-	}
 	s.NodeId = startCliche()
-	go handleClicheEvents()
+	go handleClicheEvents(ln())
 
 	// postgres connection
 	pg, err = sqlx.Connect("postgres", s.PostgresURL)
@@ -199,13 +243,23 @@ func main() {
 	go startKicking()
 	go sats4adsCleanupRoutine()
 	go lnurlBalanceCheckRoutine()
-	go checkAllOutgoingPayments(routineCtx)
+	go priceAlertRoutine()
+	go scheduledPaymentsRoutine()
+	if s.NodeAliasWarmup != 0 {
+		go nodeAliasWarmupRoutine()
+	}
+	go pendingOutgoingPaymentsRoutine(routineCtx)
 	go checkAllIncomingPayments(routineCtx)
 
 	// routes
 	//
 	// telegram webhooks
 	router.Path("/" + bot.Token).HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if shuttingDown() {
+			// telegram will retry this update later, hopefully on the new process
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
 		bytes, _ := ioutil.ReadAll(r.Body)
 		var update tgbotapi.Update
 		json.Unmarshal(bytes, &update)
@@ -215,10 +269,15 @@ func main() {
 	// lndhub-compatible routes
 	registerAPIMethods()
 
+	// metrics go on their own port so they aren't public
+	serveMetrics()
+
 	// register webserver routes
+	serveHealth()
 	serveQRCodes()
 	serveTempAssets()
 	serveLNURL()
+	serveTipJar()
 	serveLNURLBalanceNotify()
 	servePages()
 	router.Path("/").HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
@@ -235,20 +294,24 @@ func main() {
 		WriteTimeout: 300 * time.Second,
 		ReadTimeout:  300 * time.Second,
 	}
-	if err := srv.ListenAndServe(); err != nil {
+	go handleShutdownSignals(srv)
+	if err := srv.ListenAndServe(); err != nil && err != http.ErrServerClosed {
 		log.Error().Err(err).Msg("error serving http")
 	}
+
+	drainPayments()
 }
 
 func startCliche() string {
 	log.Info().Msg("starting cliche")
 
-	err := ln.Start()
+	c, err := startNode()
 	if err != nil {
 		log.Fatal().Err(err).Msg("failed to start cliche")
 	}
+	setLN(c)
 
-	nodeinfo, err := ln.GetInfo()
+	nodeinfo, err := ln().GetInfo()
 	if err != nil {
 		log.Fatal().Err(err).Msg("can't talk to cliche")
 		return ""
@@ -263,17 +326,19 @@ func startCliche() string {
 	return nodeinfo.Keys.Pub
 }
 
-func handleClicheEvents() {
+// handleClicheEvents reads the events of one cliche process, it must be called
+// again for every new one.
+func handleClicheEvents(c *cliche.Control) {
 	ctx := context.WithValue(context.Background(), "origin", "cliche")
 
 	go func() {
-		for event := range ln.IncomingPayments {
+		for event := range c.IncomingPayments {
 			go paymentReceived(ctx, event.PaymentHash, event.Msatoshi)
 		}
 	}()
 
 	go func() {
-		for event := range ln.PaymentSuccesses {
+		for event := range c.PaymentSuccesses {
 			go paymentHasSucceeded(
 				ctx,
 				event.Msatoshi,
@@ -286,7 +351,7 @@ func handleClicheEvents() {
 	}()
 
 	go func() {
-		for event := range ln.PaymentFailures {
+		for event := range c.PaymentFailures {
 			go paymentHasFailed(ctx, event.PaymentHash, event.Failure)
 		}
 	}()
@@ -314,14 +379,14 @@ func createLocalizerBundle() (t.Bundle, error) {
 		}
 		return ""
 	})
-	bundle.AddFunc("dollar", func(isat interface{}) string {
+	bundle.AddFunc("fiat", func(isat interface{}, currency string) string {
 		switch sat := isat.(type) {
 		case int64:
-			return getDollarPrice(sat * 1000)
+			return getFiatPrice(sat*1000, currency)
 		case int:
-			return getDollarPrice(int64(sat) * 1000)
+			return getFiatPrice(int64(sat)*1000, currency)
 		case float64:
-			return getDollarPrice(int64(sat * 1000))
+			return getFiatPrice(int64(sat*1000), currency)
 		default:
 			return "~"
 		}
@@ -340,7 +405,9 @@ func createLocalizerBundle() (t.Bundle, error) {
 	})
 	bundle.AddFunc("escapehtml", escapeHTML)
 	bundle.AddFunc("nodeLink", nodeLink)
-	bundle.AddFunc("nodeAlias", getNodeAlias)
+	bundle.AddFunc("nodeAlias", func(nodeId string) string {
+		return escapeHTML(stripControlChars(getNodeAlias(nodeId)))
+	})
 	bundle.AddFunc("channelLink", channelLink)
 	bundle.AddFunc("nodeAliasLink", nodeAliasLink)
 	bundle.AddFunc("makeLinks", makeLinks)
@@ -365,6 +432,12 @@ func createLocalizerBundle() (t.Bundle, error) {
 	})
 	bundle.AddFunc("lower", strings.ToLower)
 	bundle.AddFunc("roman", roman)
+	bundle.AddFunc("counter", func(useRoman interface{}, n int) string {
+		if b, _ := useRoman.(bool); b {
+			return roman(n)
+		}
+		return strconv.Itoa(n)
+	})
 	bundle.AddFunc("letter", func(i int) string { return string([]rune{rune(i) + 97}) })
 	bundle.AddFunc("add", func(a, b int) int { return a + b })
 	bundle.AddFunc("menuItem", func(sats interface{}, rawItem string, showSats bool) string {
@@ -0,0 +1,15 @@
+package main
+
+import (
+	"os"
+	"testing"
+)
+
+// TestMain loads the translations, so tests can render messages like the bot
+// does.
+func TestMain(m *testing.M) {
+	if _, err := createLocalizerBundle(); err != nil {
+		log.Fatal().Err(err).Msg("failed to create the localizer bundle")
+	}
+	os.Exit(m.Run())
+}
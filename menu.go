@@ -0,0 +1,95 @@
+package main
+
+import (
+	"context"
+	"sort"
+
+	"github.com/docopt/docopt-go"
+	"github.com/fiatjaf/lntxbot/t"
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api"
+)
+
+type menuEntry struct {
+	Name string
+	Sats float64
+}
+
+func handleMenu(ctx context.Context, opts docopt.Opts) {
+	u := ctx.Value("initiator").(User)
+
+	message, _ := ctx.Value("message").(*tgbotapi.Message)
+	isGroup := message != nil && message.Chat.Type != "private"
+
+	if opts["add"].(bool) || opts["remove"].(bool) {
+		if !isGroup {
+			send(ctx, u, t.MUSTBEGROUP)
+			return
+		}
+		if !isAdmin(message.Chat, message.From) {
+			send(ctx, u, t.MUSTBEADMIN)
+			return
+		}
+
+		g, err := ensureTelegramGroup(message.Chat.ID, u.Locale)
+		if err != nil {
+			log.Warn().Err(err).Stringer("user", &u).Int64("group", message.Chat.ID).
+				Msg("failed to ensure group")
+			return
+		}
+
+		name, _ := opts.String("<name>")
+		var sats int64
+		if opts["add"].(bool) {
+			msats, err := parseSatoshis(ctx, opts)
+			if err != nil {
+				send(ctx, g, t.ERROR, t.T{"Err": err.Error()})
+				return
+			}
+			sats = msats / 1000
+		}
+
+		go u.track("menu set", map[string]interface{}{
+			"group": g.TelegramId,
+			"name":  name,
+			"sats":  sats,
+		})
+
+		if err := g.setMenuItem(name, sats); err != nil {
+			log.Warn().Err(err).Stringer("group", &g).Str("name", name).
+				Msg("failed to set menu item")
+			send(ctx, g, t.ERROR, t.T{"Err": err.Error()})
+			return
+		}
+
+		ctx = context.WithValue(ctx, "group", g)
+	}
+
+	// list the menu
+	go u.track("menu", nil)
+
+	var custom []menuEntry
+	if g, ok := ctx.Value("group").(GroupChat); ok && g.TelegramId != 0 {
+		for name, sats := range g.getMenu() {
+			custom = append(custom, menuEntry{name, float64(sats)})
+		}
+	}
+
+	defaults := make([]menuEntry, 0, len(menuItems))
+	for name, msats := range menuItems {
+		if stringIsIn(name, []string{"msat", "msats", "sat", "sats", "btc"}) {
+			// these are units, not menu items
+			continue
+		}
+		f, _ := msats.Float64()
+		defaults = append(defaults, menuEntry{name, f / 1000})
+	}
+
+	for _, list := range [][]menuEntry{custom, defaults} {
+		sort.Slice(list, func(i, j int) bool { return list[i].Sats < list[j].Sats })
+	}
+
+	send(ctx, t.MENUMSG, t.T{
+		"Custom":  custom,
+		"Default": defaults,
+	})
+}
@@ -203,6 +203,9 @@ func send(ctx context.Context, things ...interface{}) (id interface{}) {
 		}
 
 		ctx = context.WithValue(ctx, "locale", locale)
+		if target != nil && target.Currency != "" {
+			ctx = context.WithValue(ctx, "currency", target.Currency)
+		}
 		text = translateTemplate(ctx, template, templateData)
 		text = strings.TrimSpace(text)
 	}
@@ -274,6 +277,11 @@ func send(ctx context.Context, things ...interface{}) (id interface{}) {
 				values.Set("chat_id", strconv.FormatInt(groupId, 10))
 			} else {
 				// send to user
+				if target.TelegramChatId == 0 && !edit {
+					// never started the bot, or blocked it (see below)
+					log.Debug().Msg("user has no telegram chat, not sending")
+					return nil
+				}
 				values.Set("chat_id", strconv.FormatInt(target.TelegramChatId, 10))
 			}
 
@@ -393,6 +401,13 @@ func send(ctx context.Context, things ...interface{}) (id interface{}) {
 					resp, err = bot.MakeRequest(method, values)
 				}
 				if err != nil {
+					if !useGroup && target != nil && target.TelegramChatId != 0 &&
+						values.Get("chat_id") == strconv.FormatInt(target.TelegramChatId, 10) &&
+						telegramChatGone(err) {
+						log.Info().Err(err).Msg("user has blocked the bot, marking inactive")
+						target.unsetChat()
+						return
+					}
 					log.Warn().Err(err).Msg("error sending message to telegram")
 					return
 				}
@@ -503,6 +518,13 @@ func send(ctx context.Context, things ...interface{}) (id interface{}) {
 	return nil
 }
 
+// telegramChatGone tells if telegram refused a message because the user has
+// blocked the bot or deleted their account. their chat is unset so we stop
+// trying, and set again as soon as they write to the bot in private.
+func telegramChatGone(err error) bool {
+	return err != nil && strings.HasPrefix(err.Error(), "Forbidden: ")
+}
+
 func removeKeyboardButtons(ctx context.Context) {
 	send(ctx, EDIT, &tgbotapi.InlineKeyboardMarkup{
 		InlineKeyboard: [][]tgbotapi.InlineKeyboardButton{},
@@ -0,0 +1,125 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// counters exposed in the prometheus text format on METRICS_ADDR. amounts are
+// kept in msat and shown in sat.
+var metrics = struct {
+	sync.Mutex
+	paymentsSent     int64
+	paymentsReceived int64
+	msatsSent        int64
+	msatsReceived    int64
+	paymentsFailed   map[string]int64 // by error class
+}{paymentsFailed: make(map[string]int64)}
+
+func metricPaymentSent(msats int64) {
+	metrics.Lock()
+	defer metrics.Unlock()
+	metrics.paymentsSent++
+	metrics.msatsSent += msats
+}
+
+func metricPaymentReceived(msats int64) {
+	metrics.Lock()
+	defer metrics.Unlock()
+	metrics.paymentsReceived++
+	metrics.msatsReceived += msats
+}
+
+func metricPaymentFailed(err error) {
+	metrics.Lock()
+	defer metrics.Unlock()
+	metrics.paymentsFailed[errorClass(err)]++
+}
+
+// errorClass is the key of the message messageFromError would show for this
+// error, like "noroute", or "other" for errors without one.
+func errorClass(err error) string {
+	var apperr *AppError
+	if errors.As(err, &apperr) {
+		return strings.ToLower(strings.TrimPrefix(string(apperr.Key), "Err"))
+	}
+	return "other"
+}
+
+func serveMetrics() {
+	if s.MetricsAddr == "" {
+		return
+	}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/metrics", func(w http.ResponseWriter, r *http.Request) {
+		var gauges struct {
+			Users        int64 `db:"users"`
+			BalanceMsats int64 `db:"balance"`
+		}
+		err := pg.Get(&gauges, `
+SELECT
+  (SELECT count(*) FROM account) AS users,
+  (SELECT coalesce(sum(balance), 0)::numeric(13) FROM lightning.balance) AS balance
+        `)
+		if err != nil {
+			log.Warn().Err(err).Msg("failed to get metrics gauges")
+			http.Error(w, "database error", http.StatusInternalServerError)
+			return
+		}
+
+		metrics.Lock()
+		defer metrics.Unlock()
+
+		w.Header().Set("Content-Type", "text/plain; version=0.0.4")
+
+		writeMetric(w, "lntxbot_payments_sent_total", "counter",
+			"Outgoing lightning payments that succeeded.", metrics.paymentsSent)
+		writeMetric(w, "lntxbot_payments_received_total", "counter",
+			"Incoming lightning payments to bot invoices.", metrics.paymentsReceived)
+		writeMetric(w, "lntxbot_sent_sats_total", "counter",
+			"Amount of the outgoing lightning payments that succeeded.",
+			float64(metrics.msatsSent)/1000)
+		writeMetric(w, "lntxbot_received_sats_total", "counter",
+			"Amount of the incoming lightning payments.",
+			float64(metrics.msatsReceived)/1000)
+
+		fmt.Fprintln(w, "# HELP lntxbot_payments_failed_total Outgoing lightning payments that failed, by error class.")
+		fmt.Fprintln(w, "# TYPE lntxbot_payments_failed_total counter")
+		classes := make([]string, 0, len(metrics.paymentsFailed))
+		for class := range metrics.paymentsFailed {
+			classes = append(classes, class)
+		}
+		sort.Strings(classes)
+		for _, class := range classes {
+			fmt.Fprintf(w, "lntxbot_payments_failed_total{class=%q} %d\n",
+				class, metrics.paymentsFailed[class])
+		}
+
+		fmt.Fprintln(w, "# HELP lntxbot_circuit_open External hosts we're not calling because they keep failing.")
+		fmt.Fprintln(w, "# TYPE lntxbot_circuit_open gauge")
+		for _, host := range openCircuits() {
+			fmt.Fprintf(w, "lntxbot_circuit_open{host=%q} 1\n", host)
+		}
+
+		writeMetric(w, "lntxbot_users", "gauge",
+			"Accounts in the database.", gauges.Users)
+		writeMetric(w, "lntxbot_balance_sats", "gauge",
+			"Sum of the balances of all accounts.", float64(gauges.BalanceMsats)/1000)
+	})
+
+	go func() {
+		log.Info().Str("addr", s.MetricsAddr).Msg("serving metrics")
+		if err := http.ListenAndServe(s.MetricsAddr, mux); err != nil {
+			log.Error().Err(err).Msg("error serving metrics")
+		}
+	}()
+}
+
+func writeMetric(w http.ResponseWriter, name, kind, help string, value interface{}) {
+	fmt.Fprintf(w, "# HELP %s %s\n# TYPE %s %s\n%s %v\n", name, help, name, kind, name, value)
+}
@@ -0,0 +1,130 @@
+package main
+
+import (
+	"errors"
+	"io"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/fiatjaf/go-cliche"
+)
+
+var reconnectMutex sync.Mutex
+
+// the cliche we talk to, replaced by reconnectNode while other goroutines are
+// using it, so it is only read through ln()
+var (
+	lnControl *cliche.Control
+	lnMutex   sync.RWMutex
+)
+
+func ln() *cliche.Control {
+	lnMutex.RLock()
+	defer lnMutex.RUnlock()
+	return lnControl
+}
+
+func setLN(c *cliche.Control) {
+	lnMutex.Lock()
+	defer lnMutex.Unlock()
+	lnControl = c
+}
+
+// the first wait between reconnection attempts, doubled after each one
+var nodeReconnectDelay = time.Second
+
+// startNode starts a new cliche process. replaced in tests.
+var startNode = func() (*cliche.Control, error) {
+	c := &cliche.Control{
+		JARPath: s.ClicheJARPath,
+		DataDir: s.ClicheDataDir,
+	}
+	return c, c.Start()
+}
+
+// how long nodeAlive waits for an answer before taking cliche for dead
+var nodeAliveTimeout = 10 * time.Second
+
+// nodeAlive tells if the current ln still answers. replaced in tests.
+var nodeAlive = func() bool {
+	return answersWithin(nodeAliveTimeout, func() error {
+		_, err := ln().GetInfo()
+		return err
+	})
+}
+
+// answersWithin tells if call returns without an error before the timeout.
+// cliche.Control.Call waits forever for a process that hangs, so the call is
+// left running in its goroutine when the time is up.
+func answersWithin(timeout time.Duration, call func() error) bool {
+	result := make(chan error, 1)
+	go func() { result <- call() }()
+
+	select {
+	case err := <-result:
+		return err == nil
+	case <-time.After(timeout):
+		return false
+	}
+}
+
+// nodeConnectionBroken tells if an error from ln means we lost cliche itself,
+// not that the call failed for some normal reason.
+func nodeConnectionBroken(err error) bool {
+	if errors.Is(err, io.EOF) || errors.Is(err, io.ErrClosedPipe) {
+		return true
+	}
+	msg := strings.ToLower(err.Error())
+	return strings.Contains(msg, "broken pipe") ||
+		strings.Contains(msg, "file already closed") ||
+		strings.Contains(msg, "connection reset") ||
+		strings.Contains(msg, "connection refused")
+}
+
+// reconnectNode replaces ln with a newly started cliche, waiting longer after
+// each failed attempt. Concurrent callers wait for the same restart instead of
+// starting their own.
+//
+// A cliche.Control can't be started twice: Start makes new event channels, so
+// the loops from handleClicheEvents would keep reading the old ones and cliche
+// would block on its first event. Each restart gets a new Control and its own
+// event loops. The old process is replaced when it doesn't answer a get-info
+// within nodeAliveTimeout. Control has no way to stop it, so when it is only
+// hung instead of gone it keeps running on the same datadir as the new one.
+func reconnectNode() error {
+	reconnectMutex.Lock()
+	defer reconnectMutex.Unlock()
+
+	// someone else may have fixed it while we were waiting for the lock
+	if nodeAlive() {
+		return nil
+	}
+
+	var err error
+	delay := nodeReconnectDelay
+	for attempt := 1; attempt <= s.NodeReconnectAttempts; attempt++ {
+		var c *cliche.Control
+		if c, err = startNode(); err == nil {
+			setLN(c)
+			go handleClicheEvents(c)
+			log.Info().Int("attempt", attempt).Msg("reconnected to cliche")
+			return nil
+		}
+
+		log.Warn().Err(err).Int("attempt", attempt).Dur("retry", delay).
+			Msg("failed to reconnect to cliche")
+		time.Sleep(delay)
+		delay *= 2
+	}
+
+	log.Error().Err(err).Msg("giving up reconnecting to cliche")
+	return err
+}
+
+// retryAfterReconnect returns true when err was caused by a dropped connection
+// and we managed to get it back, so the caller can repeat the call once.
+// Only use it for idempotent calls, never for paying or creating invoices.
+func retryAfterReconnect(err error) bool {
+	return err != nil && nodeConnectionBroken(err) && reconnectNode() == nil
+}
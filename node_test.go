@@ -0,0 +1,167 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/fiatjaf/go-cliche"
+)
+
+func TestNodeConnectionBroken(t *testing.T) {
+	tests := []struct {
+		err    error
+		broken bool
+	}{
+		{io.EOF, true},
+		{fmt.Errorf("reading: %w", io.EOF), true},
+		{io.ErrClosedPipe, true},
+		{errors.New("error writing json to cliche stdin ('get-info'): write |1: broken pipe"), true},
+		{fmt.Errorf("writing: %w", os.ErrClosed), true},
+		{errors.New("'pay-invoice' error: 'no route'"), false},
+		{errors.New("'check-payment' error: 'not found'"), false},
+	}
+
+	for _, test := range tests {
+		if broken := nodeConnectionBroken(test.err); broken != test.broken {
+			t.Errorf("nodeConnectionBroken(%q) = %v, want %v", test.err, broken, test.broken)
+		}
+	}
+}
+
+// fakeNode replaces the cliche process with a Control that has only its event
+// channels, so handleClicheEvents can subscribe to it.
+func fakeNode() *cliche.Control {
+	return &cliche.Control{
+		PaymentSuccesses: make(chan cliche.PaymentSucceededEvent),
+		PaymentFailures:  make(chan cliche.PaymentFailedEvent),
+		IncomingPayments: make(chan cliche.PaymentReceivedEvent),
+	}
+}
+
+func withFakeNode(t *testing.T, start func() (*cliche.Control, error)) *cliche.Control {
+	dropped := fakeNode()
+	oldLn, oldStart, oldAlive := ln(), startNode, nodeAlive
+	oldDelay, oldAttempts := nodeReconnectDelay, s.NodeReconnectAttempts
+	t.Cleanup(func() {
+		setLN(oldLn)
+		startNode, nodeAlive = oldStart, oldAlive
+		nodeReconnectDelay, s.NodeReconnectAttempts = oldDelay, oldAttempts
+	})
+
+	setLN(dropped)
+	startNode = start
+	nodeAlive = func() bool { return ln() != dropped }
+	nodeReconnectDelay = time.Millisecond
+	s.NodeReconnectAttempts = 3
+	return dropped
+}
+
+func TestReconnectNode(t *testing.T) {
+	fresh := fakeNode()
+	var starts int
+	withFakeNode(t, func() (*cliche.Control, error) {
+		starts++
+		if starts == 1 {
+			return nil, errors.New("java not ready")
+		}
+		return fresh, nil
+	})
+
+	if !retryAfterReconnect(io.ErrClosedPipe) {
+		t.Fatal("didn't reconnect after a dropped connection")
+	}
+	if ln() != fresh {
+		t.Error("ln wasn't replaced by the new Control")
+	}
+	if starts != 2 {
+		t.Errorf("started cliche %d times, want 2", starts)
+	}
+}
+
+func TestReconnectNodeOnce(t *testing.T) {
+	var mu sync.Mutex
+	var starts int
+	withFakeNode(t, func() (*cliche.Control, error) {
+		mu.Lock()
+		defer mu.Unlock()
+		starts++
+		return fakeNode(), nil
+	})
+
+	var wg sync.WaitGroup
+	for i := 0; i < 5; i++ {
+		wg.Add(2)
+		go func() {
+			defer wg.Done()
+			if err := reconnectNode(); err != nil {
+				t.Error(err)
+			}
+		}()
+
+		// payments keep using ln while it is replaced
+		go func() {
+			defer wg.Done()
+			if ln() == nil {
+				t.Error("ln is nil during a reconnection")
+			}
+		}()
+	}
+	wg.Wait()
+
+	if starts != 1 {
+		t.Errorf("concurrent callers started cliche %d times, want 1", starts)
+	}
+}
+
+func TestReconnectNodeGivesUp(t *testing.T) {
+	dropped := withFakeNode(t, func() (*cliche.Control, error) {
+		return nil, errors.New("no java")
+	})
+
+	if retryAfterReconnect(io.EOF) {
+		t.Error("said it reconnected when cliche never started")
+	}
+	if ln() != dropped {
+		t.Error("ln changed even though no cliche started")
+	}
+}
+
+func TestAnswersWithin(t *testing.T) {
+	hung := make(chan struct{})
+	defer close(hung)
+
+	tests := []struct {
+		name    string
+		call    func() error
+		answers bool
+	}{
+		{"answers", func() error { return nil }, true},
+		{"fails", func() error { return io.EOF }, false},
+		{"hangs", func() error { <-hung; return nil }, false},
+	}
+
+	for _, test := range tests {
+		if answers := answersWithin(20*time.Millisecond, test.call); answers != test.answers {
+			t.Errorf("answersWithin when cliche %s = %v, want %v", test.name, answers, test.answers)
+		}
+	}
+}
+
+func TestRetryAfterReconnectOtherErrors(t *testing.T) {
+	withFakeNode(t, func() (*cliche.Control, error) {
+		t.Error("restarted cliche for an ordinary error")
+		return fakeNode(), nil
+	})
+
+	if retryAfterReconnect(errors.New("'check-payment' error: 'not found'")) {
+		t.Error("retried after an ordinary error")
+	}
+	if retryAfterReconnect(nil) {
+		t.Error("retried without an error")
+	}
+}
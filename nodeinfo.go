@@ -0,0 +1,91 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"io/ioutil"
+	"regexp"
+	"strings"
+
+	"github.com/docopt/docopt-go"
+	"github.com/fiatjaf/lntxbot/t"
+	"github.com/tidwall/gjson"
+)
+
+var pubkeyRe = regexp.MustCompile(`^[0-9a-f]{66}$`)
+
+func handleNodeInfo(ctx context.Context, opts docopt.Opts) {
+	u := ctx.Value("initiator").(User)
+
+	pubkey, _ := opts.String("<pubkey>")
+	pubkey = strings.ToLower(strings.TrimSpace(pubkey))
+	if !pubkeyRe.MatchString(pubkey) {
+		send(ctx, u, t.ERROR, t.T{"Err": "invalid node id."})
+		return
+	}
+
+	go u.track("nodeinfo", nil)
+
+	node, err := lnGraphQuery("/nodes?select=alias,color&pubkey=eq." + pubkey)
+	if err != nil {
+		log.Warn().Err(err).Str("node", pubkey).Msg("failed to fetch node info")
+		send(ctx, u, t.ERROR, t.T{"Err": err.Error()})
+		return
+	}
+	if !node.Get("0").Exists() {
+		send(ctx, t.NODENOTSEEN, t.T{"Id": pubkey})
+		return
+	}
+
+	channels, err := lnGraphQuery(fmt.Sprintf(
+		"/channels?select=short_channel_id,satoshis&or=(node0.eq.%s,node1.eq.%s)",
+		pubkey, pubkey))
+	if err != nil {
+		log.Warn().Err(err).Str("node", pubkey).Msg("failed to fetch node channels")
+		send(ctx, u, t.ERROR, t.T{"Err": err.Error()})
+		return
+	}
+
+	var capacity int64
+	scids := make([]string, 0, len(channels.Array()))
+	for _, channel := range channels.Array() {
+		capacity += channel.Get("satoshis").Int()
+		scids = append(scids, channel.Get("short_channel_id").String())
+	}
+
+	// don't list too many channels
+	shown := scids
+	if len(shown) > 20 {
+		shown = shown[:20]
+	}
+
+	send(ctx, t.NODEINFO, t.T{
+		"Id":       pubkey,
+		"Alias":    escapeHTML(stripControlChars(node.Get("0.alias").String())),
+		"Color":    node.Get("0.color").String(),
+		"Channels": len(scids),
+		"Capacity": capacity,
+		"Scids":    strings.Join(shown, " "),
+		"More":     len(scids) > len(shown),
+	})
+}
+
+// lnGraphQuery fetches data from the same ln.fiatjaf.com API used on getNodeAlias
+func lnGraphQuery(path string) (gjson.Result, error) {
+	resp, err := explorerClient.Get("https://ln.fiatjaf.com" + path)
+	if err != nil {
+		return gjson.Result{}, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode >= 300 {
+		return gjson.Result{}, fmt.Errorf("node explorer returned status %d", resp.StatusCode)
+	}
+
+	b, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return gjson.Result{}, err
+	}
+
+	return gjson.ParseBytes(b), nil
+}
@@ -8,13 +8,16 @@ import (
 	"fmt"
 	"strconv"
 	"strings"
+	"sync"
 	"time"
 
 	"github.com/bwmarrin/discordgo"
 	"github.com/docopt/docopt-go"
+	"github.com/fiatjaf/go-cliche"
 	decodepay "github.com/fiatjaf/ln-decodepay"
 	"github.com/fiatjaf/lntxbot/t"
 	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api"
+	lru "github.com/hashicorp/golang-lru"
 )
 
 func handlePay(ctx context.Context, payer User, opts docopt.Opts) error {
@@ -26,16 +29,59 @@ func handlePay(ctx context.Context, payer User, opts docopt.Opts) error {
 
 	bolt11, _ := opts.String("<invoice>")
 
+	// a fee limit for this payment only, overriding the user default
+	rawFeeLimit, _ := opts.String("--max-fee")
+	feeLimit, err := parseFeeLimit(rawFeeLimit)
+	if err != nil {
+		send(ctx, payer, t.ERROR, t.T{"Err": messageFromError(ctx, err)})
+		return err
+	}
+
+	if err := checkInvoiceNetwork(bolt11); err != nil {
+		send(ctx, payer, t.ERROR, t.T{"Err": messageFromError(ctx, err)})
+		return err
+	}
+
 	// decode invoice
-	inv, err := decodepay.Decodepay(bolt11)
+	inv, err := decodeInvoice(bolt11)
 	if err != nil {
-		send(ctx, payer, t.FAILEDDECODE, t.T{"Err": err.Error()})
+		send(ctx, payer, t.FAILEDDECODE, t.T{"Err": messageFromError(ctx, ErrInvalidInvoice.withDetail(err))})
+		return err
+	}
+
+	// no need to go further with an invoice that can't be paid anymore
+	if err := checkInvoiceExpiry(inv); err != nil {
+		send(ctx, payer, t.ERROR, t.T{"Err": messageFromError(ctx, err)})
 		return err
 	}
 
 	hash := inv.PaymentHash
 	amount := float64(inv.MSatoshi)
 
+	// amountless invoices need an amount from the user, either given on the
+	// command or replied to the prompt
+	var amountToPay int64
+	if inv.MSatoshi == 0 {
+		if _, given := opts["<satoshis>"].(string); given {
+			amountToPay, err = parseSatoshis(ctx, opts)
+			if err != nil {
+				send(ctx, payer, t.ERROR, t.T{"Err": err.Error()})
+				return err
+			}
+
+			// typing the amount is already a confirmation
+			askConfirmation = false
+		} else {
+			askConfirmation = true
+		}
+	}
+
+	// small payments may not need a confirmation
+	if askConfirmation && inv.MSatoshi > 0 &&
+		inv.MSatoshi <= payer.getPayConfirmThreshold()*1000 {
+		askConfirmation = false
+	}
+
 	go payer.track("pay", map[string]interface{}{
 		"prompt":     askConfirmation,
 		"sats":       amount,
@@ -44,23 +90,7 @@ func handlePay(ctx context.Context, payer User, opts docopt.Opts) error {
 
 	if askConfirmation {
 		// show a button for confirmation
-		payTmplParams := t.T{
-			"Sats":            amount / 1000,
-			"Description":     escapeHTML(inv.Description),
-			"DescriptionHash": escapeHTML(inv.DescriptionHash),
-			"Hash":            hash,
-			"ReceiverName":    extractNameFromDesc(inv.Description),
-			"Payee":           inv.Payee,
-			"Created": time.Unix(int64(inv.CreatedAt), 0).
-				Format("Mon Jan 2 15:04"),
-			"Expiry": time.Unix(int64(inv.CreatedAt+inv.Expiry), 0).
-				Format("Mon Jan 2 15:04"),
-			"Expired": time.Unix(int64(inv.CreatedAt+inv.Expiry), 0).
-				Before(time.Now()),
-			"Currency":  inv.Currency,
-			"Hints":     inv.Route,
-			"IsDiscord": ctx.Value("origin").(string) == "discord",
-		}
+		payTmplParams := invoiceDetails(ctx, inv)
 
 		if ctx.Value("origin").(string) == "discord" {
 			if amount == 0 {
@@ -94,14 +124,18 @@ func handlePay(ctx context.Context, payer User, opts docopt.Opts) error {
 			data, _ := json.Marshal(struct {
 				Type   string `json:"type"`
 				Bolt11 string `json:"bolt11"`
-			}{"pay", bolt11})
-			rds.Set(fmt.Sprintf("reply:%d:%d", payer.Id, sentId), data, time.Minute*15)
+				MaxFee string `json:"maxfee,omitempty"`
+			}{"pay", bolt11, rawFeeLimit})
+			rds.Set(fmt.Sprintf("reply:%d:%d", payer.Id, sentId), data, s.ReplyPromptTimeout)
 			return nil
 		}
 
 		// normal invoice, ask for confirmation
 		hashfirstchars := hash[:5]
 		rds.Set("payinvoice:"+hashfirstchars, bolt11, s.PayConfirmTimeout)
+		if rawFeeLimit != "" {
+			rds.Set("payinvoice-maxfee:"+hashfirstchars, rawFeeLimit, s.PayConfirmTimeout)
+		}
 		keyboard := tgbotapi.NewInlineKeyboardMarkup(
 			tgbotapi.NewInlineKeyboardRow(
 				tgbotapi.NewInlineKeyboardButtonData(
@@ -118,13 +152,10 @@ func handlePay(ctx context.Context, payer User, opts docopt.Opts) error {
 		// send an "attempting" message
 		send(ctx, t.CALLBACKATTEMPT, t.T{"Hash": hash[:5]}, ctx.Value("message"))
 
-		// parse manually specified satoshis if any
-		amountToPay, _ := opts.Int("<satoshis>")
-
 		// proceed to pay
-		_, err := payer.payInvoice(ctx, bolt11, int64(amountToPay)*1000)
+		_, err := payer.payInvoice(ctx, bolt11, amountToPay, feeLimit)
 		if err != nil {
-			send(ctx, payer, t.ERROR, t.T{"Err": err.Error()}, ctx.Value("message"))
+			send(ctx, payer, t.ERROR, t.T{"Err": messageFromError(ctx, err)}, ctx.Value("message"))
 			return err
 		}
 	}
@@ -136,7 +167,7 @@ func handlePayReactionConfirm(ctx context.Context, reaction *discordgo.MessageRe
 	key := fmt.Sprintf("reaction-confirm:%s:%s", reaction.UserID, reaction.MessageID)
 	bolt11, err := rds.Get(key).Result()
 	if err != nil {
-		log.Warn().Err(err).Str("key", key).Msg("couldn't load payment details")
+		logger(ctx).Warn().Err(err).Str("key", key).Msg("couldn't load payment details")
 		return
 	}
 
@@ -144,26 +175,88 @@ func handlePayReactionConfirm(ctx context.Context, reaction *discordgo.MessageRe
 	// and it comes from the correct user
 	u, err := loadDiscordUser(reaction.UserID)
 	if err != nil {
-		log.Warn().Err(err).Str("id", reaction.UserID).
+		logger(ctx).Warn().Err(err).Str("id", reaction.UserID).
 			Msg("failed to load discord user")
 		return
 	}
 
 	messageRef := discordIDFromReaction(reaction)
 
-	_, err = u.payInvoice(ctx, bolt11, 0)
+	_, err = u.payInvoice(ctx, bolt11, 0, nil)
 	if err == nil {
-		inv, _ := decodepay.Decodepay(bolt11)
+		inv, _ := decodeInvoice(bolt11)
 		hashfirstchars := inv.PaymentHash[0:5]
 
 		send(ctx, messageRef, t.CALLBACKATTEMPT, t.T{"Hash": hashfirstchars})
 		send(ctx, messageRef, "✅")
 	} else {
-		send(ctx, messageRef, t.ERROR, t.T{"Err": err.Error()})
+		send(ctx, messageRef, t.ERROR, t.T{"Err": messageFromError(ctx, err)})
 		send(ctx, messageRef, "❌")
 	}
 }
 
+func handlePayChoose(ctx context.Context, payer User, bolt11s []string) {
+	// more than one invoice in the same message, ask which one should be paid
+	rows := make([][]tgbotapi.InlineKeyboardButton, 0, len(bolt11s)+1)
+	for _, bolt11 := range bolt11s {
+		inv, err := decodeInvoice(bolt11)
+		if err != nil {
+			continue
+		}
+
+		hashfirstchars := inv.PaymentHash[:5]
+		rds.Set("payinvoice:"+hashfirstchars, bolt11, s.PayConfirmTimeout)
+
+		description := []rune(inv.Description)
+		if len(description) > 30 {
+			description = append(description[:29], '…')
+		}
+		label := translateTemplate(ctx, t.PAYCHOOSEBUTTON, t.T{
+			"Sats":        float64(inv.MSatoshi) / 1000,
+			"Description": string(description),
+		})
+		rows = append(rows, tgbotapi.NewInlineKeyboardRow(
+			tgbotapi.NewInlineKeyboardButtonData(
+				label, fmt.Sprintf("choosepay=%s", hashfirstchars)),
+		))
+	}
+
+	if len(rows) == 0 {
+		send(ctx, payer, t.FAILEDDECODE, t.T{"Err": "no valid invoices found."})
+		return
+	}
+
+	rows = append(rows, tgbotapi.NewInlineKeyboardRow(
+		tgbotapi.NewInlineKeyboardButtonData(
+			translate(ctx, t.CANCEL),
+			fmt.Sprintf("cancel=%d", payer.Id)),
+	))
+
+	go payer.track("pay choose", map[string]interface{}{"n": len(rows) - 1})
+
+	keyboard := tgbotapi.InlineKeyboardMarkup{InlineKeyboard: rows}
+	send(ctx, t.PAYCHOOSE, t.T{"N": len(rows) - 1}, ctx.Value("message"), &keyboard)
+}
+
+func handlePayChooseCallback(ctx context.Context) {
+	u := ctx.Value("initiator").(User)
+
+	defer removeKeyboardButtons(ctx)
+	hashfirstchars := ctx.Value("callbackQuery").(*tgbotapi.CallbackQuery).Data[10:]
+	bolt11, err := rds.Get("payinvoice:" + hashfirstchars).Result()
+	if err != nil {
+		send(ctx, t.CALLBACKEXPIRED)
+		return
+	}
+
+	opts, _, err := parse("/pay " + bolt11)
+	if err != nil {
+		return
+	}
+
+	handlePay(ctx, u, opts)
+}
+
 func handlePayCallback(ctx context.Context) {
 	u := ctx.Value("initiator").(User)
 
@@ -177,7 +270,10 @@ func handlePayCallback(ctx context.Context) {
 
 	send(ctx, t.CALLBACKSENDING)
 
-	_, err = u.payInvoice(ctx, bolt11, 0)
+	rawFeeLimit, _ := rds.Get("payinvoice-maxfee:" + hashfirstchars).Result()
+	feeLimit, _ := parseFeeLimit(rawFeeLimit)
+
+	_, err = u.payInvoice(ctx, bolt11, 0, feeLimit)
 	if err == nil {
 		send(ctx, t.CALLBACKATTEMPT, t.T{"Hash": hashfirstchars}, APPEND)
 	} else {
@@ -192,12 +288,14 @@ func handlePayVariableAmount(ctx context.Context, msatoshi int64, raw string) {
 
 	var data struct {
 		Invoice string `json:"bolt11"`
+		MaxFee  string `json:"maxfee"`
 	}
 	json.Unmarshal([]byte(raw), &data)
+	feeLimit, _ := parseFeeLimit(data.MaxFee)
 
-	_, err := u.payInvoice(ctx, data.Invoice, msatoshi)
+	_, err := u.payInvoice(ctx, data.Invoice, msatoshi, feeLimit)
 	if err != nil {
-		send(ctx, u, t.ERROR, t.T{"Err": err.Error()}, ctx.Value("message"))
+		send(ctx, u, t.ERROR, t.T{"Err": messageFromError(ctx, err)}, ctx.Value("message"))
 		return
 	}
 
@@ -207,8 +305,102 @@ func handlePayVariableAmount(ctx context.Context, msatoshi int64, raw string) {
 	})
 }
 
+var (
+	errPaymentFailed       = errors.New("payment failed")
+	errPaymentStillPending = errors.New("payment still pending")
+
+	// guards the removal of single channels from waitingPaymentSuccesses
+	waitingPaymentSuccessesMutex sync.Mutex
+)
+
+// invoiceDetails are the fields shown for an invoice on PAYPROMPT and
+// DECODEINVOICE.
+func invoiceDetails(ctx context.Context, inv decodepay.Bolt11) t.T {
+	return t.T{
+		"Sats":            float64(inv.MSatoshi) / 1000,
+		"Description":     escapeHTML(inv.Description),
+		"DescriptionHash": escapeHTML(inv.DescriptionHash),
+		"Hash":            inv.PaymentHash,
+		"ReceiverName":    extractNameFromDesc(inv.Description),
+		"Payee":           inv.Payee,
+		"Created": time.Unix(int64(inv.CreatedAt), 0).
+			Format("Mon Jan 2 15:04"),
+		"Expiry": time.Unix(int64(inv.CreatedAt+inv.Expiry), 0).
+			Format("Mon Jan 2 15:04"),
+		"Expired": time.Unix(int64(inv.CreatedAt+inv.Expiry), 0).
+			Before(time.Now()),
+		"Currency":  inv.Currency,
+		"Hints":     inv.Route,
+		"IsDiscord": ctx.Value("origin").(string) == "discord",
+	}
+}
+
+var decodedInvoices, _ = lru.New(512)
+
+// decodeInvoice is decodepay.Decodepay with a cache, as the same invoice is
+// often decoded many times along the payment flow.
+func decodeInvoice(bolt11 string) (decodepay.Bolt11, error) {
+	if inv, ok := decodedInvoices.Get(bolt11); ok {
+		return inv.(decodepay.Bolt11), nil
+	}
+
+	inv, err := decodepay.Decodepay(bolt11)
+	if err == nil {
+		decodedInvoices.Add(bolt11, inv)
+	}
+	return inv, err
+}
+
+// invoiceNetworks maps bolt11 prefixes to the network they belong to, longest
+// prefixes first so lntbs isn't taken for lntb.
+var invoiceNetworks = []struct{ prefix, network string }{
+	{"lnbcrt", "regtest"},
+	{"lntbs", "signet"},
+	{"lntb", "testnet"},
+	{"lnsb", "simnet"},
+	{"lnbc", "mainnet"},
+}
+
+func invoiceNetwork(bolt11 string) string {
+	bolt11 = strings.ToLower(bolt11)
+	for _, n := range invoiceNetworks {
+		if strings.HasPrefix(bolt11, n.prefix) {
+			return n.network
+		}
+	}
+	return ""
+}
+
+// checkInvoiceNetwork returns ErrWrongNetwork when the invoice is for a different
+// chain than the one our node is on, before we try to decode or pay it.
+func checkInvoiceNetwork(bolt11 string) error {
+	network := invoiceNetwork(bolt11)
+	if network == "" || network == s.Network {
+		return nil
+	}
+	return ErrWrongNetwork.withData(t.T{"Invoice": network, "Bot": s.Network})
+}
+
+// checkInvoiceExpiry returns ErrInvoiceExpired, saying how long ago, if the
+// invoice has expired.
+func checkInvoiceExpiry(inv decodepay.Bolt11) error {
+	expiresAt := time.Unix(int64(inv.CreatedAt+inv.Expiry), 0)
+	if expiresAt.After(time.Now()) {
+		return nil
+	}
+	return ErrInvoiceExpired.withData(t.T{
+		"Minutes": int(time.Since(expiresAt).Minutes()),
+	})
+}
+
+// waitPaymentSuccess returns a channel that gets the preimage when the payment
+// succeeds or an empty string when it fails. It may block forever, prefer
+// waitPaymentResult.
 func waitPaymentSuccess(hash string) (preimage <-chan string) {
-	wait := make(chan string)
+	waitingPaymentSuccessesMutex.Lock()
+	defer waitingPaymentSuccessesMutex.Unlock()
+
+	wait := make(chan string, 1)
 	waitingPaymentSuccesses.Upsert(hash, wait,
 		func(exists bool, arr interface{}, v interface{}) interface{} {
 			if exists {
@@ -221,7 +413,55 @@ func waitPaymentSuccess(hash string) (preimage <-chan string) {
 	return wait
 }
 
+// waitPaymentResult waits for a payment until ctx is done, in which case it
+// returns errPaymentStillPending and stops listening for that payment.
+func waitPaymentResult(ctx context.Context, hash string) (preimage string, err error) {
+	return awaitPaymentResult(ctx, hash, waitPaymentSuccess(hash))
+}
+
+// awaitPaymentResult is waitPaymentResult on a channel from waitPaymentSuccess
+// that was registered earlier, as it must be before paying: internal payments
+// succeed before payInvoice returns.
+func awaitPaymentResult(ctx context.Context, hash string, wait <-chan string) (preimage string, err error) {
+	select {
+	case preimage = <-wait:
+		if preimage == "" {
+			return "", errPaymentFailed
+		}
+		return preimage, nil
+	case <-ctx.Done():
+		removeWaitingPaymentSuccess(hash, wait)
+		return "", errPaymentStillPending
+	}
+}
+
+func removeWaitingPaymentSuccess(hash string, wait <-chan string) {
+	waitingPaymentSuccessesMutex.Lock()
+	defer waitingPaymentSuccessesMutex.Unlock()
+
+	chans, ok := waitingPaymentSuccesses.Get(hash)
+	if !ok {
+		return
+	}
+
+	remaining := make([]interface{}, 0, len(chans.([]interface{})))
+	for _, ch := range chans.([]interface{}) {
+		if ch.(chan string) != wait {
+			remaining = append(remaining, ch)
+		}
+	}
+
+	if len(remaining) == 0 {
+		waitingPaymentSuccesses.Remove(hash)
+	} else {
+		waitingPaymentSuccesses.Set(hash, remaining)
+	}
+}
+
 func resolveWaitingPaymentSuccess(hash string, preimage string) {
+	waitingPaymentSuccessesMutex.Lock()
+	defer waitingPaymentSuccessesMutex.Unlock()
+
 	if chans, ok := waitingPaymentSuccesses.Get(hash); ok {
 		for _, ch := range chans.([]interface{}) {
 			select {
@@ -243,9 +483,8 @@ func paymentHasSucceeded(
 ) {
 	// if it succeeds we mark the transaction as not pending anymore
 	// plus save fees and preimage
-	if feesPaid < int64(float64(msatoshi)*0.003) {
-		feesPaid = int64(float64(msatoshi) * 0.003)
-	}
+	attempt := loadPaymentAttempt(hash)
+	feesPaid = chargedFee(msatoshi, feesPaid, attempt.MaxFee)
 
 	// if there's a tag we save that too, otherwise leave it null
 	tagn := sql.NullString{String: tag, Valid: tag != ""}
@@ -261,16 +500,20 @@ WHERE payment_hash = $3 AND pending
 RETURNING from_id, trigger_message
     `, feesPaid, preimage, hash, tagn)
 	if err != nil {
-		log.Error().Err(err).Str("hash", hash).
+		logger(ctx).Error().Err(err).Str("hash", hash).
 			Int64("fees", feesPaid).Msg("failed to update transaction paid status")
 		return
 	}
 
 	go resolveWaitingPaymentSuccess(hash, preimage)
+	metricPaymentSent(msatoshi)
+
+	attempts := attempt.Attempts
+	rds.Del("payattempt:" + hash)
 
 	user, err := loadUser(res.UserId)
 	if err != nil {
-		log.Error().Err(err).Int("id", res.UserId).Msg("no user with id on pay success")
+		logger(ctx).Error().Err(err).Int("id", res.UserId).Msg("no user with id on pay success")
 		return
 	}
 
@@ -284,10 +527,17 @@ RETURNING from_id, trigger_message
 		"Hash":      hash,
 		"Preimage":  preimage,
 		"ShortHash": hash[:5],
+		"Attempts":  attempts,
 	}, ctx.Value("message"))
 }
 
 func paymentHasFailed(ctx context.Context, hash string, failures []string) {
+	if retryPayment(ctx, hash, failures) {
+		return
+	}
+	attempts := loadPaymentAttempt(hash).Attempts
+	rds.Del("payattempt:" + hash)
+
 	var res struct {
 		UserId         int `db:"from_id"`
 		TriggerMessage int `db:"trigger_message"`
@@ -298,33 +548,155 @@ WHERE payment_hash = $1 AND to_id IS NULL
 RETURNING from_id, trigger_message
     `, hash)
 	if err != nil {
-		log.Error().Err(err).Str("hash", hash).
+		logger(ctx).Error().Err(err).Str("hash", hash).
 			Msg("failed to cancel transaction after routing failure")
 		return
 	}
 
 	rds.Set("hash:"+strconv.Itoa(res.UserId)+":"+hash[0:5], hash, time.Hour*24*2)
 
+	// an empty preimage tells whoever is waiting that the payment has failed
+	go resolveWaitingPaymentSuccess(hash, "")
+	metricPaymentFailed(lightningNodeError(errors.New(strings.Join(failures, "\n"))))
+
 	user, err := loadUser(res.UserId)
 	if err != nil {
-		log.Error().Err(err).Str("hash", hash).Int("id", res.UserId).
+		logger(ctx).Error().Err(err).Str("hash", hash).Int("id", res.UserId).
 			Msg("failed to load user after routing failure")
 		return
 	}
 
-	send(ctx, user, res.TriggerMessage,
-		t.PAYMENTFAILED, t.T{"FailureString": strings.Join(failures, "\n")},
-		ctx.Value("message"))
+	send(ctx, user, res.TriggerMessage, t.PAYMENTFAILED, t.T{
+		"FailureString": strings.Join(failures, "\n"),
+		"Attempts":      attempts,
+	}, ctx.Value("message"))
+}
+
+// paymentAttempt is kept while an external payment is in flight so it can be
+// retried after temporary failures.
+type paymentAttempt struct {
+	Bolt11   string `json:"bolt11"`
+	Msatoshi int64  `json:"msatoshi"`
+	Attempts int    `json:"attempts"`
+	MaxFee   *int64 `json:"maxfee,omitempty"` // from the user fee limit
+}
+
+func savePaymentAttempt(hash string, attempt paymentAttempt) {
+	b, _ := json.Marshal(attempt)
+	rds.Set("payattempt:"+hash, b, time.Hour*24)
+}
+
+func loadPaymentAttempt(hash string) (attempt paymentAttempt) {
+	b, err := rds.Get("payattempt:" + hash).Bytes()
+	if err == nil {
+		json.Unmarshal(b, &attempt)
+	}
+	return
+}
+
+// retryPayment pays again if the failure looks temporary (no route, timeouts)
+// and there are attempts left, returning true if it did. The node picks the
+// route on each attempt, so a retry may go through a different path.
+func retryPayment(ctx context.Context, hash string, failures []string) bool {
+	attempt := loadPaymentAttempt(hash)
+	if attempt.Bolt11 == "" || attempt.Attempts >= s.PaymentMaxAttempts {
+		return false
+	}
+
+	failure := strings.ToLower(strings.Join(failures, "; "))
+	if !errors.Is(lightningNodeError(errors.New(failure)), ErrNoRoute) &&
+		!strings.Contains(failure, "temporary") &&
+		!strings.Contains(failure, "timeout") {
+		return false
+	}
+
+	if inv, err := decodeInvoice(attempt.Bolt11); err != nil ||
+		checkInvoiceExpiry(inv) != nil {
+		return false
+	}
+
+	attempt.Attempts++
+	savePaymentAttempt(hash, attempt)
+	logger(ctx).Info().Str("hash", hash).Int("attempt", attempt.Attempts).
+		Strs("failures", failures).Msg("retrying payment")
+
+	paymentWatchers.Add(1)
+	go func() {
+		defer paymentWatchers.Done()
+		_, err := ln().PayInvoice(cliche.PayInvoiceParams{
+			Invoice:  attempt.Bolt11,
+			Msatoshi: attempt.Msatoshi,
+		})
+		if err != nil {
+			paymentHasFailed(ctx, hash, []string{err.Error()})
+		}
+	}()
+
+	return true
+}
+
+const (
+	paymentProgressInterval = 15 * time.Second
+	paymentProgressMax      = 10 * time.Minute
+)
+
+// reportPaymentProgress shows a message on telegram while a payment is still
+// pending, so long payments don't look stuck. It's removed when the payment
+// resolves, as the result is notified by paymentHasSucceeded/paymentHasFailed.
+func reportPaymentProgress(ctx context.Context, u User, hash string, msatoshi int64) {
+	if origin, _ := ctx.Value("origin").(string); origin != "telegram" {
+		return
+	}
+
+	var progressMessageId interface{}
+	start := time.Now()
+	for time.Since(start) < paymentProgressMax {
+		time.Sleep(paymentProgressInterval)
+
+		info, err := ln().CheckPayment(hash)
+		if retryAfterReconnect(err) {
+			info, err = ln().CheckPayment(hash)
+		}
+		if err != nil {
+			logger(ctx).Warn().Err(err).Str("hash", hash).
+				Msg("failed to check payment progress")
+			break
+		}
+		if info.Status != "pending" {
+			break
+		}
+
+		params := t.T{
+			"Sats":    float64(msatoshi) / 1000,
+			"Hash":    hash[:5],
+			"Seconds": int(time.Since(start).Seconds()),
+		}
+		if progressMessageId == nil {
+			progressMessageId = send(ctx, u, t.PAYMENTPROGRESS, params)
+		} else {
+			send(ctx, u, EDIT, progressMessageId, t.PAYMENTPROGRESS, params)
+		}
+	}
+
+	if id, ok := progressMessageId.(int); ok {
+		deleteMessage(&tgbotapi.Message{
+			Chat:      &tgbotapi.Chat{ID: u.TelegramChatId},
+			MessageID: id,
+		})
+	}
 }
 
 func checkOutgoingPayment(ctx context.Context, hash string) {
-	info, err := ln.CheckPayment(hash)
+	info, err := ln().CheckPayment(hash)
+	if retryAfterReconnect(err) {
+		info, err = ln().CheckPayment(hash)
+	}
 	if err != nil {
-		log.Error().Err(err).Str("hash", hash).Msg("failed to check-payment")
+		logger(ctx).Error().Err(err).Str("hash", hash).Msg("failed to check-payment")
 		return
 	}
 	if info.IsIncoming {
-		log.Error().Err(err).Str("hash", hash).
+		logger(ctx).Error().Err(err).Str("hash", hash).
 			Msg("tried to check outgoing with an incoming invoice")
 		return
 	}
@@ -347,12 +719,12 @@ func checkOutgoingPayment(ctx context.Context, hash string) {
 		if err == nil &&
 			t.Before(time.Now().Add(-2*time.Hour)) &&
 			t.After(time.Now().AddDate(0, -3, 0)) {
-			log.Warn().Str("hash", hash).Time("time", t).
+			logger(ctx).Warn().Str("hash", hash).Time("time", t).
 				Msg("tx in the range of acceptable cancellation on getsentinfo []")
 
 			go paymentHasFailed(ctx, hash, []string{})
 		} else {
-			log.Warn().Str("hash", hash).Err(err).Time("time", t).
+			logger(ctx).Warn().Str("hash", hash).Err(err).Time("time", t).
 				Msg("check-invoice says it's failed, but we can't cancel this transaction because it's too new")
 		}
 	}
@@ -0,0 +1,87 @@
+package main
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	decodepay "github.com/fiatjaf/ln-decodepay"
+)
+
+// internal payments succeed before payInvoice returns, so a waiter registered
+// only after paying never hears about them.
+func TestAwaitPaymentResultRegisteredBeforePaying(t *testing.T) {
+	hash := "waitbeforepaying"
+
+	wait := waitPaymentSuccess(hash)
+	resolveWaitingPaymentSuccess(hash, "preimage") // what an internal payInvoice does
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	preimage, err := awaitPaymentResult(ctx, hash, wait)
+	if err != nil || preimage != "preimage" {
+		t.Errorf("awaitPaymentResult = %q, %v, want the preimage", preimage, err)
+	}
+
+	ctx, cancel = context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	if _, err := waitPaymentResult(ctx, hash); err != errPaymentStillPending {
+		t.Errorf("waiting after the payment resolved = %v, want %v",
+			err, errPaymentStillPending)
+	}
+}
+
+func TestAwaitPaymentResultFailure(t *testing.T) {
+	hash := "waitfailure"
+
+	wait := waitPaymentSuccess(hash)
+	resolveWaitingPaymentSuccess(hash, "")
+
+	if _, err := awaitPaymentResult(context.Background(), hash, wait); err != errPaymentFailed {
+		t.Errorf("awaitPaymentResult on a failure = %v, want %v", err, errPaymentFailed)
+	}
+}
+
+func TestRemoveWaitingPaymentSuccess(t *testing.T) {
+	hash := "waitremoved"
+
+	first := waitPaymentSuccess(hash)
+	second := waitPaymentSuccess(hash)
+	removeWaitingPaymentSuccess(hash, first)
+	resolveWaitingPaymentSuccess(hash, "preimage")
+
+	select {
+	case <-first:
+		t.Error("a removed waiter was resolved")
+	default:
+	}
+	if preimage := <-second; preimage != "preimage" {
+		t.Errorf("remaining waiter got %q, want the preimage", preimage)
+	}
+	if _, ok := waitingPaymentSuccesses.Get(hash); ok {
+		t.Error("waiters weren't cleaned up after resolving")
+	}
+}
+
+// the donation example from BOLT-11.
+const testBolt11 = "lnbc1pvjluezpp5qqqsyqcyq5rqwzqfqqqsyqcyq5rqwzqfqqqsyqcyq5rqwzqfqypqdpl2pkx2ctnv5sxxmmwwd5kgetjypeh2ursdae8g6twvus8g6rfwvs8qun0dfjkxaq8rkx3yf5tcsyz3d73gafnh3cax9rn449d9p5uxz9ezhhypd0elx87sjle52x86fux2ypatgddc6k63n7erqz25le42c4u4ecky03ylcqca784w"
+
+// the same invoice is decoded again on every step of the payment flow, and
+// when users paste it more than once.
+func BenchmarkDecodeInvoiceRepeated(b *testing.B) {
+	if _, err := decodeInvoice(testBolt11); err != nil {
+		b.Fatal(err)
+	}
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		decodeInvoice(testBolt11)
+	}
+}
+
+// what every step cost before the cache.
+func BenchmarkDecodeInvoiceUncached(b *testing.B) {
+	for i := 0; i < b.N; i++ {
+		decodepay.Decodepay(testBolt11)
+	}
+}
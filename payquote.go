@@ -0,0 +1,82 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/docopt/docopt-go"
+	"github.com/fiatjaf/lntxbot/t"
+)
+
+// feeReserve is the maximum fee we reserve from the user balance when sending
+// an external payment. The unused part is given back after it succeeds.
+func feeReserve(msatoshi int64) int64 {
+	reserve := float64(msatoshi) * 0.005
+	if msatoshi < 1000000 {
+		reserve += 5000 // account for exemptfee
+	}
+	return int64(reserve)
+}
+
+func handlePayQuote(ctx context.Context, opts docopt.Opts) {
+	u := ctx.Value("initiator").(User)
+
+	bolt11, _ := opts.String("<invoice>")
+	bolt11 = strings.ToLower(trimLightningScheme(bolt11))
+	inv, err := decodeInvoice(bolt11)
+	if err != nil {
+		send(ctx, u, t.ERROR, t.T{"Err": "Failed to decode invoice: " + err.Error()})
+		return
+	}
+
+	amount := inv.MSatoshi
+	if amount == 0 {
+		amount, err = parseSatoshis(ctx, opts)
+		if err != nil {
+			send(ctx, u, t.ERROR, t.T{"Err": "Invoice has no amount, please specify one."})
+			return
+		}
+	}
+
+	go u.track("payquote", map[string]interface{}{"sats": amount / 1000})
+
+	params := t.T{
+		"Sats":      float64(amount) / 1000,
+		"Payee":     inv.Payee,
+		"Internal":  inv.Payee == s.NodeId,
+		"Reachable": true,
+	}
+
+	limit := u.getFeeLimit()
+
+	// cliche has no command to query a route, it only finds one when paying,
+	// so the quote can't tell the actual fee or the number of hops. it shows
+	// the most the payment can cost instead, which is what is held from the
+	// balance while paying.
+	var reserve int64
+	if inv.Payee != s.NodeId {
+		reserve = heldFee(limit, amount)
+
+		// nodes without public channels can only be reached through the route
+		// hints in the invoice, so we can't tell if there is a path to them
+		channels, err := lnGraphQuery(fmt.Sprintf(
+			"/channels?select=short_channel_id&limit=1&or=(node0.eq.%s,node1.eq.%s)",
+			inv.Payee, inv.Payee))
+		if err != nil {
+			log.Warn().Err(err).Str("node", inv.Payee).
+				Msg("failed to query payee channels on payquote")
+		} else {
+			params["Reachable"] = channels.Get("0").Exists()
+		}
+	}
+	params["Fee"] = float64(reserve) / 1000
+	params["Total"] = float64(amount+reserve) / 1000
+
+	if limit != nil {
+		params["Limit"] = limit.String()
+		params["AboveLimit"] = checkFeeLimit(limit, amount) != nil
+	}
+
+	send(ctx, u, t.PAYQUOTE, params)
+}
@@ -14,6 +14,21 @@ CREATE TABLE account (
   password text NOT NULL DEFAULT md5(random()::text) || md5(random()::text), -- used in lndhub interface
   locale text NOT NULL DEFAULT 'en', -- default language for messages
   manual_locale boolean NOT NULL DEFAULT false,
+  currency text NOT NULL DEFAULT 'USD', -- fiat currency used when displaying amounts
+  max_fee text NOT NULL DEFAULT '', -- maximum routing fee, in sat or as a percentage like '1%'
+  lightning_alias text UNIQUE, -- chosen name for the lightning address, besides the telegram username
+  pay_confirm_threshold int NOT NULL DEFAULT 0, -- in sat, payments up to this don't ask for confirmation
+  invoice_description text NOT NULL DEFAULT '', -- template for invoices made without a description, '' means INVOICE_DESCRIPTION
+  spend_alert_threshold int NOT NULL DEFAULT 0, -- in sat, payments above this send an extra alert, 0 disables
+  totp_secret text, -- base32, payments above totp_threshold need a code when set
+  totp_threshold int NOT NULL DEFAULT 0, -- in sat
+  frozen boolean NOT NULL DEFAULT false, -- set with /freeze, blocks all outgoing payments
+  webhook text NOT NULL DEFAULT '', -- called on every payment received
+  skip_qr boolean NOT NULL DEFAULT false, -- send invoices as text only, without the QR image
+  onboarded boolean NOT NULL DEFAULT false, -- whether the first-run instructions were shown
+  roman boolean NOT NULL DEFAULT false, -- show counters in roman numerals, just for fun
+  invoice_min_sat bigint, -- overrides INVOICE_MIN_SATS for this user, 0 means no limit
+  invoice_max_sat bigint, -- overrides INVOICE_MAX_SATS for this user, 0 means no limit
   appdata jsonb NOT NULL DEFAULT '{}' -- data for all apps this user have, as a map of {"appname": {anything}}
 );
 
@@ -25,6 +40,28 @@ CREATE TABLE balance_check (
   PRIMARY KEY(service, account)
 );
 
+CREATE TABLE price_alert (
+  id serial PRIMARY KEY,
+  account int REFERENCES account (id),
+  currency text NOT NULL,
+  direction text NOT NULL, -- 'above' or 'below'
+  price numeric(20, 2) NOT NULL, -- in units of currency per bitcoin
+
+  UNIQUE (account, currency, direction, price)
+);
+
+CREATE TABLE scheduled_payment (
+  id serial PRIMARY KEY,
+  account int NOT NULL REFERENCES account (id),
+  receiver int REFERENCES account (id), -- for internal transfers
+  address text, -- a lightning address, when not an internal transfer
+  msatoshi bigint NOT NULL,
+  period text NOT NULL, -- 'day', 'week' or 'month'
+  next_run timestamptz NOT NULL,
+
+  CHECK ((receiver IS NULL) != (address IS NULL))
+);
+
 CREATE TABLE groupchat (
   telegram_id bigint UNIQUE,
   discord_guild_id TEXT UNIQUE,
@@ -35,6 +72,9 @@ CREATE TABLE groupchat (
   coinflips bool NOT NULL DEFAULT true,
   expensive_price int NOT NULL DEFAULT 0,
   expensive_pattern text NOT NULL DEFAULT '',
+  menu jsonb NOT NULL DEFAULT '{}', -- custom menu items as {"name": satoshis}
+  tip_limit int NOT NULL DEFAULT 0, -- maximum sat for tips and giveaways, 0 means no limit
+  treasury boolean NOT NULL DEFAULT false -- admins spend from the group account
 );
 
 CREATE TABLE lightning.transaction (
@@ -52,7 +92,9 @@ CREATE TABLE lightning.transaction (
   remote_node text,
   anonymous boolean NOT NULL DEFAULT false,
   tag text,
-  proxied_with text -- the transaction related to this if used the proxy account
+  proxied_with text, -- the transaction related to this if used the proxy account
+  fiat_currency text, -- the currency of whoever made the transaction
+  msat_per_fiat bigint -- the exchange rate to fiat_currency at the time, null on older transactions
 );
 
 CREATE INDEX ON lightning.transaction (from_id);
@@ -69,7 +111,8 @@ CREATE VIEW lightning.account_txn AS
       THEN coalesce(t.telegram_username, t.telegram_id::text)
       ELSE NULL
     END AS telegram_peer,
-    status, fees, payment_hash, description, tag, preimage, payee_node
+    status, fees, payment_hash, description, tag, preimage, payee_node,
+    fiat_currency, msat_per_fiat
   FROM (
       SELECT time,
         from_id AS account_id,
@@ -80,7 +123,7 @@ CREATE VIEW lightning.account_txn AS
         -amount AS amount, fees,
         payment_hash, description, tag, preimage,
         remote_node AS payee_node,
-        pending
+        pending, fiat_currency, msat_per_fiat
       FROM lightning.transaction
       WHERE from_id IS NOT NULL
     UNION ALL
@@ -93,7 +136,7 @@ CREATE VIEW lightning.account_txn AS
         amount, 0 AS fees,
         payment_hash, description, tag, preimage,
         NULL as payee_node,
-        pending
+        pending, fiat_currency, msat_per_fiat
       FROM lightning.transaction
       WHERE to_id IS NOT NULL
   ) AS x
@@ -0,0 +1,185 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"strings"
+	"time"
+
+	"github.com/docopt/docopt-go"
+	"github.com/fiatjaf/lntxbot/t"
+)
+
+type PriceAlert struct {
+	Id        int     `db:"id"`
+	Account   int     `db:"account"`
+	Currency  string  `db:"currency"`
+	Direction string  `db:"direction"`
+	Price     float64 `db:"price"`
+}
+
+func handlePriceAlert(ctx context.Context, opts docopt.Opts) {
+	u := ctx.Value("initiator").(User)
+
+	switch {
+	case opts["above"].(bool), opts["below"].(bool):
+		direction := "above"
+		if opts["below"].(bool) {
+			direction = "below"
+		}
+
+		price, err := opts.Float64("<price>")
+		if err != nil || price <= 0 {
+			send(ctx, u, t.ERROR, t.T{"Err": "invalid price."})
+			return
+		}
+
+		currency, err := opts.String("<currency>")
+		if err != nil {
+			currency = u.Currency
+		}
+		currency = strings.ToUpper(currency)
+		if !stringIsIn(currency, CURRENCIES) {
+			send(ctx, u, t.ERROR, t.T{"Err": "currency not supported."})
+			return
+		}
+
+		go u.track("pricealert add", map[string]interface{}{
+			"currency":  currency,
+			"direction": direction,
+		})
+
+		if err := u.addPriceAlert(currency, direction, price); err != nil {
+			send(ctx, u, t.ERROR, t.T{"Err": err.Error()})
+			return
+		}
+	case opts["remove"].(bool):
+		id, err := opts.Int("<id>")
+		if err != nil {
+			send(ctx, u, t.ERROR, t.T{"Err": "invalid alert id."})
+			return
+		}
+
+		go u.track("pricealert remove", nil)
+
+		if err := u.removePriceAlert(id); err != nil {
+			send(ctx, u, t.ERROR, t.T{"Err": err.Error()})
+			return
+		}
+	}
+
+	alerts, err := u.listPriceAlerts()
+	if err != nil {
+		log.Warn().Err(err).Stringer("user", &u).Msg("failed to list price alerts")
+		send(ctx, u, t.ERROR, t.T{"Err": err.Error()})
+		return
+	}
+
+	send(ctx, u, t.PRICEALERTS, t.T{"Alerts": alerts})
+}
+
+func (u User) addPriceAlert(currency, direction string, price float64) error {
+	res, err := pg.Exec(`
+INSERT INTO price_alert (account, currency, direction, price)
+VALUES ($1, $2, $3, $4)
+ON CONFLICT (account, currency, direction, price) DO NOTHING
+    `, u.Id, currency, direction, price)
+	if err != nil {
+		log.Warn().Err(err).Stringer("user", &u).Msg("failed to add price alert")
+		return ErrDatabase
+	}
+
+	if n, _ := res.RowsAffected(); n == 0 {
+		return errors.New("You already have this alert.")
+	}
+
+	return nil
+}
+
+func (u User) removePriceAlert(id int) error {
+	res, err := pg.Exec(`
+DELETE FROM price_alert WHERE id = $1 AND account = $2
+    `, id, u.Id)
+	if err != nil {
+		log.Warn().Err(err).Stringer("user", &u).Msg("failed to remove price alert")
+		return ErrDatabase
+	}
+
+	if n, _ := res.RowsAffected(); n == 0 {
+		return errors.New("Alert not found.")
+	}
+
+	return nil
+}
+
+func (u User) listPriceAlerts() (alerts []PriceAlert, err error) {
+	err = pg.Select(&alerts, `
+SELECT id, account, currency, direction, price
+FROM price_alert
+WHERE account = $1
+ORDER BY currency, price
+    `, u.Id)
+	if err == sql.ErrNoRows {
+		err = nil
+	}
+	return
+}
+
+func priceAlertRoutine() {
+	ctx := context.WithValue(context.Background(), "origin", "background")
+
+	for {
+		var currencies []string
+		err := pg.Select(&currencies, `SELECT DISTINCT currency FROM price_alert`)
+		if err != nil && err != sql.ErrNoRows {
+			log.Error().Err(err).Msg("failed to fetch price alert currencies")
+		}
+
+		for _, currency := range currencies {
+			// uses the cached rate, refreshing it if it's too old
+			msatPerFiat, err := getMsatsPerFiatUnit(currency)
+			if err != nil {
+				log.Warn().Err(err).Str("currency", currency).
+					Msg("failed to get rate on price alert routine")
+				continue
+			}
+			price := 100000000000 / float64(msatPerFiat)
+
+			var alerts []PriceAlert
+			err = pg.Select(&alerts, `
+DELETE FROM price_alert
+WHERE currency = $1 AND (
+  (direction = 'above' AND price <= $2) OR
+  (direction = 'below' AND price >= $2)
+)
+RETURNING id, account, currency, direction, price
+            `, currency, price)
+			if err != nil && err != sql.ErrNoRows {
+				log.Error().Err(err).Str("currency", currency).
+					Msg("failed to fetch triggered price alerts")
+				continue
+			}
+
+			for _, alert := range alerts {
+				u, err := loadUser(alert.Account)
+				if err != nil {
+					log.Error().Err(err).Int("user", alert.Account).
+						Msg("failed to load user on price alert routine")
+					continue
+				}
+
+				send(context.WithValue(ctx, "initiator", u), u,
+					t.PRICEALERTTRIGGERED, t.T{
+						"Alert": alert,
+						"Price": price,
+					})
+				go u.track("pricealert triggered", map[string]interface{}{
+					"currency": currency,
+				})
+			}
+		}
+
+		time.Sleep(time.Minute * 10)
+	}
+}
@@ -1,8 +1,10 @@
 package main
 
 import (
+	"context"
 	"encoding/base64"
 	"errors"
+	"fmt"
 	"io"
 	"net/http"
 	"net/url"
@@ -10,12 +12,39 @@ import (
 	"strings"
 	"time"
 
+	"github.com/docopt/docopt-go"
+	"github.com/fiatjaf/lntxbot/t"
 	"github.com/lucsky/cuid"
 	"github.com/skip2/go-qrcode"
 	chqr "github.com/tuotoo/qrcode"
 	"gopkg.in/jmcvetta/napping.v3"
 )
 
+const (
+	qrMediumMaxLength = 400  // above this we use the lowest error correction
+	qrMaxLength       = 2000 // codes bigger than this are too dense to scan
+)
+
+func handleQR(ctx context.Context, opts docopt.Opts) {
+	value := strings.TrimSpace(
+		getVariadicFieldOrReplyToContent(incomingMessage(ctx), opts, "<text>"))
+	if value == "" {
+		handleHelp(ctx, "qr")
+		return
+	}
+	if len(value) > qrMaxLength {
+		send(ctx, t.ERROR, t.T{
+			"Err": fmt.Sprintf("text too long for a QR code, the maximum is %d characters.",
+				qrMaxLength),
+		})
+		return
+	}
+
+	go ctx.Value("initiator").(User).track("qr", nil)
+
+	send(ctx, qrURL(value), "<pre>"+escapeHTML(value)+"</pre>")
+}
+
 func serveQRCodes() {
 	router.PathPrefix("/qr/").HandlerFunc(
 		func(w http.ResponseWriter, r *http.Request) {
@@ -35,7 +64,13 @@ func serveQRCodes() {
 				value = strings.ToUpper(value)
 			}
 
-			qr, err := qrcode.New(value, qrcode.Low)
+			// short values get more error correction, long ones need the space
+			level := qrcode.Medium
+			if len(value) > qrMediumMaxLength {
+				level = qrcode.Low
+			}
+
+			qr, err := qrcode.New(value, level)
 			if err != nil {
 				log.Warn().Err(err).Str("value", value).Msg("failed to encode qr")
 				http.Error(w, "failed to encode "+value+" as a QR code.", 400)
@@ -85,7 +85,7 @@ func handleSats4Ads(ctx context.Context, u User, opts docopt.Opts) {
 			return
 		}
 
-		msats, err := parseSatoshis(opts)
+		msats, err := parseSatoshis(ctx, opts)
 		if err != nil {
 			send(ctx, u, t.ERROR, t.T{"App": "sats4ads", "Err": err.Error()})
 			return
@@ -284,6 +284,9 @@ OFFSET $3
 		if err != nil {
 			// message wasn't sent
 			// logger.Info().Err(err).Msg("message wasn't sent. skipping.")
+			if telegramChatGone(err) {
+				target.unsetChat()
+			}
 			err = nil
 			continue
 		}
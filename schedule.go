@@ -0,0 +1,255 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+
+	"github.com/docopt/docopt-go"
+	"github.com/fiatjaf/lntxbot/t"
+)
+
+type ScheduledPayment struct {
+	Id           int           `db:"id"`
+	Account      int           `db:"account"`
+	Receiver     sql.NullInt64 `db:"receiver"`
+	Address      string        `db:"address"`
+	ReceiverName string        `db:"receiver_name"`
+	Msatoshi     int64         `db:"msatoshi"`
+	Period       string        `db:"period"`
+	NextRun      time.Time     `db:"next_run"`
+}
+
+func (sp ScheduledPayment) Sats() float64 { return float64(sp.Msatoshi) / 1000 }
+
+// after returns the first run after the given time, skipping all the runs
+// that were missed.
+func (sp ScheduledPayment) after(now time.Time) time.Time {
+	next := sp.NextRun
+	for !next.After(now) {
+		switch sp.Period {
+		case "day":
+			next = next.AddDate(0, 0, 1)
+		case "week":
+			next = next.AddDate(0, 0, 7)
+		default:
+			next = next.AddDate(0, 1, 0)
+		}
+	}
+	return next
+}
+
+func handleSchedule(ctx context.Context, opts docopt.Opts) {
+	u := ctx.Value("initiator").(User)
+
+	switch {
+	case opts["daily"].(bool), opts["weekly"].(bool), opts["monthly"].(bool):
+		interval := "month"
+		if opts["daily"].(bool) {
+			interval = "day"
+		} else if opts["weekly"].(bool) {
+			interval = "week"
+		}
+
+		msats, err := parseSatoshis(ctx, opts)
+		if err != nil {
+			send(ctx, u, t.ERROR, t.T{"Err": messageFromError(ctx, ErrInvalidAmount.withDetail(err))})
+			return
+		}
+
+		sp := ScheduledPayment{Msatoshi: msats, Period: interval}
+		username, _ := opts.String("<receiver>")
+		if name, domain, ok := parseLightningAddress(username); ok {
+			sp.Address = name + "@" + domain
+		} else if receiver, err := examineTelegramUsername(username); err == nil {
+			if receiver.Id == u.Id {
+				send(ctx, u, t.ERROR, t.T{"Err": "Can't pay yourself."})
+				return
+			}
+			sp.Receiver = sql.NullInt64{Int64: int64(receiver.Id), Valid: true}
+		} else {
+			send(ctx, u, t.MISSINGRECEIVER)
+			return
+		}
+
+		go u.track("schedule add", map[string]interface{}{
+			"interval": interval,
+			"lnurl":    sp.Address != "",
+			"sats":     msats / 1000,
+		})
+
+		if err := u.addScheduledPayment(sp); err != nil {
+			send(ctx, u, t.ERROR, t.T{"Err": err.Error()})
+			return
+		}
+	case opts["cancel"].(bool):
+		id, err := opts.Int("<id>")
+		if err != nil {
+			send(ctx, u, t.ERROR, t.T{"Err": "invalid schedule id."})
+			return
+		}
+
+		go u.track("schedule cancel", nil)
+
+		if err := u.removeScheduledPayment(id); err != nil {
+			send(ctx, u, t.ERROR, t.T{"Err": err.Error()})
+			return
+		}
+	}
+
+	schedules, err := u.listScheduledPayments()
+	if err != nil {
+		log.Warn().Err(err).Stringer("user", &u).Msg("failed to list schedules")
+		send(ctx, u, t.ERROR, t.T{"Err": ErrDatabase.Error()})
+		return
+	}
+
+	send(ctx, u, t.SCHEDULES, t.T{"Schedules": schedules})
+}
+
+func (u User) addScheduledPayment(sp ScheduledPayment) error {
+	_, err := pg.Exec(`
+INSERT INTO scheduled_payment (account, receiver, address, msatoshi, period, next_run)
+VALUES ($1, $2, $3, $4, $5, now() + ('1 ' || $5)::interval)
+    `, u.Id, sp.Receiver, sql.NullString{String: sp.Address, Valid: sp.Address != ""},
+		sp.Msatoshi, sp.Period)
+	if err != nil {
+		log.Warn().Err(err).Stringer("user", &u).Msg("failed to add schedule")
+		return ErrDatabase
+	}
+	return nil
+}
+
+func (u User) removeScheduledPayment(id int) error {
+	res, err := pg.Exec(`
+DELETE FROM scheduled_payment WHERE id = $1 AND account = $2
+    `, id, u.Id)
+	if err != nil {
+		log.Warn().Err(err).Stringer("user", &u).Msg("failed to remove schedule")
+		return ErrDatabase
+	}
+
+	if n, _ := res.RowsAffected(); n == 0 {
+		return errors.New("Schedule not found.")
+	}
+
+	return nil
+}
+
+const scheduledPaymentColumns = `
+  sp.id, sp.account, sp.receiver, coalesce(sp.address, '') AS address,
+  coalesce('@' || a.telegram_username, a.discord_username, sp.address, '') AS receiver_name,
+  sp.msatoshi, sp.period, sp.next_run
+`
+
+func (u User) listScheduledPayments() (schedules []ScheduledPayment, err error) {
+	err = pg.Select(&schedules, `
+SELECT `+scheduledPaymentColumns+`
+FROM scheduled_payment AS sp
+LEFT OUTER JOIN account AS a ON a.id = sp.receiver
+WHERE sp.account = $1
+ORDER BY sp.next_run
+    `, u.Id)
+	if err == sql.ErrNoRows {
+		err = nil
+	}
+	return
+}
+
+func scheduledPaymentsRoutine() {
+	ctx := context.WithValue(context.Background(), "origin", "background")
+
+	for {
+		var schedules []ScheduledPayment
+		err := pg.Select(&schedules, `
+SELECT `+scheduledPaymentColumns+`
+FROM scheduled_payment AS sp
+LEFT OUTER JOIN account AS a ON a.id = sp.receiver
+WHERE sp.next_run <= now()
+        `)
+		if err != nil && err != sql.ErrNoRows {
+			log.Error().Err(err).Msg("failed to fetch due scheduled payments")
+		}
+
+		for _, sp := range schedules {
+			// move the next run forward before paying, and only pay if we were
+			// the ones who did it, so a payment is never made twice
+			res, err := pg.Exec(`
+UPDATE scheduled_payment SET next_run = $3
+WHERE id = $1 AND next_run = $2
+            `, sp.Id, sp.NextRun, sp.after(time.Now()))
+			if err != nil {
+				log.Error().Err(err).Int("id", sp.Id).
+					Msg("failed to update scheduled payment")
+				continue
+			}
+			if n, _ := res.RowsAffected(); n == 0 {
+				continue
+			}
+
+			u, err := loadUser(sp.Account)
+			if err != nil {
+				log.Error().Err(err).Int("user", sp.Account).
+					Msg("failed to load user on scheduled payments routine")
+				continue
+			}
+
+			runScheduledPayment(context.WithValue(ctx, "initiator", u), u, sp)
+		}
+
+		time.Sleep(time.Minute)
+	}
+}
+
+func runScheduledPayment(ctx context.Context, u User, sp ScheduledPayment) {
+	log.Info().Stringer("user", &u).Int("id", sp.Id).
+		Int64("msats", sp.Msatoshi).Msg("running scheduled payment")
+	go u.track("schedule run", map[string]interface{}{
+		"interval": sp.Period,
+		"lnurl":    sp.Address != "",
+		"sats":     sp.Msatoshi / 1000,
+	})
+
+	if sp.Address != "" {
+		// the lnurl flow will notify the user of failures by itself
+		handleLNURL(ctx, sp.Address, handleLNURLOpts{
+			payAmountWithoutPrompt: &sp.Msatoshi,
+		})
+		return
+	}
+
+	receiver, err := loadUser(int(sp.Receiver.Int64))
+	if err != nil {
+		log.Warn().Err(err).Int("id", sp.Id).
+			Msg("failed to load receiver of scheduled payment")
+		send(ctx, u, t.SCHEDULEFAILED, t.T{
+			"Schedule": sp,
+			"Err":      ErrDatabase.Error(),
+		})
+		return
+	}
+
+	err = u.sendInternally(ctx, receiver, false, sp.Msatoshi,
+		int64(float64(sp.Msatoshi)*0.003), "scheduled payment", "", "")
+	if err != nil {
+		log.Warn().Err(err).Stringer("user", &u).Int("id", sp.Id).
+			Msg("scheduled payment failed")
+		send(ctx, u, t.SCHEDULEFAILED, t.T{
+			"Schedule": sp,
+			"Err":      messageFromError(ctx, err),
+		})
+		return
+	}
+
+	send(ctx, u, t.USERSENTTOUSER, t.T{
+		"User":    receiver.AtName(ctx),
+		"Sats":    sp.Msatoshi / 1000,
+		"RawSats": "",
+	})
+	send(ctx, receiver, t.USERSENTYOUSATS, t.T{
+		"User":    u.AtName(ctx),
+		"Sats":    sp.Msatoshi / 1000,
+		"RawSats": "",
+	})
+}
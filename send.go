@@ -6,7 +6,6 @@ import (
 
 	"github.com/bwmarrin/discordgo"
 	"github.com/docopt/docopt-go"
-	"github.com/fiatjaf/go-lnurl"
 	"github.com/fiatjaf/lntxbot/t"
 	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api"
 )
@@ -28,12 +27,18 @@ func handleSend(ctx context.Context, opts docopt.Opts) {
 		description string
 	)
 
+	if !u.allowPaymentRate(ctx) {
+		return
+	}
+
 	// get quantity
-	msats, err := parseSatoshis(opts)
+	msats, err := parseSatoshis(ctx, opts)
 	amtraw := opts["<satoshis>"].(string)
 
 	if err != nil || msats <= 0 {
-		send(ctx, u, t.ERROR, t.T{"Err": err.Error()})
+		send(ctx, u, t.ERROR, t.T{"Err": messageFromError(ctx, ErrInvalidAmount.withDetail(err))})
+		return
+	} else if !checkTipLimit(ctx, msats) {
 		return
 	} else {
 		username, _ = opts.String("<receiver>")
@@ -45,13 +50,13 @@ func handleSend(ctx context.Context, opts docopt.Opts) {
 		anonymous = true
 	}
 
-	if extra, ok := opts["<description>"].([]string); ok {
-		description = strings.Join(extra, " ")
-	}
+	// the note may also come from the message being replied to
+	description = getVariadicFieldOrReplyToContent(
+		incomingMessage(ctx), opts, "<description>")
 
 	// maybe this is a lightning address like username@domain.com?
-	if _, _, ok := lnurl.ParseInternetIdentifier(username); ok {
-		handleLNURL(ctx, username, handleLNURLOpts{
+	if name, domain, ok := parseLightningAddress(username); ok {
+		handleLNURL(ctx, name+"@"+domain, handleLNURLOpts{
 			payAmountWithoutPrompt: &msats,
 			forceSendComment:       description,
 			anonymous:              anonymous,
@@ -74,6 +79,23 @@ func handleSend(ctx context.Context, opts docopt.Opts) {
 	case *tgbotapi.Message: // telegram
 		receiver, err = examineTelegramUsername(username)
 		if receiver != nil {
+			// more usernames after the first one split the amount among all
+			receivers := []User{*receiver}
+			words := strings.Fields(description)
+			for len(words) > 0 && strings.HasPrefix(words[0], "@") {
+				other, err := examineTelegramUsername(words[0])
+				if err != nil {
+					break
+				}
+				receivers = append(receivers, *other)
+				words = words[1:]
+			}
+			if len(receivers) > 1 {
+				handleSendSplit(ctx, receivers, msats, anonymous,
+					strings.Join(words, " "))
+				return
+			}
+
 			goto ensured
 		}
 
@@ -132,15 +154,18 @@ ensured:
 			Str("from", u.Username).
 			Str("to", receiver.AtName(ctx)).
 			Msg("failed to send/tip")
-		send(ctx, g, u, t.FAILEDSEND, t.T{"Err": err.Error()})
+		send(ctx, g, u, t.FAILEDSEND, t.T{"Err": messageFromError(ctx, err)})
 		return
 	}
 
+	note := escapeHTML(strings.TrimSpace(description))
+
 	// notify sender
 	send(ctx, u, t.USERSENTTOUSER, t.T{
 		"User":    receiver.AtName(ctx),
 		"Sats":    msats / 1000,
 		"RawSats": amtraw,
+		"Note":    note,
 		"ReceiverHasNoChat": receiver.TelegramChatId == 0 &&
 			receiver.DiscordChannelId == "",
 	})
@@ -149,12 +174,17 @@ ensured:
 	if receiver.hasPrivateChat() && !ctx.Value("spammy").(bool) {
 		// if possible privately
 		if anonymous {
-			send(ctx, receiver, t.RECEIVEDSATSANON, t.T{"Sats": msats / 1000})
+			send(ctx, receiver, t.RECEIVEDSATSANON, t.T{
+				"Sats":    msats / 1000,
+				"RawSats": amtraw,
+				"Note":    note,
+			})
 		} else {
 			send(ctx, receiver, t.USERSENTYOUSATS, t.T{
 				"User":    u.AtName(ctx),
 				"Sats":    msats / 1000,
 				"RawSats": amtraw,
+				"Note":    note,
 			})
 		}
 	}
@@ -170,3 +200,166 @@ ensured:
 		}, ctx.Value("message"), FORCESPAMMY)
 	}
 }
+
+// handleSendSplit divides the amount equally among the receivers, in whole
+// satoshis, giving the remainder to the first.
+func handleSendSplit(
+	ctx context.Context,
+	receivers []User,
+	msats int64,
+	anonymous bool,
+	description string,
+) {
+	u := ctx.Value("initiator").(User)
+	g, _ := ctx.Value("group").(GroupChat)
+
+	sats := msats / 1000
+	each := sats / int64(len(receivers))
+	if each == 0 {
+		send(ctx, g, u, t.ERROR, t.T{"Err": ErrInvalidAmount.Error()})
+		return
+	}
+
+	transfers := make([]InternalTransfer, len(receivers))
+	for i, receiver := range receivers {
+		amount := each * 1000
+		if i == 0 {
+			amount += (sats % int64(len(receivers))) * 1000
+		}
+		transfers[i] = InternalTransfer{
+			Target: receiver,
+			Msats:  amount,
+			Fees:   int64(float64(amount) * 0.003),
+		}
+	}
+
+	note := escapeHTML(strings.TrimSpace(description))
+	if m, ok := ctx.Value("message").(*tgbotapi.Message); ok &&
+		!anonymous && m.Chat.Type != "private" {
+		description = strings.TrimSpace(
+			description + " (" + telegramMessageLink(m) + ")")
+	}
+
+	go u.track("send split", map[string]interface{}{
+		"receivers": len(receivers),
+		"sats":      sats,
+	})
+
+	err := u.sendInternallyToMany(ctx, transfers, anonymous,
+		strings.TrimSpace(description), "")
+	if err != nil {
+		log.Warn().Err(err).Str("from", u.Username).Int("receivers", len(receivers)).
+			Msg("failed to send split")
+		send(ctx, g, u, t.FAILEDSEND, t.T{"Err": messageFromError(ctx, err)})
+		return
+	}
+
+	// notify sender
+	shares := make([]t.T, len(transfers))
+	for i, transfer := range transfers {
+		shares[i] = t.T{
+			"User": transfer.Target.AtName(ctx),
+			"Sats": transfer.Msats / 1000,
+		}
+	}
+	send(ctx, u, t.USERSENTTOUSERS, t.T{"Sats": sats, "Shares": shares})
+
+	// notify receivers
+	for _, transfer := range transfers {
+		if anonymous {
+			send(ctx, transfer.Target, t.RECEIVEDSATSANON, t.T{
+				"Sats":    transfer.Msats / 1000,
+				"RawSats": "",
+				"Note":    note,
+			})
+		} else {
+			send(ctx, transfer.Target, t.USERSENTYOUSATS, t.T{
+				"User":    u.AtName(ctx),
+				"Sats":    transfer.Msats / 1000,
+				"RawSats": "",
+				"Note":    note,
+			})
+		}
+	}
+}
+
+// handleSendMany sends different amounts to many users at once, like
+// /sendmany @alice=1000 @bob=2500. Receivers that can't be found are skipped.
+func handleSendMany(ctx context.Context, opts docopt.Opts) {
+	u := ctx.Value("initiator").(User)
+
+	if !u.allowPaymentRate(ctx) {
+		return
+	}
+
+	var (
+		transfers []InternalTransfer
+		failed    []string
+		total     int64
+	)
+	for _, payment := range opts["<payments>"].([]string) {
+		parts := strings.SplitN(payment, "=", 2)
+		if len(parts) != 2 {
+			failed = append(failed, payment)
+			continue
+		}
+
+		msats, err := parseAmountString(ctx, parts[1])
+		if err != nil {
+			failed = append(failed, payment)
+			continue
+		}
+
+		receiver, err := examineTelegramUsername(parts[0])
+		if err != nil {
+			failed = append(failed, payment)
+			continue
+		}
+
+		transfers = append(transfers, InternalTransfer{
+			Target: *receiver,
+			Msats:  msats,
+			Fees:   int64(float64(msats) * 0.003),
+		})
+		total += msats + int64(float64(msats)*0.003)
+	}
+
+	if len(transfers) == 0 {
+		send(ctx, u, t.SENDMANYSUMMARY, t.T{"Failed": failed})
+		return
+	}
+
+	if total > getBalance(pg, u.Id) {
+		send(ctx, u, t.ERROR, t.T{
+			"Err": messageFromError(ctx, ErrInsufficientBalance)})
+		return
+	}
+
+	go u.track("sendmany", map[string]interface{}{
+		"receivers": len(transfers),
+		"failed":    len(failed),
+		"sats":      total / 1000,
+	})
+
+	err := u.sendInternallyToMany(ctx, transfers, false, "", "")
+	if err != nil {
+		log.Warn().Err(err).Str("from", u.Username).Int("receivers", len(transfers)).
+			Msg("failed to sendmany")
+		send(ctx, u, t.FAILEDSEND, t.T{"Err": messageFromError(ctx, err)})
+		return
+	}
+
+	sent := make([]t.T, len(transfers))
+	for i, transfer := range transfers {
+		sent[i] = t.T{
+			"User": transfer.Target.AtName(ctx),
+			"Sats": float64(transfer.Msats) / 1000,
+		}
+		send(ctx, transfer.Target, t.USERSENTYOUSATS, t.T{
+			"User":    u.AtName(ctx),
+			"Sats":    transfer.Msats / 1000,
+			"RawSats": "",
+		})
+	}
+	send(ctx, u, t.SENDMANYSUMMARY, t.T{"Sent": sent, "Failed": failed})
+}
@@ -0,0 +1,55 @@
+package main
+
+import (
+	"context"
+	"net/http"
+	"os"
+	"os/signal"
+	"sync"
+	"syscall"
+	"time"
+)
+
+// shutdownCtx is cancelled when we get SIGTERM/SIGINT, after that no new
+// commands are accepted.
+var shutdownCtx, shutdown = context.WithCancel(context.Background())
+
+// paymentWatchers tracks goroutines that are paying something or waiting for
+// a payment result so we don't kill them halfway through on deploys.
+var paymentWatchers sync.WaitGroup
+
+func shuttingDown() bool { return shutdownCtx.Err() != nil }
+
+// handleShutdownSignals stops the http server when the process is told to quit,
+// which makes the ListenAndServe in main return and drainPayments run.
+func handleShutdownSignals(srv *http.Server) {
+	sig := make(chan os.Signal, 1)
+	signal.Notify(sig, syscall.SIGTERM, syscall.SIGINT)
+	log.Info().Stringer("signal", <-sig).Msg("shutting down")
+	shutdown()
+
+	ctx, cancel := context.WithTimeout(context.Background(), s.ShutdownTimeout)
+	defer cancel()
+	if err := srv.Shutdown(ctx); err != nil {
+		log.Warn().Err(err).Msg("error stopping http server")
+	}
+}
+
+// drainPayments waits for the in-flight payment goroutines until the timeout.
+// Whatever is still pending after that is in the database and will be resolved
+// by pendingOutgoingPaymentsRoutine when we start again.
+func drainPayments() {
+	done := make(chan struct{})
+	go func() {
+		paymentWatchers.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+		log.Info().Msg("all in-flight payments finished")
+	case <-time.After(s.ShutdownTimeout):
+		log.Warn().Dur("timeout", s.ShutdownTimeout).
+			Msg("exiting with payments still in flight")
+	}
+}
@@ -2,14 +2,65 @@ package main
 
 import (
 	"context"
+	"time"
 
 	"github.com/fiatjaf/lntxbot/t"
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api"
 )
 
+// commands the onboarding buttons run, by callback data
+var onboardingCommands = map[string]string{
+	"receive": "/invoice any",
+	"lnurl":   "/invoice lnurl",
+}
+
 func handleStart(ctx context.Context) {
-	yourname := ctx.Value("initiator").(User).Username
+	u := ctx.Value("initiator").(User)
+
+	yourname := u.Username
 	if yourname == "" {
 		yourname = "yourname"
 	}
 	send(ctx, t.START, t.T{"YourName": yourname})
+
+	// first run: explain how to get some money in, only once
+	if !u.Onboarded && u.setOnboarded() && getBalance(pg, u.Id) == 0 {
+		go u.track("onboarding", nil)
+
+		send(ctx, t.ONBOARDING, &tgbotapi.InlineKeyboardMarkup{
+			InlineKeyboard: [][]tgbotapi.InlineKeyboardButton{
+				{
+					tgbotapi.NewInlineKeyboardButtonData(
+						translate(ctx, t.ONBOARDINGRECEIVE), "onboard=receive"),
+					tgbotapi.NewInlineKeyboardButtonData(
+						translate(ctx, t.ONBOARDINGLNURL), "onboard=lnurl"),
+				},
+			},
+		})
+	}
+}
+
+func handleOnboardingCallback(ctx context.Context) {
+	u := ctx.Value("initiator").(User)
+	cb := ctx.Value("callbackQuery").(*tgbotapi.CallbackQuery)
+
+	text, ok := onboardingCommands[cb.Data[8:]]
+	if !ok || cb.Message == nil {
+		send(ctx, t.CALLBACKEXPIRED)
+		return
+	}
+
+	go u.track("onboarding button", map[string]interface{}{"button": cb.Data[8:]})
+
+	// run the command as if the user had typed it
+	handleTelegramMessage(
+		context.WithValue(context.Background(), "origin", "telegram"),
+		&tgbotapi.Message{
+			MessageID: cb.Message.MessageID,
+			From:      cb.From,
+			Chat:      cb.Message.Chat,
+			Date:      int(time.Now().Unix()),
+			Text:      text,
+		},
+	)
 }
@@ -0,0 +1,63 @@
+package main
+
+import (
+	"context"
+	"math"
+
+	"github.com/fiatjaf/lntxbot/t"
+)
+
+// every level needs more payments than the previous one: 10, 40, 90, 160...
+const paymentsPerLevelStep = 10
+
+func levelForPayments(n int) int {
+	return int(math.Sqrt(float64(n) / paymentsPerLevelStep))
+}
+
+func paymentsForLevel(level int) int {
+	return paymentsPerLevelStep * level * level
+}
+
+type UserStats struct {
+	Sent          int   `db:"sent"`
+	Received      int   `db:"received"`
+	MsatsSent     int64 `db:"msats_sent"`
+	MsatsReceived int64 `db:"msats_received"`
+}
+
+func (u User) getStats() (stats UserStats, err error) {
+	err = pg.Get(&stats, `
+SELECT
+  count(*) FILTER (WHERE amount < 0) AS sent,
+  count(*) FILTER (WHERE amount > 0) AS received,
+  coalesce(sum(-amount) FILTER (WHERE amount < 0), 0)::bigint AS msats_sent,
+  coalesce(sum(amount) FILTER (WHERE amount > 0), 0)::bigint AS msats_received
+FROM lightning.account_txn
+WHERE account_id = $1 AND NOT pending
+    `, u.Id)
+	return
+}
+
+func handleStats(ctx context.Context) {
+	u := ctx.Value("initiator").(User)
+
+	stats, err := u.getStats()
+	if err != nil {
+		log.Warn().Err(err).Stringer("user", &u).Msg("failed to load stats")
+		send(ctx, u, t.ERROR, t.T{"Err": ErrDatabase.Error()})
+		return
+	}
+
+	go u.track("stats", nil)
+
+	payments := stats.Sent + stats.Received
+	level := levelForPayments(payments)
+	send(ctx, u, t.STATSMSG, t.T{
+		"Stats":        stats,
+		"SatsSent":     float64(stats.MsatsSent) / 1000,
+		"SatsReceived": float64(stats.MsatsReceived) / 1000,
+		"Level":        level,
+		"NextLevel":    level + 1,
+		"Missing":      paymentsForLevel(level+1) - payments,
+	})
+}
@@ -1,6 +1,7 @@
 package t
 
 import (
+	"fmt"
 	"strings"
 	"text/template"
 )
@@ -32,6 +33,7 @@ func (bundle *Bundle) AddLanguage(lang string, translations map[Key]string) erro
 		tmpl := template.New(string(key))
 
 		tmpl.Funcs(bundle.Funcs)
+		tmpl.Funcs(template.FuncMap{"plural": pluralFunc(lang)})
 
 		tmpl, err := tmpl.Parse(strtemplate)
 		if err != nil {
@@ -59,14 +61,26 @@ func (bundle *Bundle) Check() (missing map[string][]Key) {
 	return
 }
 
+// Render executes the template for the given key in the given language. If
+// the language doesn't have the key, or if its template fails or renders
+// nothing, the default language is used instead.
 func (bundle *Bundle) Render(lang string, key Key, data interface{}) (string, error) {
-	out := strings.Builder{}
+	if lang != bundle.DefaultLanguage {
+		if translationTemplate, exists := bundle.Translations[lang][key]; exists {
+			out := strings.Builder{}
+			err := translationTemplate.Execute(&out, data)
+			if err == nil && strings.TrimSpace(out.String()) != "" {
+				return out.String(), nil
+			}
+		}
+	}
 
-	translationTemplate, exists := bundle.Translations[lang][key]
+	translationTemplate, exists := bundle.Translations[bundle.DefaultLanguage][key]
 	if !exists {
-		translationTemplate = bundle.Translations[bundle.DefaultLanguage][key]
+		return "", fmt.Errorf("no translation for '%s'", key)
 	}
 
+	out := strings.Builder{}
 	err := translationTemplate.Execute(&out, data)
 	if err != nil {
 		return "", err
@@ -0,0 +1,52 @@
+package t
+
+import "testing"
+
+func TestRenderFallback(t *testing.T) {
+	bundle := NewBundle("en")
+	if err := bundle.AddLanguage("en", map[Key]string{
+		YES:       "Yes",
+		NO:        "No",
+		CANCELED:  "Canceled.",
+		COMPLETED: "Done{{if .Name}}, {{.Name}}{{end}}!",
+	}); err != nil {
+		t.Fatal(err)
+	}
+	if err := bundle.AddLanguage("pt", map[Key]string{
+		YES:       "Sim",
+		NO:        "  ",
+		COMPLETED: "{{.Name.Missing}}",
+	}); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name    string
+		lang    string
+		key     Key
+		message string
+	}{
+		{"translated", "pt", YES, "Sim"},
+		{"missing in the locale", "pt", CANCELED, "Canceled."},
+		{"renders nothing in the locale", "pt", NO, "No"},
+		{"fails in the locale", "pt", COMPLETED, "Done, Maria!"},
+		{"unknown locale", "xx", YES, "Yes"},
+		{"default locale", "en", YES, "Yes"},
+	}
+
+	for _, test := range tests {
+		message, err := bundle.Render(test.lang, test.key, T{"Name": "Maria"})
+		if err != nil {
+			t.Errorf("%s: %s", test.name, err)
+			continue
+		}
+		if message != test.message {
+			t.Errorf("%s: Render(%q, %q) = %q, want %q",
+				test.name, test.lang, test.key, message, test.message)
+		}
+	}
+
+	if _, err := bundle.Render("pt", WITHDRAW, nil); err == nil {
+		t.Error("Render of a key missing in every language didn't fail")
+	}
+}
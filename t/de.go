@@ -39,7 +39,9 @@ lnurl-auth success!
 `,
 	LNURLPAYPROMPT: `🟢 <code>{{.Domain}}</code> erwartet {{if .FixedAmount}}<i>{{.FixedAmount | printf "%.15g"}} sat</i>{{else}} einen Wert zwischen <i>{{.Min | printf "%.15g"}}</i> und <i>{{.Max | printf "%.15g"}} sat</i>{{end}} for:
 
-<code>{{if .Long}}{{.Long | html}}{{else}}{{.Text | html}}{{end}}</code>{{if .WillSendPayerData}}
+<code>{{.Text | html}}</code>{{with .Long}}
+
+<i>{{. | html}}</i>{{end}}{{if .WillSendPayerData}}
 
 ---
 
@@ -53,11 +55,14 @@ lnurl-auth success!
 
 <b>Um die Zahlung zu bestätigen, bitte mit einem Text antworten</b>`,
 	LNURLPAYAMOUNTSNOTICE: `<code>{{.Domain}}</code> erwartet {{if .Exact}}{{.Min | printf "%.3f"}}{{else if .NoMax}} mindestens {{.Min | printf "%.0f"}}{{else}} zwischen {{.Min | printf "%.0f"}} und {{.Max | printf "%.0f"}}{{end}} sat.`,
-	LNURLPAYSUCCESS: `<code>{{.Domain}}</code> sagt:
+	LNURLPAYSUCCESS: `✅ Zahlung an <code>{{.Domain}}</code> erfolgreich. /tx_{{.HashFirstChars}}
+<code>{{.Hash}}</code>{{if .HasAction}}
+
+<code>{{.Domain}}</code> sagt:
 {{.Text}}
-{{if .DecipherError}}Fehler beim Entziffern ({{.DecipherError}}):
+{{if .DecipherError}}⚠️ Das von <code>{{.Domain}}</code> gesendete Geheimnis konnte nicht entschlüsselt werden: <i>{{.DecipherError}}</i>. Frage dort mit dem obigen Hash danach.
 {{end}}{{if .Value}}<pre>{{.Value}}</pre>
-{{end}}{{if .URL}}<a href="{{.URL}}">{{.URL}}</a>{{end}}
+{{end}}{{if .URL}}<a href="{{.URL}}">{{.URL}}</a>{{end}}{{end}}
     `,
 	LNURLPAYMETADATA: `#lnurlpay metadata:
 <b>domain</b>: <i>{{.Domain}}</i>
@@ -70,10 +75,11 @@ lnurl-auth success!
 	RENAMABLEMSG:      "Jeder kann diese Gruppe umbenennen, wenn er Betrag X an Sats bezahlt {{.Sat}} (Vergewissere dich, dass du @lntxbot als Administrator festgelegt hast).",
 	RENAMEPROMPT:      "Bezahle <b>{{.Sats}} sat</b> um diese Gruppe umzubennenen <i>{{.Name}}</i>?",
 	GROUPNOTRENAMABLE: "Diese Gruppe kann nicht umbenannt werden!",
+	TIPLIMITEXCEEDED: "Der Betrag überschreitet das Limit dieser Gruppe von {{.Sat}} sat.",
 
 	INTERNALPAYMENTUNEXPECTED: "Etwas Unerwartetes ist passiert. Wenn das eine interne Rechnung ist, wird sie fehlschlagen. Vielleicht ist die Rechnung abgelaufen oder etwas anderes ist passiert, wir wissen es nicht. Wenn das eine externe Rechnung ist, ignoriere die Warnung.",
 	PAYMENTFAILED:             "❌ Bezahlung fehlgeschlagen.\n\n<i>{{.FailureString}}</i>",
-	PAIDMESSAGE: `✅ Paid with <i>{{printf "%.15g" .Sats}} sat</i> ({{dollar .Sats}}) (+ <i>{{.Fee}}</i> fee). 
+	PAIDMESSAGE: `✅ Paid with <i>{{printf "%.15g" .Sats}} sat</i> ({{fiat .Sats $.FiatCurrency}}) (+ <i>{{.Fee}}</i> fee). 
 
 <b>Hash:</b> <code>{{.Hash}}</code>{{if .Preimage}}
 <b>Proof:</b> <code>{{.Preimage}}</code>{{end}}
@@ -85,7 +91,7 @@ lnurl-auth success!
 	INSUFFICIENTBALANCE: `Unzureichendes Guthaben für {{.Purpose}}. Benötigt {{.Sats | printf "%.15g"}} Sats mehr.`,
 
 	PAYMENTRECEIVED: `
-      ⚡️ Zahlung erhalten{{if .SenderName}} von <i>{{ .SenderName }}</i>{{end}}: {{.Sats}} sat ({{dollar .Sats}}). /tx_{{.Hash}}{{if .Message}} {{.Message | messageLink}}{{end}} #tx
+      ⚡️ Zahlung erhalten{{if .SenderName}} von <i>{{ .SenderName }}</i>{{end}}: {{.Sats}} sat ({{fiat .Sats $.FiatCurrency}}). /tx_{{.Hash}}{{if .Message}} {{.Message | messageLink}}{{end}} #tx
       {{if .Comment}}
 📨 <i>{{.Comment}}</i>
       {{end}}
@@ -100,6 +106,16 @@ lnurl-auth success!
 	EXPENSIVENOTIFICATION: "Die Nachricht {{.Link}} hat {{if .Sender}} dich gerade {{.Price}} gekostet{{else}} dir {{.Price}} gebracht {{end}}.",
 	FREETALK:              "Nachrichten sind wieder kostenlos",
 
+	ERRINSUFFICIENTBALANCE: "Unzureichendes Guthaben.",
+	ERRDATABASE: "Datenbankfehler.",
+	ERRINVALIDAMOUNT: "Ungültiger Betrag.",
+	ERRINVOICEEXPIRED: "Diese Rechnung ist{{if .Minutes}} seit {{.Minutes}} Minute{{if gt .Minutes 1}}n{{end}}{{end}} abgelaufen.",
+	ERRNOROUTE: "Es konnte keine Route zum Empfänger gefunden werden.",
+	ERRTIMEOUT: "Zeitüberschreitung{{if .Seconds}} nach {{.Seconds}} Sekunden{{end}}.",
+	ERRLIGHTNINGNODE: "Fehler vom Lightning-Knoten: {{.Message}}",
+	ERRALREADYPAYING: "Diese Rechnung wird bereits bezahlt.",
+	ERRWRONGNETWORK:  "Dies ist eine {{.Invoice}}-Rechnung, aber dieser Bot läuft auf {{.Bot}}.",
+
 	APPBALANCE: `#{{.App | lower}} Balance: <i>{{printf "%.15g" .Balance}} sat</i>`,
 
 	HELPINTRO: `
@@ -183,9 +199,11 @@ Listet alle Transaktionen mit Seitennmmerierung (pagination controls). Jede Tran
 <code>/fundraise 10000 8 @user</code>: Telegram Nutzer @user wird 8000 Satoshis erhalten, nachdem 8 Personen teilgenommen/beigtragen haben. 
     `,
 	FUNDRAISEAD: `
-Spendenaktion {{.Fund}} für {{.ToUser}}!
+Spendenaktion {{.Fund}} für {{.ToUser}}!{{with .Title}}
+<b>{{.}}</b>{{end}}
 Zahl Spender für Vollendung benötigt: {{.Participants}}
 Jeder zahlt Betrag: {{.Sats}} sat
+{{.Progress}} {{.Contributed}}/{{.Participants}}
 Folgende Personen haben beigesteuert: {{.Registered}}
     `,
 	FUNDRAISEJOIN:        "Spende!",
@@ -268,7 +286,7 @@ Um eine Anzeige zu übertragen, musst du an den Bot eine Nachricht mit dem Anzei
 /sats4ads_broadcast_1000 veröffentlicht eine Anzeige. Die letzte Zahl ist die maximale Zahl an Satoshis, die verwendet werden. Günstigere Anzeigenlsitings werden gegenüber teuren Listings bevorzugt. Muss als Antwort auf eine andere Nachricht aufgrufen werden deren Inhalt als Anzeigentext verwendet wird.
     `,
 	SATS4ADSTOGGLE:    `#sats4ads {{if .On}}Sehe Anzeigen/Werbung und erhalte {{printf "%.15g" .Sats}} X Sats pro Zeichen.{{else}}Du wirst keine weiteren Anzeigen sehen.{{end}}`,
-	SATS4ADSBROADCAST: `#sats4ads {{if .NSent}}Nachricht veröffentlicht {{.NSent}} Zeit{{s .NSent}} für Gesamtkosten in Höhe von {{.Sats}} Sats ({{dollar .Sats}}).{{else}} Konnte keinen zu benachrichtigenden Endpunkt im Netzwerk finden, um ihn über die festgelegten Parameter zu informieren. /sats4ads_rates{{end}}`,
+	SATS4ADSBROADCAST: `#sats4ads {{if .NSent}}Nachricht veröffentlicht {{.NSent}} Zeit{{s .NSent}} für Gesamtkosten in Höhe von {{.Sats}} Sats ({{fiat .Sats $.FiatCurrency}}).{{else}} Konnte keinen zu benachrichtigenden Endpunkt im Netzwerk finden, um ihn über die festgelegten Parameter zu informieren. /sats4ads_rates{{end}}`,
 	SATS4ADSSTART:     `Nachricht wird veröffentlicht.`,
 	SATS4ADSPRICETABLE: `#sats4ads Quantity of users <b>up to</b> each pricing tier.
 {{range .Rates}}<code>{{.UpToRate}} msat</code>: <i>{{.NUsers}} user{{s .NUsers}}</i>
@@ -285,7 +303,7 @@ Jede Anzeige kostet den oben stehenden Preis <i>per character</i> + <code>1 sat<
 	STOPHELP: "Der Bot stoppt das Anzeigen von Informationen.",
 
 	PAYPROMPT: `
-{{if .Sats}}<i>{{.Sats}} sat</i> ({{dollar .Sats}})
+{{if .Sats}}<i>{{.Sats}} sat</i> ({{fiat .Sats $.FiatCurrency}})
 {{end}}{{if .Description}}<i>{{.Description}}</i>{{else}}<code>{{.DescriptionHash}}</code>{{end}}
 {{if .ReceiverName}}
 <b>Receiver</b>: {{.ReceiverName}}{{end}}
@@ -304,9 +322,10 @@ Reagier mit einem :zap: um das zu bestätigen.{{end}}
     `,
 	FAILEDDECODE: "Dekodieren der Rechnung gescheitert: {{.Err}}",
 	BALANCEMSG: `🏛
-<b>Full Balance</b>: {{printf "%.15g" .Sats}} sat ({{dollar .Sats}})
-<b>Usable Balance</b>: {{printf "%.15g" .Usable}} sat ({{dollar .Usable}})
-<b>Total received</b>: {{printf "%.15g" .Received}} sat
+<b>Full Balance</b>: {{printf "%.15g" .Sats}} sat ({{.Fiat}})
+<b>Usable Balance</b>: {{printf "%.15g" .Usable}} sat ({{.UsableFiat}})
+{{if .Pending}}<b>Ausstehend</b>: {{printf "%.15g" .Pending}} sat ({{.PendingFiat}}), eingehend und noch nicht verfügbar
+{{end}}<b>Total received</b>: {{printf "%.15g" .Received}} sat
 <b>Total sent</b>: {{printf "%.15g" .Sent}} sat
 <b>Total fees paid</b>: {{printf "%.15g" .Fees}} sat
 
@@ -316,7 +335,7 @@ Reagier mit einem :zap: um das zu bestätigen.{{end}}
 	TAGGEDBALANCEMSG: `
 <b>Total of</b> <code>received - spent</code> <b>on internal and third-party</b> /apps<b>:</b>
 
-{{range .Balances}}<code>{{.Tag}}</code>: <i>{{printf "%.15g" .Balance}} sat</i>  ({{dollar .Balance}})
+{{range .Balances}}<code>{{.Tag}}</code>: <i>{{printf "%.15g" .Balance}} sat</i>  ({{fiat .Balance $.FiatCurrency}})
 {{else}}
 <i>No tagged transactions made yet.</i>
 {{end}}
@@ -331,9 +350,9 @@ Preis: {{.Prize}}
 Registrierte Teilnehmer: {{.Registered}}
     `,
 	INVALIDPARTNUMBER: "Ungültige Anzahl an Teilnehmern: {{.Number}}",
-	USERSENTTOUSER:    "💛 {{menuItem .Sats .RawSats true }} ({{dollar .Sats}}) gesendet an {{.User}}{{if .ReceiverHasNoChat}} ({{.User}} konnte nicht informiert werden, weil dieser keinen Chat mit dem bot gestartet hat){{end}}.",
-	USERSENTYOUSATS:   "💛 {{.User}} Nutzer hat dir gesendet {{menuItem .Sats .RawSats false}} ({{dollar .Sats}}){{if .BotOp}} on a {{.BotOp}}{{end}}.",
-	RECEIVEDSATSANON:  "💛 Jemand hat dir  {{menuItem .Sats .RawSats false}} ({{dollar .Sats}} gesendet).",
+	USERSENTTOUSER:    "💛 {{menuItem .Sats .RawSats true }} ({{fiat .Sats $.FiatCurrency}}) gesendet an {{.User}}{{if .ReceiverHasNoChat}} ({{.User}} konnte nicht informiert werden, weil dieser keinen Chat mit dem bot gestartet hat){{end}}.{{if .Note}}\n📝 <i>{{.Note}}</i>{{end}}",
+	USERSENTYOUSATS:   "💛 {{.User}} Nutzer hat dir gesendet {{menuItem .Sats .RawSats false}} ({{fiat .Sats $.FiatCurrency}}){{if .BotOp}} on a {{.BotOp}}{{end}}.{{if .Note}}\n📝 <i>{{.Note}}</i>{{end}}",
+	RECEIVEDSATSANON:  "💛 Jemand hat dir  {{menuItem .Sats .RawSats false}} ({{fiat .Sats $.FiatCurrency}} gesendet).{{if .Note}}\n📝 <i>{{.Note}}</i>{{end}}",
 	FAILEDSEND:        "Senden fehlgeschlagen: ",
 	QRCODEFAIL:        "QR Code konnte nicht erfolgreich gelesen werden: {{.Err}}",
 	SAVERECEIVERFAIL:  "Speichern des Empfängers gescheitet. Das ist wahrscheinlich ein bug.",
@@ -399,12 +418,12 @@ Viel Erfolg! 🍽️
 {{if .Txn.Payee.Valid}}<b>Payee</b>: {{.Txn.Payee.String | nodeLink}} (<u>{{.Txn.Payee.String | nodeAlias}}</u>){{end}}
 <b>Hash</b>: <code>{{.Txn.Hash}}</code>{{end}}{{if .Txn.Preimage.String}}
 <b>Preimage</b>: <code>{{.Txn.Preimage.String}}</code>{{end}}
-<b>Amount</b>: <i>{{.Txn.Amount | printf "%.15g"}} sat</i> ({{dollar .Txn.Amount}})
+<b>Amount</b>: <i>{{.Txn.Amount | printf "%.15g"}} sat</i> ({{.Txn.FiatAmount $.FiatCurrency}})
 {{if not (eq .Txn.Status "RECEIVED")}}<b>Fee paid</b>: <i>{{printf "%.15g" .Txn.Fees}} sat</i>{{end}}
 {{.LogInfo}}
     `,
 	TXLIST: `<b>{{if .Offset}}Transaktion von {{.From}} an {{.To}}{{else}}Latest {{.Limit}} transactions{{end}}</b>
-{{range .Transactions}}<code>{{.StatusSmall}}</code> <code>{{.Amount | paddedSatoshis}}</code> {{.Icon}} {{.PeerActionDescription}}{{if not .TelegramPeer.Valid}}<i>{{.Description}}</i>{{end}} <i>{{.Time | timeSmall}}</i> /tx_{{.HashReduced}}
+{{range .Transactions}}<code>{{.StatusSmall}}</code> <code>{{.Amount | paddedSatoshis}}</code> ({{.FiatAmount $.FiatCurrency}}{{if .Fees}}, Gebühr {{.Fees | printf "%.15g"}}{{end}}) {{.Icon}} {{.PeerActionDescription}}{{if .Description}}<i>{{.Description | makeLinks}}</i>{{end}} <i>{{.Time | timeSmall}}</i> /tx_{{.HashReduced}}
 {{else}}
 <i>Bisher keine Transaktion vorgenommen.</i>
 {{end}}
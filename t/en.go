@@ -26,6 +26,8 @@ var EN = map[Key]string{
 	CALLBACKSENDING: "Sending payment.",
 
 	INLINEINVOICERESULT:  "Payment request for {{.Sats}} sat.",
+	INLINEVOUCHERRESULT:  "Voucher of {{.Sats}} sat anyone can withdraw.",
+	INLINEVOUCHERMSG:     "{{.User}} is sending {{.Sats}} sat! Withdraw them with a wallet that supports lnurl: <code>{{.LNURL}}</code>",
 	INLINEGIVEAWAYRESULT: "Give {{.Sats}} sat {{if .Receiver}}to @{{.Receiver}}{{else}}away{{end}}",
 	INLINEGIVEFLIPRESULT: "Give away {{.Sats}} sat to one out of {{.MaxPlayers}} participants",
 	INLINECOINFLIPRESULT: "Lottery with entry fee of {{.Sats}} sat for {{.MaxPlayers}} participants",
@@ -39,9 +41,19 @@ lnurl-auth success!
 <b>Domain</b>: <i>{{.Host}}</i>
 <b>Public Key</b>: <i>{{.PublicKey}}</i>
 `,
+	LNURLAUTHTHROTTLED: "Too many login attempts on <b>{{.Host}}</b>. Please wait {{if .Minutes}}{{.Minutes}} minutes{{else}}a little{{end}} before trying again.",
+	LNURLAUTHHELP: `Shows the public key you would log in with on a domain using lnurl-auth, without logging in.
+
+<code>/lnurlauth example.com</code>
+<code>/lnurlauth lnurl1...</code>
+    `,
+	LNURLAUTHKEY: `🔑 Your lnurl-auth key on <b>{{.Host}}</b>:
+<code>{{.PublicKey}}</code>`,
 	LNURLPAYPROMPT: `🟢 <code>{{.Domain}}</code> expects {{if .FixedAmount}}<i>{{.FixedAmount | printf "%.15g"}} sat</i>{{else}}a value between <i>{{.Min | printf "%.15g"}}</i> and <i>{{.Max | printf "%.15g"}} sat</i>{{end}} for:
 
-<code>{{if .Long}}{{.Long | html}}{{else}}{{.Text | html}}{{end}}</code>{{if .WillSendPayerData}}
+<code>{{.Text | html}}</code>{{with .Long}}
+
+<i>{{. | html}}</i>{{end}}{{if .WillSendPayerData}}
 
 ---
 
@@ -49,23 +61,33 @@ lnurl-auth success!
 - To prevent that, use <code>/lnurl --anonymous &lt;lnurl&gt;</code>.
 {{end}}
 
-{{if not .FixedAmount}}<b>Reply with the amount (in satoshis, between <i>{{.Min | printf "%.15g"}}</i> and <i>{{.Max | printf "%.15g"}}</i>) to confirm.</b>{{end}}
+{{if not .FixedAmount}}<b>Reply with the amount (in satoshis, between <i>{{.Min | printf "%.15g"}}</i> and <i>{{.Max | printf "%.15g"}}</i>, or in fiat, like <code>5 usd</code>) to confirm.</b>{{end}}
     `,
-	LNURLPAYPROMPTCOMMENT: `📨 <code>{{.Domain}}</code> expects a comment.
+	LNURLPAYPROMPTCOMMENT: `📨 <code>{{.Domain}}</code> accepts a comment{{if .MaxLength}} of up to {{.MaxLength}} characters{{end}}.
 
-<b>To confirm the payment, reply with some text</b>`,
+<b>To confirm the payment, reply with some text, or with <code>-</code> to send no comment.</b>`,
+	LNURLWITHDRAWPROMPT: `🔵 <code>{{.Domain}}</code> lets you withdraw between <i>{{.Min | printf "%.15g"}}</i> and <i>{{.Max | printf "%.15g"}} sat</i>{{if .Text}} for:
+
+<i>{{.Text | html}}</i>{{end}}
+
+<b>Reply with the amount you want to withdraw.</b>`,
 	LNURLPAYAMOUNTSNOTICE: `<code>{{.Domain}}</code> expected {{if .Exact}}{{.Min | printf "%.3f"}}{{else if .NoMax}}at least{{.Min | printf "%.0f"}}{{else}}between {{.Min | printf "%.0f"}} and {{.Max | printf "%.0f"}}{{end}} sat.`,
-	LNURLPAYSUCCESS: `<code>{{.Domain}}</code> says:
+	LNURLPAYFIATCONFIRM:   `💱 Pay <i>{{.Fiat | printf "%.2f"}} {{.Currency}}</i> (<i>{{.Sats | printf "%.15g"}} sat</i>) to <code>{{.Domain}}</code>?`,
+	LNURLPAYSUCCESS: `✅ Payment to <code>{{.Domain}}</code> succeeded. /tx_{{.HashFirstChars}}
+<code>{{.Hash}}</code>{{if .HasAction}}
+
+<code>{{.Domain}}</code> says:
 {{.Text}}
-{{if .DecipherError}}Failed to decipher ({{.DecipherError}}):
+{{if .DecipherError}}⚠️ The secret sent by <code>{{.Domain}}</code> couldn't be revealed: <i>{{.DecipherError}}</i>. Ask them for it using the hash above.
 {{end}}{{if .Value}}<pre>{{.Value}}</pre>
-{{end}}{{if .URL}}<a href="{{.URL}}">{{.URL}}</a>{{end}}
+{{end}}{{if .URL}}<a href="{{.URL}}">{{.URL}}</a>{{end}}{{end}}
     `,
 	LNURLPAYMETADATA: `#lnurlpay metadata:
 <b>domain</b>: <i>{{.Domain}}</i>
 <b>transaction</b>: /tx_{{.HashFirstChars}}
     `,
 	LNURLBALANCECHECKCANCELED: "Automatic balance checks from {{.Service}} are cancelled.",
+	LNURLWITHDRAWEXPIRED:      "⌛ The withdrawal of <i>{{.Sats}} sat</i> from <b>{{.Host}}</b> didn't complete: the invoice expired before they paid it.",
 
 	TICKETSET:         "New entrants will have to pay an invoice of {{.Sat}} sat (make sure you've set @lntxbot as administrator for this to work).",
 	TICKETUSERALLOWED: "Ticket paid. {{.User}} allowed.",
@@ -78,9 +100,13 @@ You have 15 minutes to do it or you'll be kicked and banned for one day.
 	RENAMEPROMPT:      "Pay <b>{{.Sats}} sat</b> to rename this group to <i>{{.Name}}</i>?",
 	GROUPNOTRENAMABLE: "This group is not renamable!",
 
+	TIPLIMITMSG:      "{{if .Sat}}Tips and giveaways in this group are now limited to {{.Sat}} sat.{{else}}Tips and giveaways in this group are not limited anymore.{{end}}",
+	TIPLIMITEXCEEDED: "The amount exceeds this group's limit of {{.Sat}} sat.",
+
 	INTERNALPAYMENTUNEXPECTED: "Something odd has happened. If this is an internal invoice it will fail. Maybe the invoice has expired or something else we don't know. If it is an external invoice ignore this warning.",
-	PAYMENTFAILED:             "❌ Payment failed.\n\n<i>{{.FailureString}}</i>",
-	PAIDMESSAGE: `✅ Paid with <i>{{printf "%.15g" .Sats}} sat</i> ({{dollar .Sats}}) (+ <i>{{.Fee}}</i> fee). 
+	PAYMENTFAILED:             "❌ Payment failed{{with .Attempts}}{{if gt . 1}} after {{counter $.Roman .}} attempts{{end}}{{end}}.\n\n<i>{{.FailureString}}</i>",
+	PAYMENTPROGRESS:           "⏳ Payment of <i>{{.Sats | printf \"%.15g\"}} sat</i> still in progress after {{.Seconds}}s, it may be taking many routes. /tx_{{.Hash}}",
+	PAIDMESSAGE: `✅ Paid with <i>{{printf "%.15g" .Sats}} sat</i> ({{fiat .Sats $.FiatCurrency}}) (+ <i>{{.Fee}}</i> fee){{with .Attempts}}{{if gt . 1}} after {{counter $.Roman .}} attempts{{end}}{{end}}. 
 
 <b>Hash:</b> <code>{{.Hash}}</code>{{if .Preimage}}
 <b>Proof:</b> <code>{{.Preimage}}</code>{{end}}
@@ -92,7 +118,7 @@ You have 15 minutes to do it or you'll be kicked and banned for one day.
 	INSUFFICIENTBALANCE: `Insufficient balance for {{.Purpose}}. Needs {{.Sats | printf "%.15g"}} sat more.`,
 
 	PAYMENTRECEIVED: `
-      ⚡️ Payment received{{if .SenderName}} from <i>{{ .SenderName }}</i>{{end}}: {{.Sats}} sat ({{dollar .Sats}}). /tx_{{.Hash}}{{if .Message}} {{.Message | messageLink}}{{end}} #tx
+      ⚡️ Payment received{{if .SenderName}} from <i>{{ .SenderName }}</i>{{end}}: {{.Sats}} sat ({{fiat .Sats $.FiatCurrency}}). /tx_{{.Hash}}{{if .Message}} {{.Message | messageLink}}{{end}} #tx
       {{if .Comment}}
 📨 <i>{{.Comment}}</i>
       {{end}}
@@ -101,12 +127,41 @@ You have 15 minutes to do it or you'll be kicked and banned for one day.
 
 	SPAMMYMSG:             "{{if .Spammy}}This group is now spammy.{{else}}Not spamming anymore.{{end}}",
 	COINFLIPSENABLEDMSG:   "Coinflips are {{if .Enabled}}enabled{{else}}disabled{{end}} in this group.",
+	TREASURYENABLEDMSG:    "{{if .Enabled}}Admins of this group now spend from the group account with /balance, /pay and /send.{{else}}Admins of this group use their own accounts again.{{end}}",
 	LANGUAGEMSG:           "This chat language is set to <code>{{.Language}}</code>.",
+	CURRENCYMSG:           "Your amounts will be displayed in <code>{{.Currency}}</code>.",
+	PAYCONFIRMMSG:         "{{if .Sats}}Invoices of up to {{.Sats}} sat will be paid without asking for confirmation.{{else}}All invoices will ask for confirmation before being paid.{{end}}",
+	SPENDALERTMSG:         "{{if .Sats}}You'll get an alert for every payment over {{.Sats}} sat.{{else}}Spending alerts are off.{{end}}",
+	INVOICEDESCRIPTIONMSG: "Invoices made without a description will say <i>{{.Description}}</i>.{{if not .Custom}} Use /toggle_description followed by some text to change it, {user}, {username} and {service} are replaced.{{end}}",
+	SPENDALERT:            "⚠️ {{.Sats}} sat just left your account{{with .To}} to {{.}}{{end}}{{with .Description}} ({{.}}){{end}}. You get these alerts for payments over {{.Threshold}} sat, see /toggle_alert.",
+	SKIPQRMSG:             "Your invoices will be sent {{if .Skip}}as text only{{else}}with a QR code{{end}}.",
+	ROMANMSG:              "{{if .Roman}}🏛️ Counters will be shown in roman numerals, like {{counter .Roman 2021}}.{{else}}Counters will be shown in plain numbers.{{end}}",
 	FREEJOIN:              "This group is now free to join.",
 	EXPENSIVEMSG:          "Every message in this group{{with .Pattern}} containing the pattern <code>{{.}}</code>{{end}} will cost {{.Price}} sat.",
 	EXPENSIVENOTIFICATION: "The message {{.Link}} just {{if .Sender}}cost{{else}}earned{{end}} you {{.Price}} sat.",
 	FREETALK:              "Messages are free again",
 
+	ERRINSUFFICIENTBALANCE: "Insufficient balance.",
+	ERRDATABASE:            "Database error.",
+	ERRINVALIDAMOUNT:       "Invalid amount.",
+	ERRINVOICEEXPIRED:      "This invoice has expired{{if .Minutes}} {{.Minutes}} minute{{s .Minutes}} ago{{end}}.",
+	ERRNOROUTE:             "Couldn't find a route to the receiver.",
+	ERRTIMEOUT:             "Operation has timed out{{if .Seconds}} after {{.Seconds}} seconds{{end}}.",
+	ERRLIGHTNINGNODE:       "Lightning node error: {{.Message}}",
+	ERRALREADYPAYING:       "Already paying this invoice.",
+	ERRWRONGNETWORK:        "This is a {{.Invoice}} invoice, but this bot is on {{.Bot}}.",
+	ERRRATELIMITED:         "Slow down! You can make at most {{.Limit}} payment{{s .Limit}} per minute, try again in a moment.",
+	ERRACCOUNTFROZEN:       "🧊 Your account is frozen, nothing can be sent from it. Use /unfreeze if it was you who froze it.",
+	ERRTWOFACTORREQUIRED:   "Payments over {{.Threshold}} sat need your 2FA code.{{if .Prompted}} Reply to the message above with it.{{else}} They can only be confirmed in a private chat with the bot on Telegram.{{end}}",
+	ERRINVOICEAMOUNT:       "{{if .Amountless}}Invoices without an amount can't be made, they must be of at most {{.Max}} sat.{{else}}Invoices must be {{if and .Min .Max}}between {{.Min}} and {{.Max}} sat{{else if .Max}}of at most {{.Max}} sat{{else}}of at least {{.Min}} sat{{end}}.{{end}}",
+	ERRUNEXPECTED:          "Something went wrong. Try again, and if it keeps happening please report it.",
+	ERRPAYYOURSELF:         "Can't pay yourself.",
+	ERRINVALIDINVOICE:      "Invalid invoice.",
+	ERRUNKNOWNINVOICE:      "Can't pay internal invoice that isn't from the bot.",
+	ERRINTERNALAMOUNT:      "Invoice is for {{.Sats}} sat, can't pay {{if .More}}more than the double{{else}}less{{end}}.",
+	ERRFEELIMIT:            "This payment costs at least {{.Min}} sat in fees, above your limit of {{.Limit}} ({{.Max}} sat). Use /setmaxfee to change it.",
+	ERRINVALIDFEELIMIT:     "Invalid fee limit, use an amount in sat or a percentage like 1%.",
+
 	APPBALANCE: `#{{.App | lower}} Balance: <i>{{printf "%.15g" .Balance}} sat</i>`,
 
 	HELPINTRO: `
@@ -129,6 +184,7 @@ Can also be called as an <a href="https://core.telegram.org/bots/inline">inline
 	RECEIVEHELP: `Generates a BOLT11 invoice with given satoshi value. Amounts will be added to your @lntxbot balance. If you don't provide the amount it will be an open-ended invoice that can be paid with any amount.",
 
 <code>/receive_320_for_something</code> generates an invoice for 320 sat with the description "for something"
+<code>@lntxbot 1000</code> in any chat shares an invoice for 1000 sat there, and <code>@lntxbot lnurlw 1000</code> a voucher anyone can withdraw 1000 sat from.
     `,
 
 	PAYHELP: `Decodes a BOLT11 invoice and asks if you want to pay it (unless /paynow). This is the same as just pasting or forwarding an invoice directly in the chat. Taking a picture of QR code containing an invoice works just as well (if the picture is clear).
@@ -145,6 +201,8 @@ Just pasting <code>lnbc1u1pwvmypepp5kjydaerr6rawl9zt7t2zzl9q0rf6rkpx7splhjlfnjr8
 <code>/tip 100</code>, when sent as a reply to a message in a group where the bot is added, sends 100 satoshis to the author of the message.
 <code>/send 500 @username</code> sends 500 satoshis to Telegram user @username.
 <code>/send anonymously 1000 @someone</code> same as above, but telegram user @someone will see just: "Someone has sent you 1000 satoshis".
+<code>/tip 1000 @alice @bob @carol</code> splits 1000 satoshis among the three users, giving any remainder to the first.
+<code>/sendmany @alice=1000 @bob=2500</code> sends different amounts to many users at once.
     `,
 
 	TRANSACTIONSHELP: `
@@ -153,6 +211,8 @@ Lists all your transactions with pagination controls. Each transaction has a lin
 /transactions lists all transactions, from the most recent.
 <code>/transactions --in</code> lists only the incoming transactions.
 <code>/transactions --out</code> lists only the outgoing transactions.
+
+/history is the same as /transactions.
     `,
 
 	BALANCEHELP: "Shows your current balance in satoshis, plus the sum of everything you've received and sent within the bot and the total amount of fees paid.",
@@ -193,14 +253,49 @@ You have 15 minutes to pay or you will be kicked.
 	GIVEFLIPJOIN:      "Try to win!",
 	GIVEFLIPWINNERMSG: "{{.Sender}} sent {{.Sats}} to {{.Receiver}}. These didn't get anything: {{.Losers}}.{{if .ReceiverHasNoChat}} To manage your funds, start a conversation with @lntxbot.{{end}}",
 
+	FREEZEHELP: `Freezes your account if you think someone else got access to it. Nothing can be paid, sent or withdrawn from a frozen account, but it still receives payments.
+
+/unfreeze asks for a confirmation before allowing payments again, or for your code if you use /2fa: <code>/unfreeze 123456</code>.
+    `,
+	FREEZEMSG:      "{{if .Frozen}}🧊 Your account is frozen. Payments to it still arrive, but nothing can leave it until you /unfreeze it.{{else}}Your account isn't frozen.{{end}}",
+	UNFREEZEPROMPT: "{{if .TwoFactor}}Send <code>/unfreeze &lt;code&gt;</code> with your 2FA code to unfreeze your account.{{else}}Unfreeze your account and allow payments from it again?{{end}}",
+
+	TWOFACTORHELP: `Requires a code from an authenticator app (Google Authenticator, Aegis, andOTP and others) for payments above an amount you choose, so someone who gets into your Telegram account can't take everything.
+
+<code>/2fa on 50000</code>: payments and transfers to other users over 50000 sat will ask for a code. You'll get a secret to add to your app, then confirm it with <code>/2fa verify 123456</code>.
+<code>/2fa off 123456</code>: turns it off, also needs a code.
+
+Giveaways, drops and anything else other people claim from you can't ask for a code, so those fail above the limit. After a few wrong codes in a row no code is accepted for a while.
+    `,
+	TWOFACTORSTATUS:  "{{if .Threshold}}🔐 Payments over {{.Threshold}} sat ask for your 2FA code.{{else}}2FA is off. See /help_2fa.{{end}}",
+	TWOFACTORSETUP:   "Add this secret to your authenticator app:\n\n<code>{{.Secret}}</code>\n\nor open <code>{{.URI}}</code>\n\nThen send <code>/2fa verify &lt;code&gt;</code> with the code it shows to start requiring it for payments over {{.Threshold}} sat.",
+	TWOFACTORPROMPT:  "🔐 This payment of {{.Sats}} sat is over your 2FA limit of {{.Threshold}} sat. Reply to this message with the code from your authenticator app to send it.",
+	TWOFACTORINVALID: "{{if .Locked}}Too many wrong 2FA codes. Try again in {{.Minutes}} minutes.{{else}}Wrong or already used 2FA code.{{end}}",
+
+	DROPHELP: `Drops a pot in the group that is split between the first people to claim it. Each share is sent from your balance as it is claimed, so whatever isn't claimed before the drop expires never leaves your wallet.
+
+<code>/drop 1000 5</code>: the first 5 people to click get 200 satoshis each.
+    `,
+	DROPMSG: `
+{{.User}} dropped {{.Sats}} sat!
+The first {{.Participants}} to claim get {{.Share}} sat each.
+{{.Progress}} {{.Claimed}}/{{.Participants}}{{with .Claimers}}
+Claimed by: {{.}}{{end}}
+    `,
+	DROPCLAIM: "Claim!",
+	DROPEMPTY: "This drop is over.",
+
 	FUNDRAISEHELP: `Starts a crowdfunding event with a predefined number of participants and contribution amount. If the given number of participants contribute, it will be actualized. Otherwise it will be canceled in some hours.
 
 <code>/fundraise 10000 8 @user</code>: Telegram @user will get 80000 satoshis after 8 people contribute.
+<code>/fundraise 5000 4 @user new microphone</code>: same thing, with a title for the campaign.
     `,
 	FUNDRAISEAD: `
-Fundraising {{.Fund}} to {{.ToUser}}!
+Fundraising {{.Fund}} to {{.ToUser}}!{{with .Title}}
+<b>{{.}}</b>{{end}}
 Contributors needed for completion: {{.Participants}}
 Each pays: {{.Sats}} sat
+{{.Progress}} {{.Contributed}}/{{.Participants}}
 Have contributed: {{.Registered}}
     `,
 	FUNDRAISEJOIN:        "Contribute!",
@@ -262,7 +357,177 @@ A reveal prompt can also be created in a group or chat by clicking the "share" b
 /toggle_ticket_10 starts charging a fee for all new entrants. Useful as an antispam measure. The money goes to the group owner.
 /toggle_ticket stops charging new entrants a fee. 
 /toggle_language_ru changes the chat language to Russian, /toggle_language displays the chat language, these also work in private chats.
+/toggle_currency_eur changes the fiat currency your amounts are displayed in, /toggle_currency displays it. Only works in private chats.
+/toggle_confirm_100 pays invoices of up to 100 sat without asking for confirmation, /toggle_confirm always asks. Only works in private chats.
+/toggle_alert_5000 sends you an extra alert whenever more than 5000 sat leave your account, /toggle_alert turns alerts off. Only works in private chats.
+/toggle_description Tips for {user} sets the description used on invoices you make without one, {user}, {username} and {service} are replaced. /toggle_description resets it. Only works in private chats.
+/toggle_qr toggles the QR code image on the invoices you make, for when you only want the text to copy. Only works in private chats.
+/toggle_roman shows counters like payment attempts in roman numerals, just for fun. Only works in private chats.
+/toggle_tiplimit_1000 limits tips and giveaways in the group to 1000 sat, /toggle_tiplimit removes the limit.
+/toggle_treasury makes admins use the group account for /balance, /pay and /send in the group, see /help_treasury.
 /toggle_spammy toggles 'spammy' mode. 'spammy' mode is off by default. When turned on, tip notifications will be sent in the group instead of only privately.
+    `,
+
+	ADDRESSHELP: `Shows your <a href="https://lightningaddress.com">Lightning Address</a>, which anyone can use to send you money from compatible wallets.
+
+/address_alice claims <code>alice</code> as an additional address for your account.
+    `,
+	LIGHTNINGADDRESSMSG: `{{if or .Telegram .Alias}}⚡️ You can receive payments at:
+{{if .Telegram}}
+<code>{{.Telegram}}</code>{{end}}{{if .Alias}}
+<code>{{.Alias}}</code>{{end}}{{else}}You don't have a Lightning Address yet. Choose one with <code>/address &lt;name&gt;</code>.{{end}}`,
+
+	TIPJARHELP: `Shows a static lnurl-pay, your tip jar, that anyone can pay any number of times. It's good for streams and content, as the same QR code keeps working.
+
+/tipjar_min_100 and /tipjar_max_50000 set the amounts payers can choose from.
+<code>/tipjar description Tips for my podcast</code> sets what payers see.
+<code>/tipjar image https://example.com/logo.png</code> sets the picture shown on wallets, /tipjar_image alone removes it.
+/tipjar_reset goes back to the defaults.
+    `,
+	TIPJARMSG: `<code>{{.LNURL}}</code>
+
+Payers can send between <i>{{.Min}}</i> and <i>{{.Max}} sat</i> and will see: <i>{{.Description}}</i>{{if .HasImage}}, with your picture{{end}}.`,
+
+	NODEINFOHELP: "Shows public information about a Lightning node: alias, color, channels and total capacity.",
+	NODEINFO: `{{.Id | nodeLink}}
+<b>Alias</b>: {{if .Alias}}<i>{{.Alias}}</i>{{else}}~{{end}}{{if .Color}}
+<b>Color</b>: <code>#{{.Color}}</code>{{end}}
+<b>Channels</b>: {{.Channels}}
+<b>Capacity</b>: <i>{{.Capacity}} sat</i>{{if .Scids}}
+
+{{.Scids | makeLinks}}{{if .More}} …{{end}}{{end}}
+    `,
+	NODENOTSEEN: "Node <code>{{.Id}}</code> was not seen in the public graph.",
+
+	CONVERTHELP: `Converts between satoshis and fiat without making any payment.
+
+<code>/convert 10 eur</code> shows how many satoshis 10 EUR is worth.
+<code>/convert 50000</code> shows how much 50000 sat is worth in your currency.
+    `,
+	CONVERTMSG: "💱 <i>{{.Sats | printf \"%.15g\"}} sat</i> = <i>{{.Fiat}}</i>",
+
+	PAYQUOTEHELP: `Shows how much paying an invoice would cost, including the maximum routing fee, without paying it. The route is only found when paying, so the actual fee may be lower.
+
+<code>/payquote lnbc1u1pwz...</code>
+<code>/payquote lnbc1pwz... 500</code> for invoices without an amount.
+    `,
+	PAYQUOTE: `🧾 Paying <i>{{.Sats | printf "%.15g"}} sat</i> to {{.Payee | nodeLink}}{{if .Internal}} (a user of this bot) costs no fees.{{else}}:
+<b>Maximum fee</b>: <i>{{.Fee | printf "%.15g"}} sat</i>
+<b>Maximum total</b>: <i>{{.Total | printf "%.15g"}} sat</i>{{if .Limit}}
+<b>Your fee limit</b>: {{.Limit}}{{if .AboveLimit}} ⚠️ below the least this payment costs, it will be refused.{{end}}{{end}}{{if not .Reachable}}
+
+⚠️ The payee has no public channels, it can only be reached if the invoice has route hints.{{end}}
+
+The actual fee depends on the route found when paying, unused fees are given back.{{end}}`,
+
+	SETMAXFEEHELP: `Sets the maximum routing fee you accept to pay for outgoing payments, either in satoshis or as a percentage of the amount paid. You're never charged more than that in fees, and payments that cost at least more than that (we charge 0.3% on every payment) are refused.
+
+/setmaxfee_10 limits fees to 10 sat.
+<code>/setmaxfee 0.5%</code> limits fees to 0.5% of the amount.
+/setmaxfee_off removes the limit.
+
+A limit for a single payment can also be given with <code>/pay &lt;invoice&gt; --max-fee=1%</code>.
+    `,
+	MAXFEEMSG: "{{if .Limit}}Your maximum routing fee is <b>{{.Limit}}</b>.{{else}}You have no maximum routing fee set.{{end}}",
+
+	WEBHOOKHELP: `Sets an URL that will get a POST request for every payment you receive, with a JSON body containing <code>payment_hash</code>, <code>msatoshi</code> and <code>description</code>.
+
+The request has an <code>X-Lntxbot-Signature</code> header with the HMAC-SHA256 of the body using your /api invoice token as the key, in hex.
+
+<code>/webhook https://example.com/paid</code> sets the webhook.
+/webhook_off removes it.
+    `,
+	WEBHOOKMSG: "{{if .URL}}Payments you receive will be notified to <code>{{.URL}}</code>.{{else}}You have no webhook set.{{end}}",
+
+	DECODEHELP: `Shows what is inside an invoice, an lnurl or a lightning address without paying, withdrawing or logging in. It also works as a reply to a message that contains one.
+
+<code>/decode lnbc1...</code> shows the amount, description, expiry, payee and hash of an invoice.
+<code>/decode name@domain.com</code> shows what the service of a lightning address expects.
+    `,
+	DECODEINVOICE: `
+{{if .Sats}}<i>{{.Sats}} sat</i> ({{fiat .Sats $.FiatCurrency}})
+{{end}}{{if .Description}}<i>{{.Description}}</i>{{else}}<code>{{.DescriptionHash}}</code>{{end}}
+{{if .ReceiverName}}
+<b>Receiver</b>: {{.ReceiverName}}{{end}}
+<b>Hash</b>: <code>{{.Hash}}</code>{{if ne .Currency "bc"}}
+<b>Chain</b>: {{.Currency}}{{end}}
+<b>Created at</b>: {{.Created}}
+<b>Expires at</b>: {{.Expiry}}{{if .Expired}} <b>[EXPIRED]</b>{{end}}{{if .Hints}}
+<b>Hints</b>: {{range .Hints}}
+- {{range .}}{{.ShortChannelId | channelLink}}: {{.PubKey | nodeAliasLink}}{{end}}{{end}}{{end}}
+<b>Payee</b>: {{.Payee | nodeAliasLink}}
+    `,
+	DECODELNURL: `<b>{{.Kind}}</b> at <code>{{.Host}}</code>{{with .Identifier}}
+<b>Identifier</b>: <code>{{.}}</code>{{end}}{{if .Max}}
+<b>Amount</b>: {{if eq .Min .Max}}{{.Min | printf "%.15g"}}{{else}}{{.Min | printf "%.15g"}} to {{.Max | printf "%.15g"}}{{end}} sat{{end}}{{with .Description}}
+<b>Description</b>: <i>{{. | html}}</i>{{end}}{{with .Long}}
+<i>{{. | html}}</i>{{end}}{{with .Comment}}
+<b>Comment</b>: up to {{.}} characters{{end}}
+<b>Callback</b>: <code>{{.Callback | html}}</code>`,
+
+	STATSHELP: "Shows how many payments you've made and received and your level. The more you use your wallet, the higher it gets.",
+	STATSMSG: `🏅 <b>Level {{roman .Level}}</b>
+
+📤 Sent: {{.Stats.Sent}} payment{{s .Stats.Sent}}, <i>{{.SatsSent | printf "%.15g"}} sat</i>
+📥 Received: {{.Stats.Received}} payment{{s .Stats.Received}}, <i>{{.SatsReceived | printf "%.15g"}} sat</i>
+
+{{.Missing}} more payment{{s .Missing}} to reach level {{roman .NextLevel}}.`,
+
+	TREASURYHELP: `Shows the balance of the group account, which admins can spend from after /toggle_treasury, or adds money to it from your account.
+
+/treasury_1000 sends 1000 sat from your account to the group account.
+    `,
+	TREASURYMSG: `{{if .Sats}}{{.User}} added {{.Sats}} sat to the group account.
+{{end}}💰 The group account has {{.Balance | printf "%.15g"}} sat.
+{{if .Enabled}}Admins spend from it with /balance, /pay and /send in this group.{{else}}Admins can spend from it after /toggle_treasury.{{end}}`,
+
+	LANGUAGEHELP: `Shows the languages the bot speaks, with buttons to choose yours.
+
+/language_es switches to Spanish directly.
+    `,
+
+	QRHELP: `Makes a QR code out of any text, like an invoice, an lnurl or an address, so it can be scanned from your screen.
+
+<code>/qr lnbc1...</code> shows the QR code for an invoice.
+Reply to a message with /qr to get the QR code of its text.
+    `,
+
+	PRICEALERTHELP: `Notifies you when the bitcoin price crosses a threshold. Alerts are removed after they are triggered.
+
+/pricealert_above_100000_usd will notify you when 1 BTC is worth more than 100000 USD. If no currency is given your /toggle_currency is used.
+/pricealert_below_50000 will notify you when 1 BTC is worth less than 50000.
+/pricealert_remove_3 removes the alert with id 3.
+/pricealert lists your alerts.
+    `,
+	PRICEALERTS: `{{range .Alerts}}<code>{{.Id}}</code>: 1 BTC {{.Direction}} <i>{{.Price | printf "%.2f"}} {{.Currency}}</i>
+{{else}}<i>You have no price alerts.</i>
+{{end}}`,
+	PRICEALERTTRIGGERED: "🔔 1 BTC is now worth <i>{{.Price | printf \"%.2f\"}} {{.Alert.Currency}}</i>, {{.Alert.Direction}} your alert at <i>{{.Alert.Price | printf \"%.2f\"}} {{.Alert.Currency}}</i>.",
+
+	SCHEDULEHELP: `Sends a fixed amount to someone every day, week or month, starting one period from now. The receiver can be a Telegram user or a lightning address.
+
+/schedule_weekly_1000_@someone sends 1000 sat to @someone every week.
+<code>/schedule monthly 5000 name@domain.com</code> pays 5000 sat to a lightning address every month.
+/schedule_cancel_3 cancels the schedule with id 3.
+/schedule lists your scheduled payments.
+
+If a payment fails, for example for lack of balance, you are notified and it is skipped until the next period.
+    `,
+	SCHEDULES: `{{range .Schedules}}<code>{{.Id}}</code>: <i>{{.Sats | printf "%.15g"}} sat</i> to {{.ReceiverName}} every {{.Period}}, next on {{.NextRun.Format "Jan 2 15:04"}}
+{{else}}<i>You have no scheduled payments.</i>
+{{end}}`,
+	SCHEDULEFAILED: "❌ Scheduled payment <code>{{.Schedule.Id}}</code> of <i>{{.Schedule.Sats | printf \"%.15g\"}} sat</i> to {{.Schedule.ReceiverName}} failed and was skipped: {{.Err}}",
+
+	MENUHELP: `Lists the named amounts that can be used instead of a number of satoshis, like /tip_coffee or /send_2*banana.
+
+/menu_add_coffee_2100 adds a custom item to the group menu, /menu_remove_coffee removes it. Only group admins can change the menu.
+    `,
+	MENUMSG: `{{if .Custom}}<b>Menu of this chat</b>:
+{{range .Custom}}<code>{{.Name}}</code>: <i>{{.Sats | printf "%.15g"}} sat</i>
+{{end}}
+{{end}}<b>Default menu</b>:
+{{range .Default}}<code>{{.Name}}</code>: <i>{{.Sats | printf "%.15g"}} sat</i>
+{{end}}
     `,
 
 	SATS4ADSHELP: `
@@ -283,7 +548,7 @@ To broadcast an ad you must send a message to the bot that will be your ad conte
 /sats4ads_broadcast_1000 broadcasts an ad. The last number is the maximum number of satoshis that will be spend. Cheaper ad-listeners will be preferred over more expensive ones. Must be called in a reply to another message, the contents of which will be used as the ad text.
     `,
 	SATS4ADSTOGGLE:    `#sats4ads {{if .On}}Seeing ads and receiving {{printf "%.15g" .Sats}} sat per character.{{else}}You won't see any more ads.{{end}}`,
-	SATS4ADSBROADCAST: `#sats4ads {{if .NSent}}Message broadcasted {{.NSent}} time{{s .NSent}} for a total cost of {{.Sats}} sat ({{dollar .Sats}}).{{else}}Couldn't find a peer to notify with the given parameters. /sats4ads_rates{{end}}`,
+	SATS4ADSBROADCAST: `#sats4ads {{if .NSent}}Message broadcasted {{counter $.Roman .NSent}} time{{s .NSent}} for a total cost of {{.Sats}} sat ({{fiat .Sats $.FiatCurrency}}).{{else}}Couldn't find a peer to notify with the given parameters. /sats4ads_rates{{end}}`,
 	SATS4ADSSTART:     `Message being broadcasted.`,
 	SATS4ADSPRICETABLE: `#sats4ads Quantity of users <b>up to</b> each pricing tier.
 {{range .Rates}}<code>{{.UpToRate}} msat</code>: <i>{{.NUsers}} user{{s .NUsers}}</i>
@@ -300,7 +565,7 @@ Each ad costs the above prices <i>per character</i> + <code>1 sat</code> for eac
 	STOPHELP: "The bot stops showing you notifications.",
 
 	PAYPROMPT: `
-{{if .Sats}}<i>{{.Sats}} sat</i> ({{dollar .Sats}})
+{{if .Sats}}<i>{{.Sats}} sat</i> ({{fiat .Sats $.FiatCurrency}})
 {{end}}{{if .Description}}<i>{{.Description}}</i>{{else}}<code>{{.DescriptionHash}}</code>{{end}}
 {{if .ReceiverName}}
 <b>Receiver</b>: {{.ReceiverName}}{{end}}
@@ -317,11 +582,14 @@ React with a :zap: to confirm.{{end}}
 {{else}}<b>Reply with the desired amount to confirm.</b>
 {{end}}
     `,
-	FAILEDDECODE: "Failed to decode invoice: {{.Err}}",
+	PAYCHOOSE:       "This message contains {{.N}} invoices. Which one do you want to pay?",
+	PAYCHOOSEBUTTON: `{{if .Sats}}{{.Sats | printf "%.15g"}} sat{{else}}any amount{{end}}{{if .Description}}: {{.Description}}{{end}}`,
+	FAILEDDECODE:    "Failed to decode invoice: {{.Err}}",
 	BALANCEMSG: `🏛
-<b>Full Balance</b>: {{printf "%.15g" .Sats}} sat ({{dollar .Sats}})
-<b>Usable Balance</b>: {{printf "%.15g" .Usable}} sat ({{dollar .Usable}})
-<b>Total received</b>: {{printf "%.15g" .Received}} sat
+<b>Full Balance</b>: {{printf "%.15g" .Sats}} sat ({{.Fiat}})
+<b>Usable Balance</b>: {{printf "%.15g" .Usable}} sat ({{.UsableFiat}})
+{{if .Pending}}<b>Pending</b>: {{printf "%.15g" .Pending}} sat ({{.PendingFiat}}), incoming and not spendable yet
+{{end}}<b>Total received</b>: {{printf "%.15g" .Received}} sat
 <b>Total sent</b>: {{printf "%.15g" .Sent}} sat
 <b>Total fees paid</b>: {{printf "%.15g" .Fees}} sat
 
@@ -331,7 +599,7 @@ React with a :zap: to confirm.{{end}}
 	TAGGEDBALANCEMSG: `
 <b>Total of</b> <code>received - spent</code> <b>on internal and third-party</b> /apps<b>:</b>
 
-{{range .Balances}}<code>{{.Tag}}</code>: <i>{{printf "%.15g" .Balance}} sat</i>  ({{dollar .Balance}})
+{{range .Balances}}<code>{{.Tag}}</code>: <i>{{printf "%.15g" .Balance}} sat</i>  ({{fiat .Balance $.FiatCurrency}})
 {{else}}
 <i>No tagged transactions made yet.</i>
 {{end}}
@@ -346,9 +614,9 @@ Prize: {{.Prize}}
 Registered: {{.Registered}}
     `,
 	INVALIDPARTNUMBER: "Invalid number of participants: {{.Number}}",
-	USERSENTTOUSER:    "💛 {{menuItem .Sats .RawSats true }} ({{dollar .Sats}}) sent to {{.User}}{{if .ReceiverHasNoChat}} (couldn't notify {{.User}} as they haven't started a conversation with the bot){{end}}.",
-	USERSENTYOUSATS:   "💛 {{.User}} has sent you {{menuItem .Sats .RawSats false}} ({{dollar .Sats}}){{if .BotOp}} on a {{.BotOp}}{{end}}.",
-	RECEIVEDSATSANON:  "💛 Someone has sent you {{menuItem .Sats .RawSats false}} ({{dollar .Sats}}).",
+	USERSENTTOUSER:    "💛 {{menuItem .Sats .RawSats true }} ({{fiat .Sats $.FiatCurrency}}) sent to {{.User}}{{if .ReceiverHasNoChat}} (couldn't notify {{.User}} as they haven't started a conversation with the bot){{end}}.{{if .Note}}\n📝 <i>{{.Note}}</i>{{end}}",
+	USERSENTYOUSATS:   "💛 {{.User}} has sent you {{menuItem .Sats .RawSats false}} ({{fiat .Sats $.FiatCurrency}}){{if .BotOp}} on a {{.BotOp}}{{end}}.{{if .Note}}\n📝 <i>{{.Note}}</i>{{end}}",
+	RECEIVEDSATSANON:  "💛 Someone has sent you {{menuItem .Sats .RawSats false}} ({{fiat .Sats $.FiatCurrency}}).{{if .Note}}\n📝 <i>{{.Note}}</i>{{end}}",
 	FAILEDSEND:        "Failed to send: ",
 	QRCODEFAIL:        "QR code reading unsuccessful: {{.Err}}",
 	SAVERECEIVERFAIL:  "Failed to save receiver. This is probably a bug.",
@@ -359,6 +627,15 @@ Registered: {{.Registered}}
 	CANTCANCEL:        "You don't have the powers to cancel this.",
 	FAILEDINVOICE:     "Failed to generate invoice: {{.Err}}",
 	STOPNOTIFY:        "Notifications stopped.",
+	USERSENTTOUSERS: `💛 {{.Sats}} sat ({{fiat .Sats $.FiatCurrency}}) split among:
+{{range .Shares}}- {{.User}}: <i>{{.Sats}} sat</i>
+{{end}}`,
+	SENDMANYSUMMARY: `{{if .Sent}}💛 Sent:
+{{range .Sent}}- {{.User}}: <i>{{.Sats | printf "%.15g"}} sat</i>
+{{end}}{{end}}{{if .Failed}}{{if .Sent}}
+{{end}}❌ Not sent:
+{{range .Failed}}- <code>{{. | html}}</code>
+{{end}}{{end}}`,
 	START: `
 ⚡️ @lntxbot, a <b>Bitcoin</b> Lightning wallet on your Telegram.
 
@@ -405,6 +682,16 @@ There are other commands, but learning them is left as an exercise to the user.
 
 Good luck! 🍽️
     `,
+	ONBOARDING: `👋 Welcome! Your wallet is empty, here's how to put some satoshis in it:
+
+- /invoice_1000 makes a Lightning invoice for 1000 sat that any wallet can pay. /invoice_any lets the payer choose the amount.
+- /invoice_lnurl shows a QR code that can be paid many times, from wallets that support LNURL.
+- If a service gives you an LNURL-withdraw (like a faucet or an exchange), just paste it here.
+- Friends using the bot can /send you money directly, and you can also receive at your /address.
+    `,
+	ONBOARDINGRECEIVE: "📥 Make an invoice",
+	ONBOARDINGLNURL:   "🔁 Show deposit LNURL",
+
 	WRONGCOMMAND:    "Could not understand the command. /help",
 	RETRACTQUESTION: "Retract unclaimed tip?",
 	RECHECKPENDING:  "Recheck pending payment?",
@@ -415,12 +702,12 @@ Good luck! 🍽️
 {{if .Txn.Payee.Valid}}<b>Payee</b>: {{.Txn.Payee.String | nodeLink}} (<u>{{.Txn.Payee.String | nodeAlias}}</u>){{end}}
 <b>Hash</b>: <code>{{.Txn.Hash}}</code>{{end}}{{if .Txn.Preimage.String}}
 <b>Preimage</b>: <code>{{.Txn.Preimage.String}}</code>{{end}}
-<b>Amount</b>: <i>{{.Txn.Amount | printf "%.15g"}} sat</i> ({{dollar .Txn.Amount}})
+<b>Amount</b>: <i>{{.Txn.Amount | printf "%.15g"}} sat</i> ({{.Txn.FiatAmount $.FiatCurrency}})
 {{if not (eq .Txn.Status "RECEIVED")}}<b>Fee paid</b>: <i>{{printf "%.15g" .Txn.Fees}} sat</i>{{end}}
 {{.LogInfo}}
     `,
 	TXLIST: `<b>{{if .Offset}}Transactions from {{.From}} to {{.To}}{{else}}Latest {{.Limit}} transactions{{end}}</b>
-{{range .Transactions}}<code>{{.StatusSmall}}</code> <code>{{.Amount | paddedSatoshis}}</code> {{.Icon}} {{.PeerActionDescription}}{{if not .TelegramPeer.Valid}}<i>{{.Description}}</i>{{end}} <i>{{.Time | timeSmall}}</i> /tx_{{.HashReduced}}
+{{range .Transactions}}<code>{{.StatusSmall}}</code> <code>{{.Amount | paddedSatoshis}}</code> ({{.FiatAmount $.FiatCurrency}}{{if .Fees}}, fee {{.Fees | printf "%.15g"}}{{end}}) {{.Icon}} {{.PeerActionDescription}}{{if .Description}}<i>{{.Description | makeLinks}}</i>{{end}} <i>{{.Time | timeSmall}}</i> /tx_{{.HashReduced}}
 {{else}}
 <i>No transactions made yet.</i>
 {{end}}
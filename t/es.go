@@ -41,7 +41,9 @@ var ES = map[Key]string{
 `,
 	LNURLPAYPROMPT: `🟢 <code>{{.Domain}}</code> espera que {{if .FixedAmount}}<i>{{.FixedAmount | printf "%.15g"}} sat</i>{{else}}un valor entre <i>{{.Min | printf "%.15g"}}</i> y <i>{{.Max | printf "%.15g"}} sat</i>{{end}} para:
  
- <code>{{if .Long}}{{.Long | html}}{{else}}{{.Text | html}}{{end}}</code>{{if .WillSendPayerData}}
+ <code>{{.Text | html}}</code>{{with .Long}}
+
+<i>{{. | html}}</i>{{end}}{{if .WillSendPayerData}}
  
  ---
  
@@ -55,11 +57,14 @@ var ES = map[Key]string{
         
  <b>Para confirmar el pago, responde con algo de texto</b>`,
 	LNURLPAYAMOUNTSNOTICE: `<code>{{.Domain}}</code> espera que {{if .Exact}}{{.Min | printf "%.3f"}}{{else if .NoMax}}al menos{{.Min | printf "%.0f"}}{{else}}entre {{.Min | printf "%.0f"}} y {{.Max | printf "%.0f"}}{{end}} sat.`,
-	LNURLPAYSUCCESS: `<code>{{.Domain}}</code> dice:
+	LNURLPAYSUCCESS: `✅ Pago a <code>{{.Domain}}</code> completado. /tx_{{.HashFirstChars}}
+<code>{{.Hash}}</code>{{if .HasAction}}
+
+<code>{{.Domain}}</code> dice:
 {{.Text}}
-{{if .DecipherError}}No se descifró ({{.DecipherError}}):
+{{if .DecipherError}}⚠️ No se pudo revelar el secreto enviado por <code>{{.Domain}}</code>: <i>{{.DecipherError}}</i>. Pídeselo usando el hash de arriba.
 {{end}}{{if .Value}}<pre>{{.Value}}</pre>
-{{end}}{{if .URL}}<a href="{{.URL}}">{{.URL}}</a>{{end}}
+{{end}}{{if .URL}}<a href="{{.URL}}">{{.URL}}</a>{{end}}{{end}}
     `,
 	LNURLPAYMETADATA: `#lnurlpay metadata:
 <b>dominino</b>: <i>{{.Domain}}</i>
@@ -77,10 +82,11 @@ Tienes 15 minutos para hacerlo o serás expulsado y baneado por un día.
 	RENAMABLEMSG:      "Cualquiera puede cambiar el nombre de este grupo siempre que paguen {{.Sat}} sat (asegúrate de que has puesto a @lntxbot como administrador para que esto funcione).",
 	RENAMEPROMPT:      "Pagar <b>{{.Sats}} sat</b> para cambiar el nombre de este grupo por <i>{{.Name}}</i>?",
 	GROUPNOTRENAMABLE: "¡Este grupo no se puede renombrar!",
+	TIPLIMITEXCEEDED:  "El monto excede el límite de este grupo de {{.Sat}} sat.",
 
 	INTERNALPAYMENTUNEXPECTED: "Ha ocurrido algo extraño. Si se trata de una factura interna, fallará. Puede que la factura haya caducado o algo más que desconocemos. Si se trata de una factura externa, ignora esta advertencia.",
 	PAYMENTFAILED:             "❌ Pago fallido.\n\n<i>{{.FailureString}}</i>",
-	PAIDMESSAGE: `✅ Pagado con <i>{{printf "%.15g" .Sats}} sat</i> ({{dollar .Sats}}) (+ <i>{{.Fee}}</i> fee).
+	PAIDMESSAGE: `✅ Pagado con <i>{{printf "%.15g" .Sats}} sat</i> ({{fiat .Sats $.FiatCurrency}}) (+ <i>{{.Fee}}</i> fee).
         
 <b>Hash:</b> <code>{{.Hash}}</code>{{if .Preimage}}
 <b>Prueba:</b> <code>{{.Preimage}}</code>{{end}}
@@ -92,7 +98,7 @@ Tienes 15 minutos para hacerlo o serás expulsado y baneado por un día.
 	INSUFFICIENTBALANCE: `Saldo insuficiente para {{.Purpose}}. Necesitas {{.Sats | printf "%.15g"}} sat más.`,
 
 	PAYMENTRECEIVED: `
-      ⚡️ Pago recibido{{if .SenderName}} de <i>{{ .SenderName }}</i>{{end}}: {{.Sats}} sat ({{dollar .Sats}}). /tx_{{.Hash}}{{if .Message}} {{.Message | messageLink}}{{end}} #tx
+      ⚡️ Pago recibido{{if .SenderName}} de <i>{{ .SenderName }}</i>{{end}}: {{.Sats}} sat ({{fiat .Sats $.FiatCurrency}}). /tx_{{.Hash}}{{if .Message}} {{.Message | messageLink}}{{end}} #tx
       {{if .Comment}}
 📨 <i>{{.Comment}}</i>
       {{end}}
@@ -107,6 +113,16 @@ Tienes 15 minutos para hacerlo o serás expulsado y baneado por un día.
 	EXPENSIVENOTIFICATION: "El mensaje {{.Link}}{{if .Sender}}te costó{{else}}te generó{{end}}{{.Price}} sat.",
 	FREETALK:              "Los mensajes vuelven a ser gratuitos.",
 
+	ERRINSUFFICIENTBALANCE: "Saldo insuficiente.",
+	ERRDATABASE:            "Error de base de datos.",
+	ERRINVALIDAMOUNT:       "Monto inválido.",
+	ERRINVOICEEXPIRED:      "Esta factura ha expirado{{if .Minutes}} hace {{.Minutes}} minuto{{s .Minutes}}{{end}}.",
+	ERRNOROUTE:             "No se pudo encontrar una ruta hacia el receptor.",
+	ERRTIMEOUT:             "La operación superó el tiempo límite{{if .Seconds}} de {{.Seconds}} segundos{{end}}.",
+	ERRLIGHTNINGNODE:       "Error del nodo Lightning: {{.Message}}",
+	ERRALREADYPAYING:       "Ya se está pagando esta factura.",
+	ERRWRONGNETWORK:        "Esta es una factura de {{.Invoice}}, pero este bot está en {{.Bot}}.",
+
 	APPBALANCE: `#{{.App | lower}} Saldo: <i>{{printf "%.15g" .Balance}} sat</i>`,
 
 	HELPINTRO: `
@@ -198,9 +214,11 @@ Tienes 15 minutos para pagar o serás expulsado.
 <code>/fundraise 10000 8 @user</code>: El @user de Telegram recibirá 80000 satoshis después de que 8 personas contribuyan.
     `,
 	FUNDRAISEAD: `
-Recolecta de {{.Fund}} para {{.ToUser}}!
+Recolecta de {{.Fund}} para {{.ToUser}}!{{with .Title}}
+<b>{{.}}</b>{{end}}
 Colaboradores necesarios para completarla: {{.Participants}}
 Cada uno paga: {{.Sats}} sat
+{{.Progress}} {{.Contributed}}/{{.Participants}}
 Han contribuido: {{.Registered}}
     `,
 	FUNDRAISEJOIN:        "¡Contribuye!",
@@ -283,7 +301,7 @@ Para difundir un anuncio debes enviar un mensaje al bot que será el contenido d
 /sats4ads_broadcast_1000 emite un anuncio. La última cifra es el número máximo de satoshis que se gastará. Los anuncios más baratos tendrán preferencia sobre los más caros. Debe emitirse en respuesta a otro mensaje, cuyo contenido se utilizará como texto del anuncio.
     `,
 	SATS4ADSTOGGLE:    `#sats4ads {{if .On}}Ver anuncios y recibir {{printf "%.15g" .Sats}} sat por carácter.{{else}}No verás más anuncios.{{end}}`,
-	SATS4ADSBROADCAST: `#sats4ads {{if .NSent}}Mensaje emitido {{.NSent}} tiempo{{s .NSent}} por un coste total de {{.Sats}} sat ({{dollar .Sats}}).{{else}}No se ha podido encontrar un homólogo al que notificar con los parámetros dados. /sats4ads_rates{{end}}`,
+	SATS4ADSBROADCAST: `#sats4ads {{if .NSent}}Mensaje emitido {{.NSent}} tiempo{{s .NSent}} por un coste total de {{.Sats}} sat ({{fiat .Sats $.FiatCurrency}}).{{else}}No se ha podido encontrar un homólogo al que notificar con los parámetros dados. /sats4ads_rates{{end}}`,
 	SATS4ADSSTART:     `El mensaje está siendo emitiendo.`,
 	SATS4ADSPRICETABLE: `#sats4ads Cantidad de usuarios <b>por</b> cada franja de precios.
 {{range .Rates}}<code>{{.UpToRate}} msat</code>: <i>{{.NUsers}} usuario{{s .NUsers}}</i>
@@ -300,7 +318,7 @@ Cada anuncio cuesta los precios anteriores <i>per character</i> + <code>1 sat</c
 	STOPHELP: "El bot deja de mostrarte notificaciones.",
 
 	PAYPROMPT: `
-{{if .Sats}}<i>{{.Sats}} sat</i> ({{dollar .Sats}})
+{{if .Sats}}<i>{{.Sats}} sat</i> ({{fiat .Sats $.FiatCurrency}})
 {{end}}{{if .Description}}<i>{{.Description}}</i>{{else}}<code>{{.DescriptionHash}}</code>{{end}}
 {{if .ReceiverName}}
 <b>Receptor</b>: {{.ReceiverName}}{{end}}
@@ -319,9 +337,10 @@ Reacciona con un :zap: para confirmar.{{end}}
     `,
 	FAILEDDECODE: "Fallo en la decodificación de la factura: {{.Err}}",
 	BALANCEMSG: `
-<b>Saldo total</b>: {{printf "%.15g" .Sats}} sat ({{dollar .Sats}})
-<b>Saldo disponible</b>: {{printf "%.15g" .Usable}} sat ({{dollar .Usable}})
-<b>Total recibido</b>: {{printf "%.15g" .Received}} sat
+<b>Saldo total</b>: {{printf "%.15g" .Sats}} sat ({{.Fiat}})
+<b>Saldo disponible</b>: {{printf "%.15g" .Usable}} sat ({{.UsableFiat}})
+{{if .Pending}}<b>Pendiente</b>: {{printf "%.15g" .Pending}} sat ({{.PendingFiat}}), entrante y todavía no disponible
+{{end}}<b>Total recibido</b>: {{printf "%.15g" .Received}} sat
 <b>Total enviado</b>: {{printf "%.15g" .Sent}} sat
 <b>Tarifas totales pagadas</b>: {{printf "%.15g" .Fees}} sat
 
@@ -331,7 +350,7 @@ Reacciona con un :zap: para confirmar.{{end}}
 	TAGGEDBALANCEMSG: `
 <b>Total</b> <code>recibido - gastado</code> <b>en aplicaciones internas y de terceros -></b> /apps<b>:</b>
 
-{{range .Balances}}<code>{{.Tag}}</code>: <i>{{printf "%.15g" .Balance}} sat</i>  ({{dollar .Balance}})
+{{range .Balances}}<code>{{.Tag}}</code>: <i>{{printf "%.15g" .Balance}} sat</i>  ({{fiat .Balance $.FiatCurrency}})
 {{else}}
 <i>Todavía no se ha realizado ninguna operación de etiquetado.</i>
 {{end}}
@@ -346,9 +365,9 @@ Premio: {{.Prize}}
 Registrados: {{.Registered}}
     `,
 	INVALIDPARTNUMBER: "Número inválido de participantes: {{.Number}}",
-	USERSENTTOUSER:    "💛 {{menuItem .Sats .RawSats true }} ({{dollar .Sats}}) enviado(s) a {{.User}}{{if .ReceiverHasNoChat}} (no se ha podido notificar a{{.User}} ya que no ha iniciado una conversación con el bot){{end}}.",
-	USERSENTYOUSATS:   "💛 {{.User}} te ha enviado {{menuItem .Sats .RawSats false}} ({{dollar .Sats}}){{if .BotOp}} en un {{.BotOp}}{{end}}.",
-	RECEIVEDSATSANON:  "💛 Alguien te ha enviado {{menuItem .Sats .RawSats false}} ({{dollar .Sats}}).",
+	USERSENTTOUSER:    "💛 {{menuItem .Sats .RawSats true }} ({{fiat .Sats $.FiatCurrency}}) enviado(s) a {{.User}}{{if .ReceiverHasNoChat}} (no se ha podido notificar a{{.User}} ya que no ha iniciado una conversación con el bot){{end}}.{{if .Note}}\n📝 <i>{{.Note}}</i>{{end}}",
+	USERSENTYOUSATS:   "💛 {{.User}} te ha enviado {{menuItem .Sats .RawSats false}} ({{fiat .Sats $.FiatCurrency}}){{if .BotOp}} en un {{.BotOp}}{{end}}.{{if .Note}}\n📝 <i>{{.Note}}</i>{{end}}",
+	RECEIVEDSATSANON:  "💛 Alguien te ha enviado {{menuItem .Sats .RawSats false}} ({{fiat .Sats $.FiatCurrency}}).{{if .Note}}\n📝 <i>{{.Note}}</i>{{end}}",
 	FAILEDSEND:        "Fallo de envío: ",
 	QRCODEFAIL:        "Lectura de código QR fallida: {{.Err}}",
 	SAVERECEIVERFAIL:  "No se ha podido guardar el receptor. Esto es probablemente un error.",
@@ -415,12 +434,12 @@ Hay otros comandos, pero su aprendizaje se deja como ejercicio al usuario.
 {{if .Txn.Payee.Valid}}<b>Beneficiario</b>: {{.Txn.Payee.String | nodeLink}} (<u>{{.Txn.Payee.String | nodeAlias}}</u>){{end}}
 <b>Hash</b>: <code>{{.Txn.Hash}}</code>{{end}}{{if .Txn.Preimage.String}}
 <b>Preimagen</b>: <code>{{.Txn.Preimage.String}}</code>{{end}}
-<b>Monto</b>: <i>{{.Txn.Amount | printf "%.15g"}} sat</i> ({{dollar .Txn.Amount}})
+<b>Monto</b>: <i>{{.Txn.Amount | printf "%.15g"}} sat</i> ({{.Txn.FiatAmount $.FiatCurrency}})
 {{if not (eq .Txn.Status "RECEIVED")}}<b>Tarifa pagada</b>: <i>{{printf "%.15g" .Txn.Fees}} sat</i>{{end}}
 {{.LogInfo}}
     `,
 	TXLIST: `<b>{{if .Offset}}Transacciones desde{{.From}} a {{.To}}{{else}}Últimas {{.Limit}} transacciones{{end}}</b>
-{{range .Transactions}}<code>{{.StatusSmall}}</code> <code>{{.Amount | paddedSatoshis}}</code> {{.Icon}} {{.PeerActionDescription}}{{if not .TelegramPeer.Valid}}<i>{{.Description}}</i>{{end}} <i>{{.Time | timeSmall}}</i> /tx_{{.HashReduced}}
+{{range .Transactions}}<code>{{.StatusSmall}}</code> <code>{{.Amount | paddedSatoshis}}</code> ({{.FiatAmount $.FiatCurrency}}{{if .Fees}}, tarifa {{.Fees | printf "%.15g"}}{{end}}) {{.Icon}} {{.PeerActionDescription}}{{if .Description}}<i>{{.Description | makeLinks}}</i>{{end}} <i>{{.Time | timeSmall}}</i> /tx_{{.HashReduced}}
 {{else}}
 <i>Todavía no se ha realizado ninguna transacción.</i>
 {{end}}
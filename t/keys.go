@@ -35,16 +35,24 @@ const (
 	INLINEGIVEFLIPRESULT Key = "InlineGiveflipResult"
 	INLINECOINFLIPRESULT Key = "InlineCoinflipResult"
 	INLINEHIDDENRESULT   Key = "InlineHiddenResult"
+	INLINEVOUCHERRESULT  Key = "InlineVoucherResult"
+	INLINEVOUCHERMSG     Key = "InlineVoucherMsg"
 
 	LNURLUNSUPPORTED          Key = "LnurlUnsupported"
 	LNURLERROR                Key = "LnurlError"
 	LNURLAUTHSUCCESS          Key = "LnurlAuthSuccess"
+	LNURLAUTHTHROTTLED        Key = "LnurlAuthThrottled"
+	LNURLAUTHHELP             Key = "lnurlauthHelp"
+	LNURLAUTHKEY              Key = "LnurlAuthKey"
 	LNURLPAYPROMPT            Key = "LnurlPayPrompt"
 	LNURLPAYPROMPTCOMMENT     Key = "LnurlPayPromptComment"
+	LNURLWITHDRAWPROMPT       Key = "LnurlWithdrawPrompt"
 	LNURLPAYAMOUNTSNOTICE     Key = "LnurlPayAmountsNotice"
+	LNURLPAYFIATCONFIRM       Key = "LnurlPayFiatConfirm"
 	LNURLPAYSUCCESS           Key = "LnurlPaySuccess"
 	LNURLPAYMETADATA          Key = "LnurlPayMetadata"
 	LNURLBALANCECHECKCANCELED Key = "LnurlBalanceCheckCanceled"
+	LNURLWITHDRAWEXPIRED      Key = "LnurlWithdrawExpired"
 
 	TICKETSET         Key = "TicketSet"
 	TICKETMESSAGE     Key = "TicketMessage"
@@ -54,8 +62,12 @@ const (
 	RENAMEPROMPT      Key = "RenamePrompt"
 	GROUPNOTRENAMABLE Key = "GroupNotRenamable"
 
+	TIPLIMITMSG      Key = "TipLimitMsg"
+	TIPLIMITEXCEEDED Key = "TipLimitExceeded"
+
 	INTERNALPAYMENTUNEXPECTED Key = "InternalPaymentUnexpected"
 	PAYMENTFAILED             Key = "PaymentFailed"
+	PAYMENTPROGRESS           Key = "PaymentProgress"
 	PAIDMESSAGE               Key = "PaidMessage"
 	DBERROR                   Key = "DBError"
 	INSUFFICIENTBALANCE       Key = "InsufficientBalance"
@@ -67,12 +79,41 @@ const (
 
 	SPAMMYMSG             Key = "SpammyMsg"
 	COINFLIPSENABLEDMSG   Key = "CoinflipsEnabledMsg"
+	TREASURYENABLEDMSG    Key = "TreasuryEnabledMsg"
 	LANGUAGEMSG           Key = "LanguageMsg"
+	CURRENCYMSG           Key = "CurrencyMsg"
+	PAYCONFIRMMSG         Key = "PayConfirmMsg"
+	SPENDALERTMSG         Key = "SpendAlertMsg"
+	INVOICEDESCRIPTIONMSG Key = "InvoiceDescriptionMsg"
+	SPENDALERT            Key = "SpendAlert"
+	SKIPQRMSG             Key = "SkipQRMsg"
+	ROMANMSG              Key = "RomanMsg"
 	FREEJOIN              Key = "FreeJoin"
 	EXPENSIVEMSG          Key = "ExpensiveMsg"
 	EXPENSIVENOTIFICATION Key = "ExpensiveNotification"
 	FREETALK              Key = "FreeTalk"
 
+	ERRINSUFFICIENTBALANCE Key = "ErrInsufficientBalance"
+	ERRDATABASE            Key = "ErrDatabase"
+	ERRINVALIDAMOUNT       Key = "ErrInvalidAmount"
+	ERRINVOICEEXPIRED      Key = "ErrInvoiceExpired"
+	ERRNOROUTE             Key = "ErrNoRoute"
+	ERRTIMEOUT             Key = "ErrTimeout"
+	ERRLIGHTNINGNODE       Key = "ErrLightningNode"
+	ERRALREADYPAYING       Key = "ErrAlreadyPaying"
+	ERRINVOICEAMOUNT       Key = "ErrInvoiceAmount"
+	ERRWRONGNETWORK        Key = "ErrWrongNetwork"
+	ERRTWOFACTORREQUIRED   Key = "ErrTwoFactorRequired"
+	ERRACCOUNTFROZEN       Key = "ErrAccountFrozen"
+	ERRRATELIMITED         Key = "ErrRateLimited"
+	ERRUNEXPECTED          Key = "ErrUnexpected"
+	ERRPAYYOURSELF         Key = "ErrPayYourself"
+	ERRINVALIDINVOICE      Key = "ErrInvalidInvoice"
+	ERRUNKNOWNINVOICE      Key = "ErrUnknownInvoice"
+	ERRINTERNALAMOUNT      Key = "ErrInternalAmount"
+	ERRFEELIMIT            Key = "ErrFeeLimit"
+	ERRINVALIDFEELIMIT     Key = "ErrInvalidFeeLimit"
+
 	APPBALANCE Key = "AppBalance"
 
 	HELPINTRO   Key = "HelpIntro"
@@ -111,6 +152,21 @@ const (
 	GIVEFLIPAD        Key = "GiveflipAd"
 	GIVEFLIPJOIN      Key = "GiveflipJoin"
 
+	FREEZEHELP     Key = "freezeHelp"
+	FREEZEMSG      Key = "FreezeMsg"
+	UNFREEZEPROMPT Key = "UnfreezePrompt"
+
+	TWOFACTORHELP    Key = "twofactorHelp"
+	TWOFACTORSTATUS  Key = "TwoFactorStatus"
+	TWOFACTORSETUP   Key = "TwoFactorSetup"
+	TWOFACTORPROMPT  Key = "TwoFactorPrompt"
+	TWOFACTORINVALID Key = "TwoFactorInvalid"
+
+	DROPHELP  Key = "dropHelp"
+	DROPMSG   Key = "DropMsg"
+	DROPCLAIM Key = "DropClaim"
+	DROPEMPTY Key = "DropEmpty"
+
 	FUNDRAISEHELP        Key = "fundraiseHelp"
 	FUNDRAISEAD          Key = "FundraiseAd"
 	FUNDRAISEJOIN        Key = "FundraiseJoin"
@@ -143,11 +199,60 @@ const (
 
 	TOGGLEHELP Key = "toggleHelp"
 
+	ADDRESSHELP         Key = "addressHelp"
+	LIGHTNINGADDRESSMSG Key = "LightningAddressMsg"
+
+	TIPJARHELP Key = "tipjarHelp"
+	TIPJARMSG  Key = "TipJarMsg"
+
+	NODEINFOHELP Key = "nodeinfoHelp"
+	NODEINFO     Key = "NodeInfo"
+	NODENOTSEEN  Key = "NodeNotSeen"
+
+	PAYQUOTEHELP Key = "payquoteHelp"
+	PAYQUOTE     Key = "PayQuote"
+
+	SETMAXFEEHELP Key = "setmaxfeeHelp"
+	MAXFEEMSG     Key = "MaxFeeMsg"
+
+	WEBHOOKHELP Key = "webhookHelp"
+	WEBHOOKMSG  Key = "WebhookMsg"
+
+	QRHELP Key = "qrHelp"
+
+	LANGUAGEHELP Key = "languageHelp"
+
+	DECODEHELP    Key = "decodeHelp"
+	DECODEINVOICE Key = "DecodeInvoice"
+	DECODELNURL   Key = "DecodeLNURL"
+
+	STATSHELP Key = "statsHelp"
+	STATSMSG  Key = "StatsMsg"
+
+	TREASURYHELP Key = "treasuryHelp"
+	TREASURYMSG  Key = "TreasuryMsg"
+
+	CONVERTHELP Key = "convertHelp"
+	CONVERTMSG  Key = "ConvertMsg"
+
+	PRICEALERTHELP      Key = "pricealertHelp"
+	PRICEALERTS         Key = "PriceAlerts"
+	PRICEALERTTRIGGERED Key = "PriceAlertTriggered"
+
+	SCHEDULEHELP   Key = "scheduleHelp"
+	SCHEDULES      Key = "Schedules"
+	SCHEDULEFAILED Key = "ScheduleFailed"
+
+	MENUHELP Key = "menuHelp"
+	MENUMSG  Key = "MenuMsg"
+
 	HELPHELP Key = "helpHelp"
 
 	STOPHELP Key = "stopHelp"
 
 	PAYPROMPT         Key = "PayPrompt"
+	PAYCHOOSE         Key = "PayChoose"
+	PAYCHOOSEBUTTON   Key = "PayChooseButton"
 	FAILEDDECODE      Key = "FailedDecode"
 	BALANCEMSG        Key = "BalanceMsg"
 	TAGGEDBALANCEMSG  Key = "TaggedBalanceMsg"
@@ -155,6 +260,8 @@ const (
 	LOTTERYMSG        Key = "LotteryMsg"
 	INVALIDPARTNUMBER Key = "InvalidPartNumber"
 	USERSENTTOUSER    Key = "UserSentToUser"
+	USERSENTTOUSERS   Key = "UserSentToUsers"
+	SENDMANYSUMMARY   Key = "SendManySummary"
 	USERSENTYOUSATS   Key = "UserSentYouSats"
 	RECEIVEDSATSANON  Key = "ReceivedSatsAnon"
 	FAILEDSEND        Key = "FailedSend"
@@ -168,6 +275,9 @@ const (
 	FAILEDINVOICE     Key = "FailedInvoice"
 	STOPNOTIFY        Key = "StopNotify"
 	START             Key = "Start"
+	ONBOARDING        Key = "Onboarding"
+	ONBOARDINGRECEIVE Key = "OnboardingReceive"
+	ONBOARDINGLNURL   Key = "OnboardingLNURL"
 	WRONGCOMMAND      Key = "WrongCommand"
 	RETRACTQUESTION   Key = "RetractQuestion"
 	RECHECKPENDING    Key = "RecheckPending"
@@ -0,0 +1,54 @@
+package t
+
+// pluralForm returns which form of a word should be used with the given
+// quantity in a language, following the CLDR rules for integers: 0 for "one",
+// 1 for "few" (only used by slavic languages) and 2 for "many" or "other".
+func pluralForm(lang string, n int64) int {
+	if n < 0 {
+		n = -n
+	}
+
+	switch lang {
+	case "ru", "uk":
+		switch {
+		case n%10 == 1 && n%100 != 11:
+			return 0
+		case n%10 >= 2 && n%10 <= 4 && (n%100 < 12 || n%100 > 14):
+			return 1
+		default:
+			return 2
+		}
+	default:
+		if n == 1 {
+			return 0
+		}
+		return 2
+	}
+}
+
+// pluralFunc is the "plural" template function for a language. It takes the
+// quantity and the forms ("one", "few", "many"), the ones not used by the
+// language can be omitted from the end, as in {{plural .N "spot" "spots"}}.
+func pluralFunc(lang string) func(quantity interface{}, forms ...string) string {
+	return func(quantity interface{}, forms ...string) string {
+		if len(forms) == 0 {
+			return ""
+		}
+
+		var n int64
+		switch q := quantity.(type) {
+		case int:
+			n = int64(q)
+		case int64:
+			n = q
+		case float64:
+			n = int64(q)
+		}
+
+		form := pluralForm(lang, n)
+		if form >= len(forms) {
+			form = len(forms) - 1
+		}
+		return forms[form]
+	}
+}
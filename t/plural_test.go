@@ -0,0 +1,102 @@
+package t
+
+import "testing"
+
+func TestPluralForm(t *testing.T) {
+	tests := []struct {
+		lang string
+		n    int64
+		form int
+	}{
+		{"en", 0, 2},
+		{"en", 1, 0},
+		{"en", 2, 2},
+		{"en", 11, 2},
+		{"en", -1, 0},
+		{"ru", 0, 2},
+		{"ru", 1, 0},
+		{"ru", 2, 1},
+		{"ru", 4, 1},
+		{"ru", 5, 2},
+		{"ru", 11, 2},
+		{"ru", 12, 2},
+		{"ru", 14, 2},
+		{"ru", 21, 0},
+		{"ru", 22, 1},
+		{"ru", 25, 2},
+		{"ru", 101, 0},
+		{"ru", 111, 2},
+		{"ru", 112, 2},
+		{"ru", -3, 1},
+	}
+
+	for _, test := range tests {
+		if form := pluralForm(test.lang, test.n); form != test.form {
+			t.Errorf("pluralForm(%q, %d) = %d, want %d", test.lang, test.n, form, test.form)
+		}
+	}
+}
+
+func TestPluralFunc(t *testing.T) {
+	en := pluralFunc("en")
+	ru := pluralFunc("ru")
+
+	tests := []struct {
+		plural   func(interface{}, ...string) string
+		quantity interface{}
+		forms    []string
+		word     string
+	}{
+		{en, 1, []string{"spot", "spots"}, "spot"},
+		{en, int64(3), []string{"spot", "spots"}, "spots"},
+		{en, 1.0, []string{"spot", "spots"}, "spot"},
+		{ru, 2, []string{"место", "места", "мест"}, "места"},
+		{ru, 5, []string{"место", "места", "мест"}, "мест"},
+		// forms the language doesn't use can be left out, the last is used
+		{ru, 5, []string{"место", "места"}, "места"},
+		{en, 2, nil, ""},
+		{en, "not a number", []string{"spot", "spots"}, "spots"},
+	}
+
+	for _, test := range tests {
+		if word := test.plural(test.quantity, test.forms...); word != test.word {
+			t.Errorf("plural(%v, %q) = %q, want %q", test.quantity, test.forms, word, test.word)
+		}
+	}
+}
+
+func TestRussianPlurals(t *testing.T) {
+	bundle := NewBundle("en")
+	if err := bundle.AddLanguage("en", map[Key]string{
+		ERRINVOICEEXPIRED: "This invoice has expired.",
+	}); err != nil {
+		t.Fatal(err)
+	}
+	if err := bundle.AddLanguage("ru", map[Key]string{
+		ERRINVOICEEXPIRED: RU[ERRINVOICEEXPIRED],
+	}); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		minutes int
+		message string
+	}{
+		{1, "Срок действия инвойса истёк 1 минуту назад."},
+		{3, "Срок действия инвойса истёк 3 минуты назад."},
+		{5, "Срок действия инвойса истёк 5 минут назад."},
+		{11, "Срок действия инвойса истёк 11 минут назад."},
+		{21, "Срок действия инвойса истёк 21 минуту назад."},
+		{24, "Срок действия инвойса истёк 24 минуты назад."},
+	}
+
+	for _, test := range tests {
+		message, err := bundle.Render("ru", ERRINVOICEEXPIRED, T{"Minutes": test.minutes})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if message != test.message {
+			t.Errorf("%d minutes: %q, want %q", test.minutes, message, test.message)
+		}
+	}
+}
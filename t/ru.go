@@ -41,7 +41,9 @@ lnurl-auth успех!
 `,
 	LNURLPAYPROMPT: `🟢 <code>{{.Domain}}</code> ожидает {{if .FixedAmount}}<i>{{.FixedAmount | printf "%.15g"}} сат</i>{{else}}значение между <i>{{.Min | printf "%.15g"}}</i> и <i>{{.Max | printf "%.15g"}} сат</i>{{end}} для:
 
-<code>{{if .Long}}{{.Long | html}}{{else}}{{.Text | html}}{{end}}</code>{{if .WillSendPayerData}}
+<code>{{.Text | html}}</code>{{with .Long}}
+
+<i>{{. | html}}</i>{{end}}{{if .WillSendPayerData}}
 
 ---
 
@@ -55,11 +57,14 @@ lnurl-auth успех!
 
 <b>Для подтверждения платежа прикрепите сообщение</b>`,
 	LNURLPAYAMOUNTSNOTICE: `<code>{{.Domain}}</code> ожидал {{if .Exact}}{{.Min | printf "%.3f"}}{{else if .NoMax}}минимум{{.Min | printf "%.0f"}}{{else}}между {{.Min | printf "%.0f"}} и {{.Max | printf "%.0f"}}{{end}} сат.`,
-	LNURLPAYSUCCESS: `<code>{{.Domain}}</code> ответил:
+	LNURLPAYSUCCESS: `✅ Платёж на <code>{{.Domain}}</code> выполнен. /tx_{{.HashFirstChars}}
+<code>{{.Hash}}</code>{{if .HasAction}}
+
+<code>{{.Domain}}</code> ответил:
 {{.Text}}
-{{if .DecipherError}}Ошибка расшифровки ({{.DecipherError}}):
+{{if .DecipherError}}⚠️ Секрет, присланный <code>{{.Domain}}</code>, не удалось раскрыть: <i>{{.DecipherError}}</i>. Запросите его, указав хеш выше.
 {{end}}{{if .Value}}<pre>{{.Value}}</pre>
-{{end}}{{if .URL}}<a href="{{.URL}}">{{.URL}}</a>{{end}}
+{{end}}{{if .URL}}<a href="{{.URL}}">{{.URL}}</a>{{end}}{{end}}
     `,
 	LNURLPAYMETADATA: `#lnurlpay метаданные:
 <b>домен</b>: <i>{{.Domain}}</i>
@@ -77,10 +82,11 @@ lnurl-auth успех!
 	RENAMABLEMSG:      "Любой может сменить название чата за {{.Sat}} сат (убедитесь, что вы установили @lntxbot в качестве администратора).",
 	RENAMEPROMPT:      "Заплатить <b>{{.Sats}} сат</b> за переименование группы в <i>{{.Name}}</i>?",
 	GROUPNOTRENAMABLE: "Эту группу невозможно переименовать!",
+	TIPLIMITEXCEEDED:  "Сумма превышает лимит этой группы в {{.Sat}} сат.",
 
 	INTERNALPAYMENTUNEXPECTED: "Произошло что-то странное. Если это был внутренний запрос платежа, то платёж не состоится. Вероятно, запрос устарел или произошло что-то ещё. Если это внешний запрос, игнорируйте это предупреждение.",
-	PAYMENTFAILED:             "❌ Платёж не состоялся.\n\n<i>{{.FailureString}}</i>",
-	PAIDMESSAGE: `✅ Оплачено <i>{{printf "%.15g" .Sats}} сат</i> ({{dollar .Sats}}) (+ <i>{{.Fee}}</i> комиссия). 
+	PAYMENTFAILED:             "❌ Платёж не состоялся{{with .Attempts}}{{if gt . 1}} после {{.}} {{plural . \"попытки\" \"попыток\" \"попыток\"}}{{end}}{{end}}.\n\n<i>{{.FailureString}}</i>",
+	PAIDMESSAGE: `✅ Оплачено <i>{{printf "%.15g" .Sats}} сат</i> ({{fiat .Sats $.FiatCurrency}}) (+ <i>{{.Fee}}</i> комиссия). 
 
 <b>Hash:</b> <code>{{.Hash}}</code>{{if .Preimage}}
 <b>Proof:</b> <code>{{.Preimage}}</code>{{end}}
@@ -92,7 +98,7 @@ lnurl-auth успех!
 	INSUFFICIENTBALANCE: `Недостаточный баланс для {{.Purpose}}. Необходимо на {{.Sats | printf "%.15g"}} сат больше.`,
 
 	PAYMENTRECEIVED: `
-      ⚡️ Платёж получен{{if .SenderName}} от <i>{{ .SenderName }}</i>{{end}}: {{.Sats}} сат ({{dollar .Sats}}). /tx_{{.Hash}}{{if .Message}} {{.Message | messageLink}}{{end}} #tx
+      ⚡️ Платёж получен{{if .SenderName}} от <i>{{ .SenderName }}</i>{{end}}: {{.Sats}} сат ({{fiat .Sats $.FiatCurrency}}). /tx_{{.Hash}}{{if .Message}} {{.Message | messageLink}}{{end}} #tx
       {{if .Comment}}
 📨 <i>{{.Comment}}</i>
       {{end}}
@@ -107,6 +113,16 @@ lnurl-auth успех!
 	EXPENSIVENOTIFICATION: "Сообщение {{.Link}} только что {{if .Sender}}стоило{{else}}заработало{{end}} вам {{.Price}} сат.",
 	FREETALK:              "Сообщения снова бесплатны",
 
+	ERRINSUFFICIENTBALANCE: "Недостаточно средств.",
+	ERRDATABASE:            "Ошибка базы данных.",
+	ERRINVALIDAMOUNT:       "Неверная сумма.",
+	ERRINVOICEEXPIRED:      "Срок действия инвойса истёк{{if .Minutes}} {{.Minutes}} {{plural .Minutes \"минуту\" \"минуты\" \"минут\"}} назад{{end}}.",
+	ERRNOROUTE:             "Не удалось найти маршрут до получателя.",
+	ERRTIMEOUT:             "Время ожидания истекло{{if .Seconds}} через {{.Seconds}} секунд{{end}}.",
+	ERRLIGHTNINGNODE:       "Ошибка Lightning-ноды: {{.Message}}",
+	ERRALREADYPAYING:       "Этот счёт уже оплачивается.",
+	ERRWRONGNETWORK:        "Это инвойс сети {{.Invoice}}, а бот работает в {{.Bot}}.",
+
 	APPBALANCE: `#{{.App | lower}} Баланс: <i>{{printf "%.15g" .Balance}} сат</i>`,
 
 	HELPINTRO: `
@@ -180,7 +196,7 @@ lnurl-auth успех!
     `,
 	COINFLIPWINNERMSG:      "Вы победитель в подбросе монетки с призом {{.TotalSats}} сат. Проигравшие: {{.Senders}}.",
 	COINFLIPGIVERMSG:       "Вы проиграли {{.IndividualSats}} в подбросе монетки. Победителем стал {{.Receiver}}.",
-	COINFLIPAD:             "Заплатите {{.Sats}} сат и получите шанс выиграть {{.Prize}}! Осталось {{.SpotsLeft}} из {{.MaxPlayers}} свободных мест!",
+	COINFLIPAD:             "Заплатите {{.Sats}} сат и получите шанс выиграть {{.Prize}}! Осталось {{.SpotsLeft}} {{plural .SpotsLeft \"свободное место\" \"свободных места\" \"свободных мест\"}} из {{.MaxPlayers}}!",
 	COINFLIPJOIN:           "Играть в лотерею!",
 	CALLBACKCOINFLIPWINNER: "Победитель: {{.Winner}}",
 
@@ -198,9 +214,11 @@ lnurl-auth успех!
 <code>/fundraise 10000 8 @user</code>: Пользователь @user получит 80000 сатоши, если 8 человек присоединятся к компании.
     `,
 	FUNDRAISEAD: `
-Фандрайзинг {{.Fund}} в пользу {{.ToUser}}!
+Фандрайзинг {{.Fund}} в пользу {{.ToUser}}!{{with .Title}}
+<b>{{.}}</b>{{end}}
 Необходимо участников: {{.Participants}}
 Вклад каждого: {{.Sats}} сат
+{{.Progress}} {{.Contributed}}/{{.Participants}}
 Присоединились: {{.Registered}}
     `,
 	FUNDRAISEJOIN:        "Вкладываюсь!",
@@ -278,7 +296,7 @@ Sats4ads это маркетплейс рекламы в Telegram. Платит
 /sats4ads_broadcast_1000 вещает сообщение. Последняя цифра равна максимуму сатоши, которые будут потрачены. Более дешёвые подписчики рекламы предпочтительны. Эта команда должна вызываться в ответ на другое сообщение, содержание которого будет использовано в качестве текста рекламы.
     `,
 	SATS4ADSTOGGLE:    `#sats4ads {{if .On}}Смотреть рекламу и получать {{printf "%.15g" .Sats}} сат за символ.{{else}}Вы больше не увидите рекламы.{{end}}`,
-	SATS4ADSBROADCAST: `#sats4ads {{if .NSent}}Сообщение отправлено {{.NSent}} раз с полной стоимостью {{.Sats}} сат ({{dollar .Sats}}).{{else}}Не могу найти подписчиков с подходящими параметрами. /sats4ads_rates{{end}}`,
+	SATS4ADSBROADCAST: `#sats4ads {{if .NSent}}Сообщение отправлено {{.NSent}} раз с полной стоимостью {{.Sats}} сат ({{fiat .Sats $.FiatCurrency}}).{{else}}Не могу найти подписчиков с подходящими параметрами. /sats4ads_rates{{end}}`,
 	SATS4ADSSTART:     `Сообщение в рассылке.`,
 	SATS4ADSPRICETABLE: `#sats4ads Количество пользователей в каждом диапазоне цены.
 	
@@ -296,7 +314,7 @@ Sats4ads это маркетплейс рекламы в Telegram. Платит
 	STOPHELP: "Бот перестаёт отсылать оповещения.",
 
 	PAYPROMPT: `
-{{if .Sats}}<i>{{.Sats}} сат</i> ({{dollar .Sats}})
+{{if .Sats}}<i>{{.Sats}} сат</i> ({{fiat .Sats $.FiatCurrency}})
 {{end}}{{if .Description}}<i>{{.Description}}</i>{{else}}<code>{{.DescriptionHash}}</code>{{end}}
 {{if .ReceiverName}}
 <b>Получатель</b>: {{.ReceiverName}}{{end}}
@@ -315,9 +333,10 @@ React with a :zap: to confirm.{{end}}
     `,
 	FAILEDDECODE: "Ошибка декодирования счёта: {{.Err}}",
 	BALANCEMSG: `🏛
-<b>Полный баланс</b>: {{printf "%.15g" .Sats}} сат ({{dollar .Sats}})
-<b>Доступный баланс</b>: {{printf "%.15g" .Sats}} сат ({{dollar .Usable}})
-<b>Всего получено</b>: {{printf "%.15g" .Received}} сат
+<b>Полный баланс</b>: {{printf "%.15g" .Sats}} сат ({{.Fiat}})
+<b>Доступный баланс</b>: {{printf "%.15g" .Sats}} сат ({{.UsableFiat}})
+{{if .Pending}}<b>В ожидании</b>: {{printf "%.15g" .Pending}} сат ({{.PendingFiat}}), входящие, пока недоступны
+{{end}}<b>Всего получено</b>: {{printf "%.15g" .Received}} сат
 <b>Всего отправлено</b>: {{printf "%.15g" .Sent}} сат
 <b>Всего комиссий оплачено</b>: {{printf "%.15g" .Fees}} сат
 
@@ -327,7 +346,7 @@ React with a :zap: to confirm.{{end}}
 	TAGGEDBALANCEMSG: `
 <b>Всего разница </b> <code>получено - потрачено</code> <b>на внутренние и внешние</b> /apps<b>:</b>
 
-{{range .Balances}}<code>{{.Tag}}</code>: <i>{{printf "%.15g" .Balance}} сат</i>  ({{dollar .Balance}})
+{{range .Balances}}<code>{{.Tag}}</code>: <i>{{printf "%.15g" .Balance}} сат</i>  ({{fiat .Balance $.FiatCurrency}})
 {{else}}
 <i>Пока не совершено транзакций данного типа.</i>
 {{end}}
@@ -342,9 +361,9 @@ React with a :zap: to confirm.{{end}}
 Зарегистрировано: {{.Registered}}
     `,
 	INVALIDPARTNUMBER: "Неверное количество участников: {{.Number}}",
-	USERSENTTOUSER:    "💛 {{menuItem .Sats .RawSats true }} ({{dollar .Sats}}) отправлено {{.User}}{{if .ReceiverHasNoChat}} (не могу уведомить {{.User}} так как он не начал диалог с ботом{{end}}",
-	USERSENTYOUSATS:   "💛 {{.User}} отправил вам {{menuItem .Sats .RawSats false}} ({{dollar .Sats}}){{if .BotOp}} в ходе {{.BotOp}}{{end}}.",
-	RECEIVEDSATSANON:  "💛 Кто-то отослал вам {{menuItem .Sats .RawSats false}} ({{dollar .Sats}}).",
+	USERSENTTOUSER:    "💛 {{menuItem .Sats .RawSats true }} ({{fiat .Sats $.FiatCurrency}}) отправлено {{.User}}{{if .ReceiverHasNoChat}} (не могу уведомить {{.User}} так как он не начал диалог с ботом{{end}}{{if .Note}}\n📝 <i>{{.Note}}</i>{{end}}",
+	USERSENTYOUSATS:   "💛 {{.User}} отправил вам {{menuItem .Sats .RawSats false}} ({{fiat .Sats $.FiatCurrency}}){{if .BotOp}} в ходе {{.BotOp}}{{end}}.{{if .Note}}\n📝 <i>{{.Note}}</i>{{end}}",
+	RECEIVEDSATSANON:  "💛 Кто-то отослал вам {{menuItem .Sats .RawSats false}} ({{fiat .Sats $.FiatCurrency}}).{{if .Note}}\n📝 <i>{{.Note}}</i>{{end}}",
 	FAILEDSEND:        "Ошибка отправки: ",
 	QRCODEFAIL:        "QR код не был прочитан: {{.Err}}",
 	SAVERECEIVERFAIL:  "Ошибка сохранения получателя. Это вероятно баг.",
@@ -411,12 +430,12 @@ React with a :zap: to confirm.{{end}}
 {{if .Txn.Payee.Valid}}<b>Оплатил</b>: {{.Txn.Payee.String | nodeLink}} (<u>{{.Txn.Payee.String | nodeAlias}}</u>){{end}}
 <b>Хэш</b>: <code>{{.Txn.Hash}}</code>{{end}}{{if .Txn.Preimage.String}}
 <b>Секрет (Preimage)</b>: <code>{{.Txn.Preimage.String}}</code>{{end}}
-<b>Количество</b>: <i>{{.Txn.Amount | printf "%.15g"}} сат</i> ({{dollar .Txn.Amount}})
+<b>Количество</b>: <i>{{.Txn.Amount | printf "%.15g"}} сат</i> ({{.Txn.FiatAmount $.FiatCurrency}})
 {{if not (eq .Txn.Status "RECEIVED")}}<b>Комиссия</b>: <i>{{printf "%.15g" .Txn.Fees}}</i>{{end}}
 {{.LogInfo}}
     `,
 	TXLIST: `<b>{{if .Offset}}Транзакция от {{.From}} к {{.To}}{{else}}Последние {{.Limit}} транзакций{{end}}</b>
-{{range .Transactions}}<code>{{.StatusSmall}}</code> <code>{{.Amount | paddedSatoshis}}</code> {{.Icon}} {{.PeerActionDescription}}{{if not .TelegramPeer.Valid}}<i>{{.Description}}</i>{{end}} <i>{{.Time | timeSmall}}</i> /tx_{{.HashReduced}}
+{{range .Transactions}}<code>{{.StatusSmall}}</code> <code>{{.Amount | paddedSatoshis}}</code> ({{.FiatAmount $.FiatCurrency}}{{if .Fees}}, комиссия {{.Fees | printf "%.15g"}}{{end}}) {{.Icon}} {{.PeerActionDescription}}{{if .Description}}<i>{{.Description | makeLinks}}</i>{{end}} <i>{{.Time | timeSmall}}</i> /tx_{{.HashReduced}}
 {{else}}
 <i>Ещё нет ни одной транзакции</i>
 {{end}}
@@ -1,6 +1,7 @@
 package main
 
 import (
+	"context"
 	"errors"
 	"fmt"
 	"net/http"
@@ -86,7 +87,7 @@ func getChatOwner(chatId int64) (User, error) {
 
 var pictureCache, _ = lru.NewARC(25)
 
-func getTelegramUserPicture(username string) ([]byte, error) {
+func getTelegramUserPicture(ctx context.Context, username string) ([]byte, error) {
 	if i, ok := pictureCache.Get(username); ok {
 		return i.([]byte), nil
 	}
@@ -111,7 +112,7 @@ func getTelegramUserPicture(username string) ([]byte, error) {
 		}
 	}
 
-	b, err := imageBytesFromURL(url)
+	b, err := imageBytesFromURL(ctx, url)
 	if err != nil {
 		return nil, err
 	}
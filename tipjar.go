@@ -0,0 +1,210 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/docopt/docopt-go"
+	"github.com/fiatjaf/go-lnurl"
+	"github.com/fiatjaf/lntxbot/t"
+	"github.com/gorilla/mux"
+)
+
+// TipJarData is the configuration of the static lnurl-pay a user can share to
+// receive any number of payments.
+type TipJarData struct {
+	Min         int64  `json:"min,omitempty"` // in sat
+	Max         int64  `json:"max,omitempty"` // in sat
+	Description string `json:"description,omitempty"`
+	Image       []byte `json:"image,omitempty"`
+	ImageExt    string `json:"image_ext,omitempty"`
+}
+
+const (
+	tipJarDefaultMin = 1
+	tipJarDefaultMax = 1000000
+)
+
+func (data TipJarData) limits() (min, max int64) {
+	min, max = data.Min, data.Max
+	if min == 0 {
+		min = tipJarDefaultMin
+	}
+	if max == 0 {
+		max = tipJarDefaultMax
+	}
+	return
+}
+
+func handleTipJar(ctx context.Context, opts docopt.Opts) {
+	u := ctx.Value("initiator").(User)
+
+	var data TipJarData
+	if err := u.getAppData("tipjar", &data); err != nil {
+		log.Warn().Err(err).Stringer("user", &u).Msg("failed to load tipjar")
+		send(ctx, u, t.ERROR, t.T{"Err": ErrDatabase.Error()})
+		return
+	}
+
+	changed := true
+	switch {
+	case opts["min"].(bool), opts["max"].(bool):
+		msats, err := parseSatoshis(ctx, opts)
+		if err != nil {
+			send(ctx, u, t.ERROR, t.T{"Err": messageFromError(ctx, ErrInvalidAmount.withDetail(err))})
+			return
+		}
+
+		if opts["min"].(bool) {
+			data.Min = msats / 1000
+		} else {
+			data.Max = msats / 1000
+		}
+
+		if min, max := data.limits(); min > max {
+			send(ctx, u, t.ERROR, t.T{"Err": "minimum can't be above the maximum."})
+			return
+		}
+	case opts["description"].(bool):
+		data.Description = strings.Join(opts["<description>"].([]string), " ")
+	case opts["image"].(bool):
+		imageURL, _ := opts.String("<url>")
+		if imageURL == "" {
+			data.Image = nil
+			data.ImageExt = ""
+			break
+		}
+
+		b, err := imageBytesFromURL(ctx, imageURL)
+		if err != nil {
+			send(ctx, u, t.ERROR, t.T{"Err": "failed to fetch image: " + err.Error()})
+			return
+		}
+		if len(b) > s.LNURLImageMaxSize {
+			send(ctx, u, t.ERROR, t.T{"Err": fmt.Sprintf(
+				"image too big, the maximum is %d bytes.", s.LNURLImageMaxSize)})
+			return
+		}
+
+		ext := "jpeg"
+		if http.DetectContentType(b) == "image/png" {
+			ext = "png"
+		}
+		data.Image = b
+		data.ImageExt = ext
+	case opts["reset"].(bool):
+		data = TipJarData{}
+	default:
+		changed = false
+	}
+
+	if changed {
+		if err := u.setAppData("tipjar", data); err != nil {
+			log.Warn().Err(err).Stringer("user", &u).Msg("failed to save tipjar")
+			send(ctx, u, t.ERROR, t.T{"Err": ErrDatabase.Error()})
+			return
+		}
+	}
+
+	go u.track("tipjar", map[string]interface{}{"changed": changed})
+
+	enc, err := lnurl.LNURLEncode(
+		fmt.Sprintf("%s/lnurl/tipjar/%d", s.ServiceURL, u.Id))
+	if err != nil {
+		log.Error().Err(err).Msg("error encoding tipjar lnurl")
+		return
+	}
+
+	min, max := data.limits()
+	send(ctx, u, qrURL(enc), translateTemplate(ctx, t.TIPJARMSG, t.T{
+		"LNURL":       enc,
+		"Min":         min,
+		"Max":         max,
+		"Description": escapeHTML(tipJarDescription(ctx, u, data)),
+		"HasImage":    len(data.Image) > 0,
+	}))
+}
+
+func tipJarDescription(ctx context.Context, u User, data TipJarData) string {
+	if data.Description != "" {
+		return data.Description
+	}
+	return fmt.Sprintf("Tip jar of %s on t.me/%s.", u.AtName(ctx), s.ServiceId)
+}
+
+func tipJarParams(ctx context.Context, u User) (params lnurl.LNURLPayParams, err error) {
+	var data TipJarData
+	if err = u.getAppData("tipjar", &data); err != nil {
+		return
+	}
+
+	var metadata lnurl.Metadata
+	metadata.Description = tipJarDescription(ctx, u, data)
+	if len(data.Image) > 0 {
+		metadata.Image.Bytes = data.Image
+		metadata.Image.Ext = data.ImageExt
+	}
+
+	min, max := data.limits()
+	params = lnurl.LNURLPayParams{
+		LNURLResponse: lnurl.OkResponse(),
+		Tag:           "payRequest",
+		Callback: fmt.Sprintf("%s/lnurl/tipjar/%d",
+			s.ServiceURL, u.Id),
+		MaxSendable:    max * 1000,
+		MinSendable:    min * 1000,
+		Metadata:       metadata,
+		CommentAllowed: 422,
+		PayerData: &lnurl.PayerDataSpec{
+			FreeName:         &lnurl.PayerDataItemSpec{},
+			LightningAddress: &lnurl.PayerDataItemSpec{},
+			Email:            &lnurl.PayerDataItemSpec{},
+		},
+	}
+
+	params.EncodedMetadata = params.MetadataEncoded()
+
+	return
+}
+
+func serveTipJar() {
+	router.Path("/lnurl/tipjar/{userid}").HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ctx := context.WithValue(context.Background(), "origin", "external")
+		qs := r.URL.Query()
+
+		id, err := strconv.Atoi(mux.Vars(r)["userid"])
+		if err != nil {
+			json.NewEncoder(w).Encode(lnurl.ErrorResponse("Invalid user id."))
+			return
+		}
+		receiver, err := loadUser(id)
+		if err != nil {
+			json.NewEncoder(w).Encode(lnurl.ErrorResponse("Invalid user id."))
+			return
+		}
+
+		params, err := tipJarParams(ctx, receiver)
+		if err != nil {
+			log.Warn().Err(err).Stringer("user", &receiver).
+				Msg("failed to load tipjar params")
+			json.NewEncoder(w).Encode(lnurl.ErrorResponse("Failed to load tip jar."))
+			return
+		}
+
+		if qs.Get("amount") == "" {
+			log.Debug().Str("url", r.URL.String()).Msg("tipjar first request")
+
+			go receiver.track("incoming tipjar attempt", nil)
+
+			json.NewEncoder(w).Encode(params)
+		} else {
+			log.Debug().Str("url", r.URL.String()).Msg("tipjar second request")
+
+			lnurlPayInvoice(ctx, w, qs, receiver, params, "tipjar")
+		}
+	})
+}
@@ -33,6 +33,8 @@ type Transaction struct {
 	Description    string         `db:"description"`
 	Tag            sql.NullString `db:"tag"`
 	Payee          sql.NullString `db:"payee_node"`
+	FiatCurrency   sql.NullString `db:"fiat_currency"`
+	MsatPerFiat    sql.NullInt64  `db:"msat_per_fiat"`
 
 	unclaimed *bool
 }
@@ -58,6 +60,17 @@ func (t Transaction) PeerActionDescription() string {
 	}
 }
 
+// FiatAmount uses the exchange rate stored when the transaction happened, in
+// the currency it was stored in, or the current rate in the given currency for
+// older transactions without it.
+func (t Transaction) FiatAmount(currency string) string {
+	if t.FiatCurrency.Valid && t.MsatPerFiat.Valid && t.MsatPerFiat.Int64 > 0 {
+		return fmt.Sprintf("%.2f %s",
+			t.Amount*1000/float64(t.MsatPerFiat.Int64), t.FiatCurrency.String)
+	}
+	return getFiatPrice(int64(t.Amount*1000), currency)
+}
+
 func (t Transaction) StatusSmall() string {
 	switch t.Status {
 	case "RECEIVED":
@@ -108,7 +121,7 @@ func (t Transaction) Icon() string {
 	switch t.Tag.String {
 	case "ticket":
 		return "🎟️"
-	case "giveaway", "gifts", "giveflip":
+	case "giveaway", "gifts", "giveflip", "drop":
 		return "🎁"
 	case "coinflip":
 		return "🎲"
@@ -257,7 +270,7 @@ func displayTransactionList(ctx context.Context, page int, tag string, filter In
 		"page":   page,
 	})
 
-	limit := 25
+	limit := s.TxListPageSize
 	offset := limit * (page - 1)
 
 	txns, err := u.listTransactions(limit, offset, 16, tag, filter)
@@ -279,7 +292,8 @@ func displayTransactionList(ctx context.Context, page int, tag string, filter In
 				"newer", fmt.Sprintf("txl=%d-%s-%s", page-1, filter, tag)),
 		)
 	}
-	if len(txns) > 0 {
+	if len(txns) == limit {
+		// there may be more
 		keyboard.InlineKeyboard[0] = append(
 			keyboard.InlineKeyboard[0],
 			tgbotapi.NewInlineKeyboardButtonData(
@@ -296,6 +310,16 @@ func displayTransactionList(ctx context.Context, page int, tag string, filter In
 	})
 }
 
+// pendingOutgoingPaymentsRoutine resolves the outgoing payments that were left
+// pending, first the ones that were in flight when the bot was restarted and
+// then, periodically, any for which we may have missed the node events.
+func pendingOutgoingPaymentsRoutine(ctx context.Context) {
+	for {
+		checkAllOutgoingPayments(ctx)
+		time.Sleep(s.PendingCheckInterval)
+	}
+}
+
 func checkAllOutgoingPayments(ctx context.Context) {
 	var hashes []string
 	err := pg.Select(&hashes,
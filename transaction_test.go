@@ -0,0 +1,32 @@
+package main
+
+import (
+	"database/sql"
+	"testing"
+)
+
+func TestTransactionFiatAmountStoredRate(t *testing.T) {
+	tests := []struct {
+		amount   float64
+		currency string
+		rate     int64
+		fiat     string
+	}{
+		{1000, "USD", 25000, "40.00 USD"},
+		{1500, "EUR", 30000, "50.00 EUR"},
+		{0.5, "BRL", 5000, "0.10 BRL"},
+	}
+
+	for _, test := range tests {
+		txn := Transaction{
+			Amount:       test.amount,
+			FiatCurrency: sql.NullString{String: test.currency, Valid: true},
+			MsatPerFiat:  sql.NullInt64{Int64: test.rate, Valid: true},
+		}
+		// the viewer's currency is ignored when the rate was stored
+		if fiat := txn.FiatAmount("JPY"); fiat != test.fiat {
+			t.Errorf("FiatAmount of %g sat at %d msat/%s = %q, want %q",
+				test.amount, test.rate, test.currency, fiat, test.fiat)
+		}
+	}
+}
@@ -0,0 +1,118 @@
+package main
+
+import (
+	"context"
+
+	"github.com/docopt/docopt-go"
+	"github.com/fiatjaf/lntxbot/t"
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api"
+)
+
+// account returns the shared account of the group. it is the same account
+// used when anonymous admins or the channel itself send commands, the one
+// with the chat id as its telegram_id, so its messages go to the group.
+func (g GroupChat) account() (u User, err error) {
+	u, err = ensureTelegramId(int(g.TelegramId))
+	if err != nil {
+		return
+	}
+
+	if u.TelegramChatId != g.TelegramId {
+		err = u.setChat(g.TelegramId)
+	}
+	return
+}
+
+// treasuryCommands are the ones that act on the group account instead of the
+// personal account when called by an admin in a group with the treasury on.
+// lnurl-withdraw vouchers are left out: whoever sees one can redeem it and the
+// group account's messages go to the group.
+var treasuryCommands = []string{"balance", "pay", "withdraw", "send", "tip"}
+
+// treasuryUser returns the group account if the message should be handled as
+// if the group had sent it.
+func treasuryUser(message *tgbotapi.Message, g GroupChat, opts docopt.Opts) (User, bool) {
+	if message.Chat.Type == "private" || isChannelOrGroupUser(message.From) {
+		// anonymous admins are already using the group account
+		return User{}, false
+	}
+
+	routed := false
+	for _, command := range treasuryCommands {
+		if opts[command].(bool) {
+			routed = true
+			break
+		}
+	}
+	if opts["lnurl"].(bool) {
+		// a voucher, see above
+		routed = false
+	}
+	if !routed || !g.isTreasuryEnabled() || !isAdmin(message.Chat, message.From) {
+		return User{}, false
+	}
+
+	account, err := g.account()
+	if err != nil {
+		log.Warn().Err(err).Stringer("group", &g).Msg("failed to load group account")
+		return User{}, false
+	}
+
+	return account, true
+}
+
+func handleTreasury(ctx context.Context, opts docopt.Opts) {
+	u := ctx.Value("initiator").(User)
+	g := ctx.Value("group").(GroupChat)
+	message := ctx.Value("message").(*tgbotapi.Message)
+
+	if message.Chat.Type == "private" {
+		send(ctx, u, t.MUSTBEGROUP)
+		return
+	}
+
+	account, err := g.account()
+	if err != nil {
+		log.Warn().Err(err).Stringer("group", &g).Msg("failed to load group account")
+		send(ctx, u, t.ERROR, t.T{"Err": ErrDatabase.Error()})
+		return
+	}
+
+	var sats int64
+	if _, ok := opts["<satoshis>"].(string); ok {
+		// a member is topping up the group account
+		msats, err := parseSatoshis(ctx, opts)
+		if err != nil {
+			send(ctx, u, t.ERROR, t.T{"Err": messageFromError(ctx, ErrInvalidAmount.withDetail(err))})
+			return
+		}
+
+		if u.Id == account.Id {
+			send(ctx, u, t.ERROR, t.T{"Err": messageFromError(ctx, ErrPayYourself)})
+			return
+		}
+
+		err = u.sendInternally(ctx, account, false, msats, 0,
+			"group account top-up", "", "treasury")
+		if err != nil {
+			log.Warn().Err(err).Stringer("user", &u).Stringer("group", &g).
+				Msg("failed to top up group account")
+			send(ctx, u, t.ERROR, t.T{"Err": messageFromError(ctx, err)})
+			return
+		}
+
+		sats = msats / 1000
+	}
+
+	go u.track("treasury", map[string]interface{}{
+		"group": g.TelegramId,
+		"sats":  sats,
+	})
+
+	send(ctx, g, t.TREASURYMSG, t.T{
+		"User":    u.AtName(ctx),
+		"Sats":    sats,
+		"Balance": float64(getBalance(pg, account.Id)) / 1000,
+		"Enabled": g.isTreasuryEnabled(),
+	}, message.MessageID)
+}
@@ -0,0 +1,335 @@
+package main
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha1"
+	"database/sql"
+	"encoding/base32"
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/docopt/docopt-go"
+	"github.com/fiatjaf/lntxbot/t"
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api"
+)
+
+// TOTP as in RFC 6238 with the defaults every authenticator app uses:
+// HMAC-SHA1, 30 second steps and 6 digits.
+const (
+	totpStep   = 30
+	totpDigits = 6
+	totpSkew   = 1 // steps accepted before and after the current one
+)
+
+var totpEncoding = base32.StdEncoding.WithPadding(base32.NoPadding)
+
+func newTOTPSecret() (string, error) {
+	secret := make([]byte, 20)
+	if _, err := rand.Read(secret); err != nil {
+		return "", err
+	}
+	return totpEncoding.EncodeToString(secret), nil
+}
+
+func totpCode(secret string, counter uint64) (string, error) {
+	key, err := totpEncoding.DecodeString(secret)
+	if err != nil {
+		return "", err
+	}
+
+	msg := make([]byte, 8)
+	binary.BigEndian.PutUint64(msg, counter)
+	mac := hmac.New(sha1.New, key)
+	mac.Write(msg)
+	sum := mac.Sum(nil)
+
+	offset := sum[len(sum)-1] & 0x0f
+	value := binary.BigEndian.Uint32(sum[offset:offset+4]) & 0x7fffffff
+	return fmt.Sprintf("%0*d", totpDigits, value%1000000), nil
+}
+
+// verifyTOTP checks the code against the current step and its neighbours.
+// each step can only be used once per user so a code seen by someone else
+// can't be replayed. after s.TwoFactorMaxAttempts wrong codes nothing is
+// accepted until s.TwoFactorLockout has passed since the first of them.
+func (u User) verifyTOTP(secret, code string) bool {
+	failkey := fmt.Sprintf("totpfail:%d", u.Id)
+	failures, _ := rds.Get(failkey).Int64()
+	if failures >= int64(s.TwoFactorMaxAttempts) {
+		log.Info().Stringer("user", &u).Int64("failures", failures).
+			Msg("2fa locked after too many wrong codes")
+		return false
+	}
+
+	if u.matchTOTP(secret, code) {
+		rds.Del(failkey)
+		return true
+	}
+
+	if n, err := rds.Incr(failkey).Result(); err == nil && n == 1 {
+		rds.Expire(failkey, s.TwoFactorLockout)
+	}
+	return false
+}
+
+func (u User) matchTOTP(secret, code string) bool {
+	code = strings.TrimSpace(code)
+	if len(code) != totpDigits {
+		return false
+	}
+
+	now := uint64(time.Now().Unix() / totpStep)
+	for counter := now - totpSkew; counter <= now+totpSkew; counter++ {
+		expected, err := totpCode(secret, counter)
+		if err != nil {
+			return false
+		}
+		if hmac.Equal([]byte(expected), []byte(code)) {
+			return rds.SetNX(fmt.Sprintf("totpused:%d:%d", u.Id, counter), "t",
+				time.Second*totpStep*(2*totpSkew+1)).Val()
+		}
+	}
+	return false
+}
+
+// twoFactorFailure is the data for t.TWOFACTORINVALID, saying whether the
+// code was refused because of the lockout.
+func (u User) twoFactorFailure() t.T {
+	failkey := fmt.Sprintf("totpfail:%d", u.Id)
+	failures, _ := rds.Get(failkey).Int64()
+	if failures < int64(s.TwoFactorMaxAttempts) {
+		return t.T{}
+	}
+
+	minutes := int(rds.TTL(failkey).Val().Minutes()) + 1
+	return t.T{"Locked": true, "Minutes": minutes}
+}
+
+// getTwoFactor returns the secret and the amount in satoshis above which
+// payments need a code. an empty secret means 2fa is off.
+func (u User) getTwoFactor() (secret string, threshold int64) {
+	var row struct {
+		Secret    sql.NullString `db:"totp_secret"`
+		Threshold int64          `db:"totp_threshold"`
+	}
+	err := pg.Get(&row,
+		"SELECT totp_secret, totp_threshold FROM account WHERE id = $1", u.Id)
+	if err != nil {
+		log.Warn().Err(err).Stringer("user", &u).Msg("failed to load 2fa settings")
+		return "", 0
+	}
+	return row.Secret.String, row.Threshold
+}
+
+func (u User) setTwoFactor(secret string, threshold int64) (err error) {
+	_, err = pg.Exec(
+		"UPDATE account SET totp_secret = $2, totp_threshold = $3 WHERE id = $1",
+		u.Id, sql.NullString{String: secret, Valid: secret != ""}, threshold)
+	return
+}
+
+func handleTwoFactor(ctx context.Context, opts docopt.Opts) {
+	u := ctx.Value("initiator").(User)
+	secret, threshold := u.getTwoFactor()
+
+	switch {
+	case opts["on"].(bool):
+		if secret != "" {
+			send(ctx, u, t.TWOFACTORSTATUS, t.T{"Threshold": threshold})
+			return
+		}
+
+		msats, err := parseSatoshis(ctx, opts)
+		if err != nil {
+			send(ctx, u, t.ERROR, t.T{"Err": messageFromError(ctx, err)})
+			return
+		}
+
+		newSecret, err := newTOTPSecret()
+		if err != nil {
+			logger(ctx).Warn().Err(err).Msg("failed to generate totp secret")
+			send(ctx, u, t.ERROR, t.T{"Err": err.Error()})
+			return
+		}
+
+		// only saved for real after the first code is verified
+		rds.Set(fmt.Sprintf("totpsetup:%d", u.Id),
+			fmt.Sprintf("%s:%d", newSecret, msats/1000), time.Minute*10)
+
+		send(ctx, u, t.TWOFACTORSETUP, t.T{
+			"Secret":    newSecret,
+			"URI":       totpURI(u, newSecret),
+			"Threshold": msats / 1000,
+		})
+	case opts["verify"].(bool):
+		setup, err := rds.Get(fmt.Sprintf("totpsetup:%d", u.Id)).Result()
+		var newThreshold int64
+		parts := strings.SplitN(setup, ":", 2)
+		if err != nil || len(parts) != 2 {
+			send(ctx, u, t.CALLBACKEXPIRED, t.T{"BotOp": "2FA setup"})
+			return
+		}
+		fmt.Sscan(parts[1], &newThreshold)
+
+		if !u.verifyTOTP(parts[0], opts["<code>"].(string)) {
+			send(ctx, u, t.TWOFACTORINVALID, u.twoFactorFailure())
+			return
+		}
+
+		if err := u.setTwoFactor(parts[0], newThreshold); err != nil {
+			logger(ctx).Warn().Err(err).Stringer("user", &u).Msg("failed to save 2fa")
+			send(ctx, u, t.ERROR, t.T{"Err": ErrDatabase.Error()})
+			return
+		}
+		rds.Del(fmt.Sprintf("totpsetup:%d", u.Id))
+
+		go u.track("2fa on", map[string]interface{}{"sats": newThreshold})
+		send(ctx, u, t.TWOFACTORSTATUS, t.T{"Threshold": newThreshold})
+	case opts["off"].(bool):
+		if secret == "" {
+			send(ctx, u, t.TWOFACTORSTATUS, t.T{})
+			return
+		}
+
+		if !u.verifyTOTP(secret, opts["<code>"].(string)) {
+			send(ctx, u, t.TWOFACTORINVALID, u.twoFactorFailure())
+			return
+		}
+
+		if err := u.setTwoFactor("", 0); err != nil {
+			logger(ctx).Warn().Err(err).Stringer("user", &u).Msg("failed to disable 2fa")
+			send(ctx, u, t.ERROR, t.T{"Err": ErrDatabase.Error()})
+			return
+		}
+
+		go u.track("2fa off", nil)
+		send(ctx, u, t.TWOFACTORSTATUS, t.T{})
+	default:
+		if secret == "" {
+			threshold = 0
+		}
+		send(ctx, u, t.TWOFACTORSTATUS, t.T{"Threshold": threshold})
+	}
+}
+
+func totpURI(u User, secret string) string {
+	label := url.PathEscape(s.ServiceId + ":" + u.String())
+	return "otpauth://totp/" + label + "?" + url.Values{
+		"secret": {secret},
+		"issuer": {s.ServiceId},
+	}.Encode()
+}
+
+// checkTwoFactor is called by payInvoice. payments above the user threshold
+// need a code, which is asked for with a reply prompt on telegram. once it
+// is given the payment goes through with "2fa" set on the context.
+func (u User) checkTwoFactor(
+	ctx context.Context,
+	bolt11 string,
+	msatoshi int64,
+	feeLimit *FeeLimit,
+) error {
+	return u.requireTwoFactor(ctx, msatoshi, struct {
+		Type     string    `json:"type"`
+		Bolt11   string    `json:"bolt11"`
+		Msatoshi int64     `json:"msatoshi"`
+		FeeLimit *FeeLimit `json:"feelimit,omitempty"`
+	}{"2fa", bolt11, msatoshi, feeLimit})
+}
+
+// checkTwoFactorTransfer is called by sendInternallyToMany and when making
+// lnurl-withdraw vouchers. when the user started the transfer with a telegram
+// command the prompt replays that command once the code is given. transfers
+// started by someone else, like claims on a giveaway, can't be confirmed and
+// fail above the threshold.
+func (u User) checkTwoFactorTransfer(ctx context.Context, msatoshi int64) error {
+	var replay interface{}
+	initiator, _ := ctx.Value("initiator").(User)
+	message, _ := ctx.Value("message").(*tgbotapi.Message)
+	if initiator.Id == u.Id && message != nil {
+		replay = struct {
+			Type    string            `json:"type"`
+			Message *tgbotapi.Message `json:"message"`
+		}{"2fa", message}
+	}
+
+	return u.requireTwoFactor(ctx, msatoshi, replay)
+}
+
+// requireTwoFactor fails with ErrTwoFactorRequired if the amount is over the
+// user threshold and no code was given yet. replay is what is stored for the
+// reply prompt, if nil the user isn't prompted.
+func (u User) requireTwoFactor(
+	ctx context.Context,
+	msatoshi int64,
+	replay interface{},
+) error {
+	if verified, _ := ctx.Value("2fa").(bool); verified {
+		return nil
+	}
+
+	secret, threshold := u.getTwoFactor()
+	if secret == "" || msatoshi <= threshold*1000 {
+		return nil
+	}
+
+	err := ErrTwoFactorRequired.withData(t.T{"Threshold": threshold})
+	if origin, _ := ctx.Value("origin").(string); origin != "telegram" ||
+		u.TelegramChatId == 0 || replay == nil {
+		return err
+	}
+
+	sent := send(ctx, u, &tgbotapi.ForceReply{ForceReply: true},
+		t.TWOFACTORPROMPT, t.T{"Sats": msatoshi / 1000, "Threshold": threshold})
+	sentId, ok := sent.(int)
+	if !ok {
+		return err
+	}
+
+	data, _ := json.Marshal(replay)
+	rds.Set(fmt.Sprintf("reply:%d:%d", u.Id, sentId), data, s.ReplyPromptTimeout)
+
+	return err.withData(t.T{"Threshold": threshold, "Prompted": true})
+}
+
+// handleTwoFactorReply pays what was waiting for a code once it is correct.
+func handleTwoFactorReply(ctx context.Context, code string, raw string) bool {
+	u := ctx.Value("initiator").(User)
+
+	secret, _ := u.getTwoFactor()
+	if secret == "" || !u.verifyTOTP(secret, code) {
+		send(ctx, u, t.TWOFACTORINVALID, u.twoFactorFailure())
+		return false
+	}
+
+	var data struct {
+		Invoice  string            `json:"bolt11"`
+		Msatoshi int64             `json:"msatoshi"`
+		FeeLimit *FeeLimit         `json:"feelimit"`
+		Message  *tgbotapi.Message `json:"message"`
+	}
+	json.Unmarshal([]byte(raw), &data)
+
+	ctx = context.WithValue(ctx, "2fa", true)
+
+	if data.Message != nil {
+		// an internal transfer, run the command that started it again
+		go handleTelegramMessage(ctx, data.Message)
+		return true
+	}
+
+	hash, err := u.payInvoice(ctx, data.Invoice, data.Msatoshi, data.FeeLimit)
+	if err != nil {
+		send(ctx, u, t.ERROR, t.T{"Err": messageFromError(ctx, err)}, ctx.Value("message"))
+		return true
+	}
+
+	send(ctx, u, t.CALLBACKATTEMPT, t.T{"Hash": hash[:5]}, ctx.Value("message"))
+	return true
+}
@@ -0,0 +1,37 @@
+package main
+
+import "testing"
+
+// RFC 6238 appendix B, SHA1 with the secret "12345678901234567890". the RFC
+// lists 8 digit codes, ours are their last 6 digits.
+func TestTOTPCode(t *testing.T) {
+	secret := totpEncoding.EncodeToString([]byte("12345678901234567890"))
+
+	tests := []struct {
+		time int64
+		code string
+	}{
+		{59, "287082"},
+		{1111111109, "081804"},
+		{1111111111, "050471"},
+		{1234567890, "005924"},
+		{2000000000, "279037"},
+		{20000000000, "353130"},
+	}
+
+	for _, test := range tests {
+		code, err := totpCode(secret, uint64(test.time/totpStep))
+		if err != nil {
+			t.Fatalf("totpCode at %d: %s", test.time, err)
+		}
+		if code != test.code {
+			t.Errorf("totpCode at %d: got %s, want %s", test.time, code, test.code)
+		}
+	}
+}
+
+func TestTOTPCodeInvalidSecret(t *testing.T) {
+	if _, err := totpCode("not base32!", 1); err == nil {
+		t.Error("totpCode accepted an invalid secret")
+	}
+}
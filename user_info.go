@@ -28,16 +28,35 @@ type TaggedBalance struct {
 	Balance float64 `db:"balance"`
 }
 
-func (u User) LinkingKey(domain string) (*btcec.PrivateKey, *btcec.PublicKey) {
+// LinkingKey follows LUD-05 when LNURL_AUTH_SEED is set. Otherwise it uses the
+// old derivation, based on the bot token, which will give different keys.
+// Existing instances should only set LNURL_AUTH_SEED knowing that users will
+// appear as new accounts on services they have logged in before.
+//
+// A failed LUD-05 derivation is an error, falling back to the old derivation
+// would log the user in with a different key on the same service.
+func (u User) LinkingKey(domain string) (*btcec.PrivateKey, *btcec.PublicKey, error) {
+	if s.LNURLAuthSeed != "" {
+		sk, err := lud05LinkingKey(u.Id, domain)
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to derive linking key: %w", err)
+		}
+		return sk, sk.PubKey(), nil
+	}
+
 	seedhash := sha256.Sum256([]byte(
 		fmt.Sprintf("lnurlkeyseed:%s:%d:%s",
 			domain, u.Id, s.TelegramBotToken)))
-	return btcec.PrivKeyFromBytes(btcec.S256(), seedhash[:])
+	sk, pk := btcec.PrivKeyFromBytes(btcec.S256(), seedhash[:])
+	return sk, pk, nil
 }
 
 func (u User) SignKeyAuth(domain string, k1hex string) (key string, sig string, err error) {
 	// lnurl-auth: create a key based on the user id and sign with it
-	sk, pk := u.LinkingKey(domain)
+	sk, pk, err := u.LinkingKey(domain)
+	if err != nil {
+		return "", "", err
+	}
 
 	k1, err := hex.DecodeString(k1hex)
 	if err != nil {
@@ -111,6 +130,11 @@ func (u User) checkBalanceFor(ctx context.Context, msats int64, purpose string)
 		return false
 	}
 
+	if u.Frozen {
+		send(ctx, u, t.ERRACCOUNTFROZEN, WITHALERT)
+		return false
+	}
+
 	if info, err := u.getInfo(); err != nil || info.BalanceMsat < msats {
 		send(ctx, u, t.INSUFFICIENTBALANCE, t.T{
 			"Purpose": purpose,
@@ -147,7 +171,9 @@ SELECT * FROM (
     fees::float/1000 AS fees,
     amount::float/1000 AS amount,
     payment_hash,
-    preimage
+    preimage,
+    fiat_currency,
+    msat_per_fiat
   FROM lightning.account_txn
   WHERE account_id = $1 `+filter+` AND (CASE WHEN $5 != '' THEN tag = $5 ELSE true END)
   ORDER BY time DESC
@@ -190,12 +216,21 @@ func handleBalance(ctx context.Context, opts docopt.Opts) {
 			return
 		}
 
+		// a missing rate shouldn't prevent the balance from being shown
+		price := fiatPricer(u.Currency)
+
+		pending := getPendingBalance(pg, u.Id)
+
 		send(ctx, u, t.BALANCEMSG, t.T{
-			"Sats":     info.Balance,
-			"Usable":   info.UsableBalance,
-			"Received": info.TotalReceived,
-			"Sent":     info.TotalSent,
-			"Fees":     info.TotalFees,
+			"Sats":        info.Balance,
+			"Fiat":        price(info.BalanceMsat),
+			"Usable":      info.UsableBalance,
+			"UsableFiat":  price(int64(info.UsableBalance * 1000)),
+			"Pending":     float64(pending) / 1000,
+			"PendingFiat": price(pending),
+			"Received":    info.TotalReceived,
+			"Sent":        info.TotalSent,
+			"Fees":        info.TotalFees,
 		})
 	}
 }
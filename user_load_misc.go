@@ -6,6 +6,7 @@ import (
 	"errors"
 	"fmt"
 	"net/url"
+	"regexp"
 	"strconv"
 	"strings"
 
@@ -23,6 +24,13 @@ type User struct {
 	DiscordChannelId string `db:"discord_channel_id"`
 	Password         string `db:"password"`
 	Locale           string `db:"locale"`
+	Currency         string `db:"currency"`
+	LightningAlias   string `db:"lightning_alias"`
+	SkipQR           bool   `db:"skip_qr"`
+	Onboarded        bool   `db:"onboarded"`
+	Roman            bool   `db:"roman"`
+	SpendAlert       int64  `db:"spend_alert_threshold"` // in sat, 0 means no alerts
+	Frozen           bool   `db:"frozen"`
 
 	// this is here just to accomodate a special query made on bitclouds.go routine
 	// it can be used to other similar things in the future
@@ -34,6 +42,13 @@ const USERFIELDS = `
   id,
   coalesce(telegram_username, discord_username, '') AS username,
   locale,
+  currency,
+  coalesce(lightning_alias, '') AS lightning_alias,
+  skip_qr,
+  onboarded,
+  roman,
+  spend_alert_threshold,
+  frozen,
   password,
   coalesce(telegram_id, 0) AS telegram_id,
   coalesce(telegram_chat_id, 0) AS telegram_chat_id,
@@ -75,6 +90,15 @@ WHERE telegram_username = $1
 	return
 }
 
+func loadLightningAlias(alias string) (u User, err error) {
+	err = pg.Get(&u, `
+SELECT `+USERFIELDS+`
+FROM account
+WHERE lightning_alias = $1
+    `, alias)
+	return
+}
+
 func loadTelegramUser(telegramId int) (u User, err error) {
 	err = pg.Get(&u, `
 SELECT `+USERFIELDS+`
@@ -93,10 +117,20 @@ WHERE discord_id = $1
 	return
 }
 
+// supportedLocale turns a client language code like "pt-BR" into one of the
+// languages we have translations for, or returns "" if there is none.
+func supportedLocale(code string) string {
+	lang := strings.ToLower(strings.Split(code, "-")[0])
+	if _, languageAvailable := bundle.Translations[lang]; languageAvailable {
+		return lang
+	}
+	return ""
+}
+
 func ensureTelegramUser(message *tgbotapi.Message) (u User, tcase int, err error) {
 	var username string
 	var telegramId int64
-	var locale = "en"
+	var locale string
 
 	switch isChannelOrGroupUser(message.From) {
 	case true:
@@ -105,7 +139,7 @@ func ensureTelegramUser(message *tgbotapi.Message) (u User, tcase int, err error
 	case false:
 		telegramId = int64(message.From.ID)
 		username = strings.ToLower(message.From.UserName)
-		locale = message.From.LanguageCode
+		locale = supportedLocale(message.From.LanguageCode)
 	}
 
 	vusername := sql.NullString{String: username, Valid: username != ""}
@@ -126,12 +160,15 @@ RETURNING `+USERFIELDS,
 	tcase = len(userRows)
 	switch tcase {
 	case 0:
-		// user not registered
+		// user not registered, start with the telegram language if we have it
+		if locale == "" {
+			locale = bundle.DefaultLanguage
+		}
 		err = pg.Get(&u, `
-INSERT INTO account (telegram_id, telegram_username)
-VALUES ($1, $2)
+INSERT INTO account (telegram_id, telegram_username, locale)
+VALUES ($1, $2, $3)
 RETURNING `+USERFIELDS,
-			telegramId, vusername)
+			telegramId, vusername, locale)
 	case 1:
 		// user registered, update if necessary then leave
 		u = userRows[0]
@@ -239,9 +276,167 @@ func (u *User) setChat(id int64) error {
 }
 
 func (u *User) unsetChat() {
+	u.TelegramChatId = 0
 	pg.Exec(`UPDATE account SET telegram_chat_id = NULL WHERE id = $1`, u.Id)
 }
 
+// setOnboarded marks the user as onboarded, returning false if that was
+// already the case.
+func (u *User) setOnboarded() bool {
+	res, err := pg.Exec(
+		"UPDATE account SET onboarded = true WHERE id = $1 AND NOT onboarded",
+		u.Id)
+	if err != nil {
+		log.Warn().Err(err).Stringer("user", u).Msg("failed to set onboarded")
+		return false
+	}
+	u.Onboarded = true
+	n, _ := res.RowsAffected()
+	return n == 1
+}
+
+// getPayConfirmThreshold returns the amount in satoshis up to which payments
+// are sent without asking for a confirmation.
+func (u User) getPayConfirmThreshold() (sats int64) {
+	err := pg.Get(&sats,
+		"SELECT pay_confirm_threshold FROM account WHERE id = $1", u.Id)
+	if err != nil {
+		log.Warn().Err(err).Stringer("user", &u).
+			Msg("failed to load pay confirm threshold")
+		return 0
+	}
+	return
+}
+
+func (u User) setPayConfirmThreshold(sats int64) (err error) {
+	_, err = pg.Exec(
+		"UPDATE account SET pay_confirm_threshold = $2 WHERE id = $1",
+		u.Id, sats)
+	return
+}
+
+// invoiceDescription returns the description used on invoices made without
+// one, from the template set with /toggle description or INVOICE_DESCRIPTION.
+// {user}, {username} and {service} are replaced.
+func (u User) invoiceDescription() string {
+	var template string
+	err := pg.Get(&template,
+		"SELECT invoice_description FROM account WHERE id = $1", u.Id)
+	if err != nil {
+		log.Warn().Err(err).Stringer("user", &u).
+			Msg("failed to load invoice description")
+	}
+	if template == "" {
+		template = s.InvoiceDescription
+	}
+
+	username := u.Username
+	if username == "" {
+		username = u.LightningAlias
+	}
+	user := fmt.Sprintf("user %d", u.Id)
+	if username != "" {
+		user = "@" + username
+	}
+
+	return strings.NewReplacer(
+		"{user}", user,
+		"{username}", username,
+		"{service}", s.ServiceId,
+	).Replace(template)
+}
+
+func (u User) setInvoiceDescription(template string) (err error) {
+	_, err = pg.Exec(
+		"UPDATE account SET invoice_description = $2 WHERE id = $1",
+		u.Id, template)
+	return
+}
+
+func (u *User) setSpendAlert(sats int64) error {
+	return pg.Get(&u.SpendAlert, `
+UPDATE account SET spend_alert_threshold = $2 WHERE id = $1
+RETURNING spend_alert_threshold
+    `, u.Id, sats)
+}
+
+func (u *User) toggleRoman() error {
+	return pg.Get(&u.Roman,
+		"UPDATE account SET roman = NOT roman WHERE id = $1 RETURNING roman",
+		u.Id)
+}
+
+func (u *User) toggleSkipQR() error {
+	return pg.Get(&u.SkipQR,
+		"UPDATE account SET skip_qr = NOT skip_qr WHERE id = $1 RETURNING skip_qr",
+		u.Id)
+}
+
+func (u *User) setCurrency(currency string) error {
+	currency = strings.ToUpper(currency)
+	if !stringIsIn(currency, CURRENCIES) {
+		return errors.New("currency not supported.")
+	}
+
+	_, err := pg.Exec(
+		`UPDATE account SET currency = $1 WHERE id = $2`,
+		currency, u.Id)
+	if err != nil {
+		return err
+	}
+
+	u.Currency = currency
+	return nil
+}
+
+var lightningAliasRe = regexp.MustCompile(`^[a-z0-9_.-]{3,32}$`)
+
+func (u *User) setLightningAlias(alias string) error {
+	alias = strings.ToLower(strings.TrimSpace(alias))
+	if !lightningAliasRe.MatchString(alias) {
+		return errors.New("Address must have 3 to 32 letters, numbers, '_', '.' or '-'.")
+	}
+	if _, err := strconv.Atoi(alias); err == nil {
+		return errors.New("Address can't be just a number.")
+	}
+
+	// aliases can't shadow other people's telegram usernames
+	var taken bool
+	err := pg.Get(&taken, `
+SELECT EXISTS (
+  SELECT 1 FROM account
+  WHERE id != $1 AND (lightning_alias = $2 OR telegram_username = $2)
+)
+    `, u.Id, alias)
+	if err != nil {
+		return ErrDatabase
+	}
+	if taken {
+		return errors.New("This address is already taken.")
+	}
+
+	_, err = pg.Exec(
+		`UPDATE account SET lightning_alias = $1 WHERE id = $2`,
+		alias, u.Id)
+	if err != nil {
+		return errors.New("This address is already taken.")
+	}
+
+	u.LightningAlias = alias
+	return nil
+}
+
+// LightningAddress returns the address this user can be paid at, if any.
+func (u User) LightningAddress() string {
+	if u.LightningAlias != "" {
+		return u.LightningAlias + "@" + getHost()
+	}
+	if u.Username != "" && u.TelegramId != 0 {
+		return u.Username + "@" + getHost()
+	}
+	return ""
+}
+
 func ensureDiscordUser(discordId, username, locale string) (u User, err error) {
 	username = strings.ToLower(username)
 
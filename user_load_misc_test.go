@@ -0,0 +1,15 @@
+package main
+
+import "testing"
+
+func TestSetCurrencyUnknown(t *testing.T) {
+	for _, currency := range []string{"", "XYZ", "dollars", "US"} {
+		u := User{Currency: "USD"}
+		if err := u.setCurrency(currency); err == nil {
+			t.Errorf("setCurrency(%q) accepted an unknown currency", currency)
+		}
+		if u.Currency != "USD" {
+			t.Errorf("setCurrency(%q) changed the currency to %q", currency, u.Currency)
+		}
+	}
+}
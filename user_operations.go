@@ -36,7 +36,9 @@ SELECT
   amount::float/1000 AS amount,
   payment_hash,
   coalesce(preimage, '') AS preimage,
-  payee_node
+  payee_node,
+  fiat_currency,
+  msat_per_fiat
 FROM lightning.account_txn
 WHERE account_id = $1
   AND payment_hash LIKE $2 || '%'
@@ -70,7 +72,7 @@ func (u User) makeInvoice(
 	args *MakeInvoiceArgs,
 ) (bolt11 string, hash string, err error) {
 	msatoshi := args.Msatoshi
-	log.Debug().Stringer("user", &u).
+	logger(ctx).Debug().Stringer("user", &u).
 		Str("desc", args.Description).Int64("msats", msatoshi).
 		Msg("generating invoice")
 
@@ -78,6 +80,16 @@ func (u User) makeInvoice(
 		args.Expiry = &s.InvoiceTimeout
 	}
 
+	if args.Description == "" && args.DescriptionHash == "" {
+		args.Description = u.invoiceDescription()
+	}
+
+	if !args.IgnoreInvoiceSizeLimit {
+		if err := checkInvoiceLimits(u, msatoshi); err != nil {
+			return "", "", err
+		}
+	}
+
 	preimage := make([]byte, 32)
 	if _, err := rand.Read(preimage); err != nil {
 		return "", "", fmt.Errorf("can't create random preimage: %w", err)
@@ -86,9 +98,10 @@ func (u User) makeInvoice(
 	// hide the user id inside the preimage (first 4 bytes)
 	binary.BigEndian.PutUint32(preimage, uint32(u.Id))
 
-	// TODO: "expireIn":        int((*args.Expiry).Seconds()),
+	// TODO: "expireIn": int((*args.Expiry).Seconds()), cliche doesn't take an
+	// expiry yet, so the bolt11 gets the node default (see saveInvoiceData)
 
-	inv, err := ln.CreateInvoice(cliche.CreateInvoiceParams{
+	inv, err := ln().CreateInvoice(cliche.CreateInvoiceParams{
 		Msatoshi:        msatoshi,
 		Preimage:        hex.EncodeToString(preimage),
 		Description:     args.Description,
@@ -135,10 +148,19 @@ func (u User) payInvoice(
 	ctx context.Context,
 	bolt11 string,
 	manuallySpecifiedMsatoshi int64,
+	feeLimit *FeeLimit, // if nil the user's default will be used
 ) (hash string, err error) {
-	inv, err := decodepay.Decodepay(bolt11)
+	if u.Frozen {
+		return "", ErrAccountFrozen
+	}
+
+	if err := checkInvoiceNetwork(bolt11); err != nil {
+		return "", err
+	}
+
+	inv, err := decodeInvoice(bolt11)
 	if err != nil {
-		return "", errors.New("Failed to decode invoice: " + err.Error())
+		return "", ErrInvalidInvoice.withDetail(err)
 	}
 
 	if u.TelegramChatId != 0 {
@@ -148,19 +170,41 @@ func (u User) payInvoice(
 	amount := inv.MSatoshi
 	hash = inv.PaymentHash
 
+	if err := checkInvoiceExpiry(inv); err != nil {
+		return hash, err
+	}
+
+	// prevent the same invoice from being paid twice, like on double-taps
+	if !paymentsInFlight.SetIfAbsent(hash, u.Id) {
+		return hash, ErrAlreadyPaying
+	}
+	defer paymentsInFlight.Remove(hash)
+
+	var alreadyPaid bool
+	err = pg.Get(&alreadyPaid, `
+SELECT true FROM lightning.transaction WHERE payment_hash = $1 AND from_id = $2
+    `, hash, u.Id)
+	if err == nil && alreadyPaid {
+		return hash, ErrAlreadyPaying
+	}
+
 	if amount == 0 {
 		amount = manuallySpecifiedMsatoshi
 		if amount == 0 {
-			return hash, errors.New("Can't send 0.")
+			return hash, ErrInvalidAmount
 		}
 	}
 
+	if err := u.checkTwoFactor(ctx, bolt11, amount, feeLimit); err != nil {
+		return hash, err
+	}
+
 	if inv.Payee == s.NodeId {
 		data, err := loadInvoiceData(inv.PaymentHash)
 		if err != nil {
-			log.Debug().Err(err).Interface("invoice", inv).
+			logger(ctx).Debug().Err(err).Interface("invoice", inv).
 				Msg("no invoice stored for this hash, not a bot invoice?")
-			return hash, errors.New("Can't pay internal invoice that isn't from the bot.")
+			return hash, ErrUnknownInvoice
 		}
 
 		// it's an internal invoice. mark as paid internally.
@@ -176,26 +220,29 @@ func (u User) payInvoice(
 		}
 
 		if data.Msatoshi > amount {
-			return hash,
-				fmt.Errorf("Invoice is for %d, can't pay less.", data.Msatoshi)
+			return hash, ErrInternalAmount.withData(t.T{
+				"Sats": float64(data.Msatoshi) / 1000})
 		} else if amount > data.Msatoshi*2 {
-			return hash,
-				fmt.Errorf("Invoice is for %d, can't pay more than the double.",
-					data.Msatoshi)
+			return hash, ErrInternalAmount.withData(t.T{
+				"Sats": float64(data.Msatoshi) / 1000, "More": true})
 		}
 
 		go paymentReceived(ctx, hash, data.Msatoshi)
 		go paymentHasSucceeded(ctx, amount, 0, data.Preimage, data.Tag, hash)
 
+		u.alertSpending(ctx, amount, "", inv.Description)
 		return hash, nil
 	} else {
 		// it's an invoice from elsewhere, continue and
 		// actually send the lightning payment
-		err = u.actuallySendExternalPayment(ctx, bolt11, inv, amount)
+		feeLimit = resolveFeeLimit(feeLimit, u.getFeeLimit)
+
+		err = u.actuallySendExternalPayment(ctx, bolt11, inv, amount, feeLimit)
 		if err != nil {
 			return hash, err
 		}
 
+		u.alertSpending(ctx, amount, "", inv.Description)
 		return hash, nil
 	}
 }
@@ -205,13 +252,23 @@ func (u User) actuallySendExternalPayment(
 	bolt11 string,
 	inv decodepay.Bolt11,
 	msatoshi int64,
+	feeLimit *FeeLimit,
 ) (err error) {
 	hash := inv.PaymentHash
 
+	if err := checkFeeLimit(feeLimit, msatoshi); err != nil {
+		return err
+	}
+	fee_reserve := heldFee(feeLimit, msatoshi)
+
+	// the rate lookup may go to the network, so do it before holding a
+	// database transaction open
+	currency, rate := currentFiatRate(u.Currency)
+
 	// insert payment as pending
 	txn, err := pg.BeginTxx(ctx, &sql.TxOptions{})
 	if err != nil {
-		log.Debug().Err(err).Msg("database error starting transaction")
+		logger(ctx).Debug().Err(err).Msg("database error starting transaction")
 		return ErrDatabase
 	}
 	defer txn.Rollback()
@@ -225,43 +282,54 @@ func (u User) actuallySendExternalPayment(
 		}
 	}
 
-	fee_reserve := float64(msatoshi) * 0.005
-	if msatoshi < 1000000 {
-		fee_reserve += 5000 // account for exemptfee
-	}
-
 	_, err = txn.Exec(`
 INSERT INTO lightning.transaction
   (from_id, amount, fees, description, payment_hash, pending,
-   trigger_message, remote_node)
-VALUES ($1, $2, $3, $4, $5, true, $6, $7)
-    `, u.Id, msatoshi, int64(fee_reserve), inv.Description,
-		hash, tgMessageId, inv.Payee)
+   trigger_message, remote_node, fiat_currency, msat_per_fiat)
+VALUES ($1, $2, $3, $4, $5, true, $6, $7, $8, $9)
+    `, u.Id, msatoshi, fee_reserve, inv.Description,
+		hash, tgMessageId, inv.Payee, currency, rate)
 	if err != nil {
-		log.Debug().Err(err).Int64("msatoshi", msatoshi).
+		logger(ctx).Debug().Err(err).Int64("msatoshi", msatoshi).
 			Msg("database error inserting transaction")
-		return errors.New("Payment already in course.")
+		return ErrAlreadyPaying.withDetail(err)
 	}
 
 	if balance := getBalance(txn, u.Id); balance < 0 {
-		return errors.New("Insufficient balance.")
+		return ErrInsufficientBalance
 	}
 
 	err = txn.Commit()
 	if err != nil {
-		log.Debug().Err(err).Msg("database error committing transaction")
+		logger(ctx).Debug().Err(err).Msg("database error committing transaction")
 		return ErrDatabase
 	}
 
 	// perform payment
+	attempt := paymentAttempt{Bolt11: bolt11, Msatoshi: msatoshi, Attempts: 1}
+	if feeLimit != nil {
+		maxFee := feeLimit.maxFee(msatoshi)
+		attempt.MaxFee = &maxFee
+	}
+	savePaymentAttempt(hash, attempt)
+	paymentWatchers.Add(1)
 	go func() {
-		_, err := ln.PayInvoice(cliche.PayInvoiceParams{
+		defer paymentWatchers.Done()
+		_, err := ln().PayInvoice(cliche.PayInvoiceParams{
 			Invoice:  bolt11,
 			Msatoshi: msatoshi,
 		})
 		if err != nil {
-			send(ctx, t.ERROR, t.T{"Err": err.Error()})
+			if retryPayment(ctx, hash, []string{err.Error()}) {
+				return
+			}
+			metricPaymentFailed(lightningNodeError(err))
+			send(ctx, t.ERROR, t.T{"Err": messageFromError(ctx, lightningNodeError(err))})
+			return
 		}
+
+		// the node splits the payment in parts if needed, which may take a while
+		reportPaymentProgress(ctx, u, hash, msatoshi)
 	}()
 
 	return nil
@@ -277,7 +345,7 @@ func (u User) addInternalPendingInvoice(
 	// insert payment as pending
 	txn, err := pg.BeginTxx(ctx, &sql.TxOptions{})
 	if err != nil {
-		log.Debug().Err(err).Msg("database error starting transaction")
+		logger(ctx).Debug().Err(err).Msg("database error starting transaction")
 		return ErrDatabase
 	}
 	defer txn.Rollback()
@@ -297,8 +365,8 @@ INSERT INTO lightning.transaction
 VALUES ($1, $2, $3, $4, $5, true, $6)
     `, u.Id, targetId, msats, desc, hash, tgMessageId)
 	if err != nil {
-		log.Debug().Err(err).Msg("database error inserting transaction")
-		return errors.New("Payment already in course.")
+		logger(ctx).Debug().Err(err).Msg("database error inserting transaction")
+		return ErrAlreadyPaying.withDetail(err)
 	}
 
 	balance := getBalance(txn, u.Id)
@@ -308,7 +376,7 @@ VALUES ($1, $2, $3, $4, $5, true, $6)
 
 	err = txn.Commit()
 	if err != nil {
-		log.Debug().Err(err).Msg("database error committing transaction")
+		logger(ctx).Debug().Err(err).Msg("database error committing transaction")
 		return ErrDatabase
 	}
 
@@ -325,24 +393,62 @@ func (u User) sendInternally(
 	hash string,
 	tag string,
 ) error {
-	if target.Id == u.Id {
-		return errors.New("Can't pay yourself.")
+	return u.sendInternallyToMany(ctx, []InternalTransfer{{
+		Target: target,
+		Msats:  msats,
+		Fees:   fees,
+		Hash:   hash,
+	}}, anonymous, desc, tag)
+}
+
+type InternalTransfer struct {
+	Target User
+	Msats  int64
+	Fees   int64
+	Hash   string
+}
+
+// sendInternallyToMany makes all the transfers in a single database
+// transaction, so either all of them succeed or none.
+func (u User) sendInternallyToMany(
+	ctx context.Context,
+	transfers []InternalTransfer,
+	anonymous bool,
+	desc string,
+	tag string,
+) error {
+	if u.Frozen {
+		return ErrAccountFrozen
 	}
 
-	if msats == 0 {
-		// if nothing was provided, end here
-		return ErrInvalidAmount
+	var total int64
+	for _, transfer := range transfers {
+		if transfer.Target.Id == u.Id {
+			return ErrPayYourself
+		}
+
+		if transfer.Msats == 0 {
+			// if nothing was provided, end here
+			return ErrInvalidAmount
+		}
+
+		total += transfer.Msats + transfer.Fees
+	}
+
+	if err := u.checkTwoFactorTransfer(ctx, total); err != nil {
+		return err
 	}
 
 	var (
 		descn = sql.NullString{String: desc, Valid: desc != ""}
 		tagn  = sql.NullString{String: tag, Valid: tag != ""}
-		hashn = sql.NullString{String: hash, Valid: hash != ""}
 	)
 
+	currency, rate := currentFiatRate(u.Currency)
+
 	txn, err := pg.BeginTxx(ctx, &sql.TxOptions{})
 	if err != nil {
-		return ErrDatabase
+		return ErrDatabase.withDetail(err)
 	}
 	defer txn.Rollback()
 
@@ -355,7 +461,10 @@ func (u User) sendInternally(
 		}
 	}
 
-	_, err = txn.Exec(`
+	for _, transfer := range transfers {
+		hashn := sql.NullString{String: transfer.Hash, Valid: transfer.Hash != ""}
+
+		_, err = txn.Exec(`
 INSERT INTO lightning.transaction (
   from_id,
   to_id,
@@ -365,7 +474,9 @@ INSERT INTO lightning.transaction (
   description,
   tag,
   payment_hash,
-  trigger_message
+  trigger_message,
+  fiat_currency,
+  msat_per_fiat
 )
 VALUES (
   $1,
@@ -379,11 +490,15 @@ VALUES (
     THEN $8::text
     ELSE md5(random()::text) || md5(random()::text)
   END,
-  $9
+  $9,
+  $10,
+  $11
 )
-    `, u.Id, target.Id, anonymous, msats, fees, descn, tagn, hashn, tgMessageId)
-	if err != nil {
-		return ErrDatabase
+    `, u.Id, transfer.Target.Id, anonymous, transfer.Msats, transfer.Fees,
+			descn, tagn, hashn, tgMessageId, currency, rate)
+		if err != nil {
+			return ErrDatabase.withDetail(err)
+		}
 	}
 
 	balance := getBalance(txn, u.Id)
@@ -391,14 +506,76 @@ VALUES (
 		return ErrInsufficientBalance
 	}
 
+	if err := checkProxyBalance(txn); err != nil {
+		return ErrDatabase.withDetail(err)
+	}
+
 	err = txn.Commit()
 	if err != nil {
-		return ErrDatabase
+		return ErrDatabase.withDetail(err)
+	}
+
+	for _, transfer := range transfers {
+		u.alertSpending(ctx, transfer.Msats+transfer.Fees,
+			transfer.Target.AtName(ctx), desc)
 	}
 
 	return nil
 }
 
+// checkPaymentRate counts payments per user in one minute windows and fails
+// with ErrRateLimited after s.PaymentRateLimit of them. The admin is exempt.
+// It is called by the payment commands, not by payInvoice or
+// sendInternallyToMany, as those also move money on behalf of the user when
+// other people claim drops or giveaways.
+func (u User) checkPaymentRate() error {
+	if s.PaymentRateLimit == 0 || (s.AdminAccount > 0 && u.Id == s.AdminAccount) {
+		return nil
+	}
+
+	key := fmt.Sprintf("payrate:%d:%d", u.Id, time.Now().Unix()/60)
+	count, err := rds.Incr(key).Result()
+	if err != nil {
+		// better to let payments through than to block everybody
+		log.Warn().Err(err).Stringer("user", &u).Msg("failed to count payment rate")
+		return nil
+	}
+	if count == 1 {
+		rds.Expire(key, time.Minute*2)
+	}
+
+	if count > int64(s.PaymentRateLimit) {
+		log.Info().Stringer("user", &u).Int64("count", count).Msg("payment rate limited")
+		return ErrRateLimited.withData(t.T{"Limit": s.PaymentRateLimit})
+	}
+	return nil
+}
+
+// allowPaymentRate is checkPaymentRate for command handlers, telling the user
+// when they must slow down.
+func (u User) allowPaymentRate(ctx context.Context) bool {
+	if err := u.checkPaymentRate(); err != nil {
+		send(ctx, u, t.ERROR, t.T{"Err": messageFromError(ctx, err)})
+		return false
+	}
+	return true
+}
+
+// alertSpending warns the user when a payment is above the threshold they set
+// with /toggle_alert. It doesn't block anything, it's just a heads-up.
+func (u User) alertSpending(ctx context.Context, msats int64, to, description string) {
+	if u.SpendAlert == 0 || msats <= u.SpendAlert*1000 {
+		return
+	}
+
+	send(ctx, u, t.SPENDALERT, t.T{
+		"Sats":        float64(msats) / 1000,
+		"To":          to,
+		"Description": escapeHTML(description),
+		"Threshold":   u.SpendAlert,
+	})
+}
+
 func (u User) sendThroughProxy(
 	ctx context.Context,
 	// these must be unique across payments that must be combined, otherwise different
@@ -414,6 +591,10 @@ func (u User) sendThroughProxy(
 	pending bool,
 	tag string,
 ) (string, error) {
+	if u.Frozen {
+		return translate(ctx, t.ERRACCOUNTFROZEN), ErrAccountFrozen
+	}
+
 	var (
 		tagn        = sql.NullString{String: tag, Valid: tag != ""}
 		sourcedescn = sql.NullString{String: sourcedesc, Valid: sourcedesc != ""}
@@ -464,7 +645,7 @@ VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9)
 
 	// check proxy balance (should be always zero)
 	if err := checkProxyBalance(txn); err != nil {
-		log.Error().Err(err).Msg("proxy balance check")
+		logger(ctx).Error().Err(err).Msg("proxy balance check")
 		return "Database error.", err
 	}
 
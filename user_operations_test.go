@@ -0,0 +1,103 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	decodepay "github.com/fiatjaf/ln-decodepay"
+	"github.com/jmoiron/sqlx"
+)
+
+// blockingDB is a database whose queries wait until released and then fail,
+// so a payment can be held in the middle of payInvoice. it counts the
+// database transactions started, which is what starts a payment.
+type blockingDB struct {
+	queried chan struct{} // closed on the first query
+	release chan struct{}
+	once    sync.Once
+	begins  int32
+}
+
+var errBlockingDB = errors.New("no database in tests")
+
+func (db *blockingDB) Open(string) (driver.Conn, error) { return blockingConn{db}, nil }
+
+type blockingConn struct{ db *blockingDB }
+
+func (c blockingConn) Prepare(string) (driver.Stmt, error) { return blockingStmt{c.db}, nil }
+func (c blockingConn) Close() error                        { return nil }
+func (c blockingConn) Begin() (driver.Tx, error) {
+	atomic.AddInt32(&c.db.begins, 1)
+	return nil, errBlockingDB
+}
+
+type blockingStmt struct{ db *blockingDB }
+
+func (s blockingStmt) Close() error  { return nil }
+func (s blockingStmt) NumInput() int { return -1 }
+func (s blockingStmt) Exec([]driver.Value) (driver.Result, error) {
+	return nil, errBlockingDB
+}
+func (s blockingStmt) Query([]driver.Value) (driver.Rows, error) {
+	s.db.once.Do(func() { close(s.db.queried) })
+	<-s.db.release
+	return nil, errBlockingDB
+}
+
+func TestPayInvoiceConcurrentSameHash(t *testing.T) {
+	db := &blockingDB{queried: make(chan struct{}), release: make(chan struct{})}
+	sql.Register("blocking-payinvoice", db)
+	conn, _ := sql.Open("blocking-payinvoice", "")
+
+	oldPg, oldNetwork := pg, s.Network
+	defer func() { pg, s.Network = oldPg, oldNetwork }()
+	pg = sqlx.NewDb(conn, "postgres")
+	s.Network = "mainnet"
+
+	// a decoded invoice in the cache, so no real signature is needed
+	bolt11 := "lnbc10u1concurrentpayinvoice"
+	hash := "c0ffee0000000000000000000000000000000000000000000000000000000001"
+	decodedInvoices.Add(bolt11, decodepay.Bolt11{
+		MSatoshi:    1000000,
+		PaymentHash: hash,
+		Payee:       "03" + hash,
+		CreatedAt:   int(time.Now().Unix()),
+		Expiry:      3600,
+	})
+	defer decodedInvoices.Remove(bolt11)
+	fiatRates.Set("USD", fiatRate{MsatPerFiat: 2500, FetchedAt: time.Now()})
+	defer fiatRates.Remove("USD")
+
+	u := User{Id: 1, Currency: "USD"}
+	ctx := context.WithValue(context.Background(), "2fa", true)
+
+	first := make(chan error)
+	go func() {
+		_, err := u.payInvoice(ctx, bolt11, 0, nil)
+		first <- err
+	}()
+
+	// the first payment is now past the in-flight check, waiting on the
+	// database, when the double-tap comes
+	<-db.queried
+	if _, err := u.payInvoice(ctx, bolt11, 0, nil); !errors.Is(err, ErrAlreadyPaying) {
+		t.Errorf("second payInvoice = %v, want %v", err, ErrAlreadyPaying)
+	}
+
+	close(db.release)
+	if err := <-first; !errors.Is(err, ErrDatabase) {
+		t.Errorf("first payInvoice = %v, want it to try paying and fail on the database", err)
+	}
+	if begins := atomic.LoadInt32(&db.begins); begins != 1 {
+		t.Errorf("started %d payments, want 1", begins)
+	}
+	if paymentsInFlight.Has(hash) {
+		t.Error("the payment is still marked in flight")
+	}
+}
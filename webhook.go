@@ -0,0 +1,108 @@
+package main
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/url"
+	"time"
+
+	"github.com/docopt/docopt-go"
+	"github.com/fiatjaf/lntxbot/t"
+	"github.com/imroc/req"
+)
+
+const webhookAttempts = 5
+
+func handleWebhook(ctx context.Context, opts docopt.Opts) {
+	u := ctx.Value("initiator").(User)
+
+	switch {
+	case opts["off"].(bool):
+		if err := u.setWebhook(""); err != nil {
+			log.Warn().Err(err).Stringer("user", &u).Msg("failed to unset webhook")
+			send(ctx, u, t.ERROR, t.T{"Err": ErrDatabase.Error()})
+			return
+		}
+		go u.track("webhook", map[string]interface{}{"off": true})
+	default:
+		webhook, err := opts.String("<url>")
+		if err != nil {
+			break
+		}
+
+		if parsed, err := url.Parse(webhook); err != nil ||
+			(parsed.Scheme != "https" && parsed.Scheme != "http") ||
+			parsed.Host == "" {
+			send(ctx, u, t.ERROR, t.T{"Err": "invalid URL."})
+			return
+		}
+
+		if err := u.setWebhook(webhook); err != nil {
+			log.Warn().Err(err).Stringer("user", &u).Msg("failed to set webhook")
+			send(ctx, u, t.ERROR, t.T{"Err": ErrDatabase.Error()})
+			return
+		}
+		go u.track("webhook", nil)
+	}
+
+	send(ctx, u, t.WEBHOOKMSG, t.T{"URL": u.getWebhook()})
+}
+
+func (u User) getWebhook() (webhook string) {
+	err := pg.Get(&webhook, "SELECT webhook FROM account WHERE id = $1", u.Id)
+	if err != nil {
+		log.Warn().Err(err).Stringer("user", &u).Msg("failed to load webhook")
+		return ""
+	}
+	return
+}
+
+func (u User) setWebhook(webhook string) (err error) {
+	_, err = pg.Exec("UPDATE account SET webhook = $2 WHERE id = $1", u.Id, webhook)
+	return
+}
+
+// invoiceAPIToken is the API token with permission to create invoices.
+func (u User) invoiceAPIToken() string {
+	return base64.StdEncoding.EncodeToString(
+		[]byte(fmt.Sprintf("%d:%s", u.Id, hashString(u.Password))))
+}
+
+// postWebhook sends a payment notification to a webhook, retrying with an
+// increasing delay until it succeeds. The body is signed with the invoice API
+// token of the user, so the receiver can check it came from us.
+func postWebhook(u User, webhook string, payload interface{}) {
+	body, _ := json.Marshal(payload)
+	mac := hmac.New(sha256.New, []byte(u.invoiceAPIToken()))
+	mac.Write(body)
+	signature := hex.EncodeToString(mac.Sum(nil))
+
+	delay := 5 * time.Second
+	for attempt := 1; ; attempt++ {
+		resp, err := req.Post(webhook, req.Header{
+			"Content-Type":        "application/json",
+			"X-Lntxbot-Signature": signature,
+		}, body)
+		if err == nil && resp.Response().StatusCode >= 300 {
+			err = errors.New(resp.String())
+		}
+		if err == nil {
+			return
+		}
+
+		log.Warn().Err(err).Str("webhook", webhook).Stringer("user", &u).
+			Int("attempt", attempt).Msg("failed to call webhook")
+		if attempt == webhookAttempts {
+			return
+		}
+
+		time.Sleep(delay)
+		delay *= 2
+	}
+}